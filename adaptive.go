@@ -0,0 +1,138 @@
+package subping
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// adaptiveWindow is the number of latest ping outcomes the timeout ratio of the adaptive mode is computed over.
+	adaptiveWindow = 32
+
+	// adaptiveStep is the number of outcomes between two evaluations of a full window.
+	adaptiveStep = adaptiveWindow / 4
+
+	// adaptiveScaleUpRatio is the timeout ratio at or below which the adaptive mode adds workers.
+	adaptiveScaleUpRatio = 0.25
+
+	// adaptiveScaleDownRatio is the timeout ratio at or above which the adaptive mode removes workers.
+	adaptiveScaleDownRatio = 0.75
+)
+
+// workerScaler limits the number of workers pinging at once in the adaptive mode, see Options.Adaptive. The limit
+// starts at the base count and is scaled from the outcomes of the latest pings: raised by half while at most a
+// quarter of them time out, and halved, down to the base count, while at least three quarters of them do.
+type workerScaler struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// limit is the number of workers allowed to ping at once, between base and max.
+	limit, base, max int
+
+	// active is the number of workers pinging.
+	active int
+
+	// window holds the latest outcomes, true for a timeout, next being the index of the oldest one.
+	window []bool
+	next   int
+
+	// observed counts the outcomes since the window was last cleared.
+	observed int
+}
+
+// newWorkerScaler returns a workerScaler allowing base workers at first, a quarter of max.
+func newWorkerScaler(max int) *workerScaler {
+	base := max / 4
+	if base < 1 {
+		base = 1
+	}
+
+	w := &workerScaler{limit: base, base: base, max: max, window: make([]bool, adaptiveWindow)}
+	w.cond = sync.NewCond(&w.mu)
+
+	return w
+}
+
+// acquire blocks until the worker is allowed to ping.
+func (w *workerScaler) acquire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.active >= w.limit {
+		w.cond.Wait()
+	}
+
+	w.active++
+}
+
+// release lets another worker ping.
+func (w *workerScaler) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.active--
+	w.cond.Broadcast()
+}
+
+// observe records the outcome of a ping and rescales the limit once the window is full, every adaptiveStep
+// outcomes. It returns the new limit and the timeout ratio of the window when the limit changed. The window is
+// then cleared, so the next change is only based on the outcomes at the new limit.
+func (w *workerScaler) observe(timedOut bool) (limit int, ratio float64, changed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.window[w.next] = timedOut
+	w.next = (w.next + 1) % len(w.window)
+	w.observed++
+
+	if w.observed < len(w.window) || (w.observed-len(w.window))%adaptiveStep != 0 {
+		return w.limit, 0, false
+	}
+
+	timeouts := 0
+	for _, t := range w.window {
+		if t {
+			timeouts++
+		}
+	}
+	ratio = float64(timeouts) / float64(len(w.window))
+
+	previous := w.limit
+	switch {
+	case ratio <= adaptiveScaleUpRatio:
+		w.limit += (w.limit + 1) / 2
+		if w.limit > w.max {
+			w.limit = w.max
+		}
+	case ratio >= adaptiveScaleDownRatio:
+		w.limit /= 2
+		if w.limit < w.base {
+			w.limit = w.base
+		}
+	}
+
+	if w.limit == previous {
+		return w.limit, ratio, false
+	}
+
+	w.observed = 0
+	w.cond.Broadcast()
+
+	return w.limit, ratio, true
+}
+
+// observeOutcome feeds the outcome of the ping of target to the scaler of the adaptive mode, logging each change of
+// the number of workers.
+func (s *Subping) observeOutcome(target string, result Result) {
+	if s.scaler == nil {
+		return
+	}
+
+	workers, ratio, changed := s.scaler.observe(result.PacketsRecv == 0)
+	if !changed {
+		return
+	}
+
+	s.logger.Debug("Scaled the workers.", "workers", workers, "timeout_ratio", ratio, "target", target,
+		"elapsed", time.Since(s.startedAt).Round(time.Millisecond))
+}
@@ -0,0 +1,106 @@
+package subping_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// concurrencyPinger is a ping.Pinger taking a few milliseconds per ping, on which every target replies when online
+// is set and times out otherwise. It records the highest number of concurrent pings.
+type concurrencyPinger struct {
+	online bool
+
+	mu      sync.Mutex
+	active  int
+	highest int
+}
+
+func (p *concurrencyPinger) Ping(_ string, opts ping.Options) (ping.Result, error) {
+	p.mu.Lock()
+	p.active++
+	if p.active > p.highest {
+		p.highest = p.active
+	}
+	p.mu.Unlock()
+
+	time.Sleep(2 * time.Millisecond)
+
+	p.mu.Lock()
+	p.active--
+	p.mu.Unlock()
+
+	if !p.online {
+		return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+	}
+
+	return ping.Result{AvgRtt: time.Millisecond, PacketsSent: opts.Count, PacketsRecv: opts.Count}, nil
+}
+
+func TestAdaptive(t *testing.T) {
+	tests := []struct {
+		name        string
+		online      bool
+		adaptive    bool
+		wantHighest func(highest int) bool
+		want        string
+	}{
+		{
+			name:        "Sparse subnet stays at the base count",
+			online:      false,
+			adaptive:    true,
+			wantHighest: func(highest int) bool { return highest <= 4 },
+			want:        "at most 4",
+		},
+		{
+			name:        "Dense subnet scales up",
+			online:      true,
+			adaptive:    true,
+			wantHighest: func(highest int) bool { return highest > 4 && highest <= 16 },
+			want:        "between 5 and 16",
+		},
+		{
+			name:        "Fixed worker count",
+			online:      false,
+			adaptive:    false,
+			wantHighest: func(highest int) bool { return highest > 4 },
+			want:        "more than 4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := &concurrencyPinger{online: tt.online}
+			logger := &recordingLogger{}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     "10.0.0.0/24",
+				Count:      1,
+				MaxWorkers: 16,
+				Adaptive:   tt.adaptive,
+				Logger:     logger,
+				Pinger:     pinger,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			if sp.TotalResults != 256 {
+				t.Errorf("TotalResults = %d, want 256", sp.TotalResults)
+			}
+
+			if !tt.wantHighest(pinger.highest) {
+				t.Errorf("Run() pinged %d targets at once, want %s", pinger.highest, tt.want)
+			}
+
+			_, scaled := logger.find("debug", "Scaled the workers.")
+			if scaled != (tt.adaptive && tt.online) {
+				t.Errorf("Run() logged a scaling of the workers = %v, want %v", scaled, tt.adaptive && tt.online)
+			}
+		})
+	}
+}
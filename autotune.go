@@ -0,0 +1,139 @@
+package subping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// autoTuneTargetsPerWorker is the number of targets each worker pings in a calibration burst.
+	autoTuneTargetsPerWorker = 2
+
+	// autoTuneLossMargin is the increase of the average packet loss, in percentage points, over the burst with a
+	// single worker beyond which a concurrency level is considered to overload the network or the host, so the tuner
+	// backs off to the previous level.
+	autoTuneLossMargin = 10
+
+	// autoTuneMinGain is the relative throughput gain below which doubling the workers is not worth it.
+	autoTuneMinGain = 0.1
+)
+
+// AutoTune pings calibration bursts with 1, 2, 4 and so on up to MaxWorkers workers and returns the number of workers
+// with the best throughput before the loss rises, to be applied with SetMaxWorkers. The calibration pings are
+// reported to OnDispatch but not kept in Results, and ctx stops them early.
+func (s *Subping) AutoTune(ctx context.Context) int {
+	it := s.targets(s.NewIterator())
+
+	// nextTargets returns the next n targets, starting over from the first target when the subnets are exhausted.
+	nextTargets := func(n int) []string {
+		targets := make([]string, 0, n)
+
+		// restarted reports whether the iterator started over without yielding a target since.
+		restarted := false
+
+		for len(targets) < n {
+			ip, _ := it.Next()
+			if ip == nil {
+				if restarted {
+					break
+				}
+
+				it = s.targets(s.NewIterator())
+				restarted = true
+				continue
+			}
+
+			if _, ok := s.excluded[ip.String()]; ok {
+				continue
+			}
+
+			targets = append(targets, ip.String())
+			restarted = false
+		}
+
+		return targets
+	}
+
+	best := 1
+	bestRate, baselineLoss := s.calibrate(ctx, 1, nextTargets(autoTuneTargetsPerWorker))
+
+	for workers := 2; workers <= s.MaxWorkers && ctx.Err() == nil; workers *= 2 {
+		rate, loss := s.calibrate(ctx, workers, nextTargets(workers*autoTuneTargetsPerWorker))
+		s.logger.Debug(fmt.Sprintf("Calibration with %d workers: %.1f hosts/s, %.1f%% loss.", workers, rate, loss))
+
+		if loss > baselineLoss+autoTuneLossMargin || rate < bestRate*(1+autoTuneMinGain) {
+			break
+		}
+
+		best, bestRate = workers, rate
+	}
+
+	return best
+}
+
+// SetMaxWorkers sets MaxWorkers to workers, e.g. the result of AutoTune, and resizes the job channel to match unless
+// Options.JobBufferSize was given. It must not be called while a run is in progress.
+func (s *Subping) SetMaxWorkers(workers int) error {
+	if workers < 1 {
+		return errors.New("max workers should be more than zero (0)")
+	}
+
+	s.MaxWorkers = workers
+	s.config.MaxWorkers = workers
+
+	if s.jobBufferDefaulted {
+		s.JobBufferSize = defaultJobBufferSize(s.TotalHosts(), workers)
+		s.config.JobBufferSize = s.JobBufferSize
+	}
+
+	return nil
+}
+
+// calibrate pings targets with the given number of concurrent workers and returns the number of targets completed
+// per second and their average packet loss.
+func (s *Subping) calibrate(ctx context.Context, workers int, targets []string) (rate, loss float64) {
+	if len(targets) == 0 {
+		return 0, 0
+	}
+
+	var (
+		mu        sync.Mutex
+		totalLoss float64
+		wg        sync.WaitGroup
+		jobs      = make(chan string)
+	)
+
+	start := s.now()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for target := range jobs {
+				s.dispatch(target)
+				result, _ := s.pingHost(ctx, target)
+
+				mu.Lock()
+				totalLoss += result.PacketLoss
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, target := range targets {
+		jobs <- target
+	}
+	close(jobs)
+	wg.Wait()
+
+	elapsed := s.now().Sub(start).Seconds()
+	if elapsed <= 0 {
+		elapsed = time.Nanosecond.Seconds()
+	}
+
+	return float64(len(targets)) / elapsed, totalLoss / float64(len(targets))
+}
@@ -0,0 +1,199 @@
+package subping_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// congestedPinger is a ping.Pinger taking latency to reply, and losing every packet of the pings made while more
+// than capacity pings are in flight, like a link that drops packets once overloaded. Rather than sleeping, it derives
+// the number of pings in flight from the calibration bursts of AutoTune, two targets per worker with 1, 2, 4 and so
+// on workers, and advances clock as if the pings of a burst ran in parallel.
+type congestedPinger struct {
+	clock    *recordingClock
+	latency  time.Duration
+	capacity int
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *congestedPinger) Ping(_ string, opts ping.Options) (ping.Result, error) {
+	p.mu.Lock()
+	p.calls++
+
+	// Each burst pings two targets per worker, so the burst with w workers ends with the 2 × (2w - 1)th ping.
+	workers := 1
+	for 2*(2*workers-1) < p.calls {
+		workers *= 2
+	}
+	p.mu.Unlock()
+
+	p.clock.Sleep(p.latency / time.Duration(workers))
+
+	if workers > p.capacity {
+		return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+	}
+
+	return ping.Result{AvgRtt: p.latency, PacketsSent: opts.Count, PacketsRecv: opts.Count}, nil
+}
+
+func TestAutoTune(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxWorkers int
+		want       int
+	}{
+		{
+			name:       "Backs off when the loss rises",
+			maxWorkers: 64,
+			want:       4,
+		},
+		{
+			name:       "Capped to MaxWorkers",
+			maxWorkers: 2,
+			want:       2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &recordingClock{}
+			pinger := &congestedPinger{clock: clock, latency: 20 * time.Millisecond, capacity: 4}
+
+			var (
+				mu         sync.Mutex
+				dispatched int
+			)
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     "10.0.0.0/24",
+				Count:      1,
+				MaxWorkers: tt.maxWorkers,
+				Pinger:     pinger,
+				Clock:      clock,
+				OnDispatch: func(string) {
+					mu.Lock()
+					dispatched++
+					mu.Unlock()
+				},
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			if got := sp.AutoTune(context.Background()); got != tt.want {
+				t.Errorf("AutoTune() = %d, want %d", got, tt.want)
+			}
+
+			if len(sp.Results) != 0 {
+				t.Errorf("Results holds %d calibration results, want none", len(sp.Results))
+			}
+
+			if dispatched == 0 || dispatched != pinger.calls {
+				t.Errorf("OnDispatch saw %d calibration targets, want the %d pinged", dispatched, pinger.calls)
+			}
+		})
+	}
+}
+
+func TestAutoTuneCancelled(t *testing.T) {
+	clock := &recordingClock{}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/24",
+		Count:      1,
+		MaxWorkers: 64,
+		Pinger:     &congestedPinger{clock: clock, latency: time.Millisecond, capacity: 64},
+		Clock:      clock,
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if got := sp.AutoTune(ctx); got != 1 {
+		t.Errorf("AutoTune() = %d, want 1 when cancelled before the first level", got)
+	}
+}
+
+func TestSetMaxWorkers(t *testing.T) {
+	tests := []struct {
+		name          string
+		subnet        string
+		exclude       []string
+		jobBufferSize int
+		workers       int
+		wantBuffer    int
+		wantErr       bool
+	}{
+		{
+			name:       "Default job buffer follows the workers",
+			workers:    4,
+			wantBuffer: 64,
+		},
+		{
+			name:          "Given job buffer is kept",
+			jobBufferSize: 10,
+			workers:       4,
+			wantBuffer:    10,
+		},
+		{
+			name:       "Every host excluded",
+			subnet:     "10.0.0.0/31",
+			exclude:    []string{"10.0.0.0", "10.0.0.1"},
+			workers:    4,
+			wantBuffer: 1,
+		},
+		{
+			name:    "No worker",
+			workers: 0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subnet := tt.subnet
+			if subnet == "" {
+				subnet = "10.0.0.0/24"
+			}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:        subnet,
+				Count:         1,
+				MaxWorkers:    128,
+				Exclude:       tt.exclude,
+				JobBufferSize: tt.jobBufferSize,
+				Pinger:        ping.NewMockPinger(nil),
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			err = sp.SetMaxWorkers(tt.workers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetMaxWorkers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if sp.MaxWorkers != tt.workers || sp.JobBufferSize != tt.wantBuffer {
+				t.Errorf("MaxWorkers, JobBufferSize = %d, %d, want %d, %d",
+					sp.MaxWorkers, sp.JobBufferSize, tt.workers, tt.wantBuffer)
+			}
+
+			if got := sp.EffectiveOptions(); got.MaxWorkers != tt.workers || got.JobBufferSize != tt.wantBuffer {
+				t.Errorf("EffectiveOptions() MaxWorkers, JobBufferSize = %d, %d, want %d, %d",
+					got.MaxWorkers, got.JobBufferSize, tt.workers, tt.wantBuffer)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package subping
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CompareBaseline compares the current results with the baseline results of an earlier scan, e.g. read with
+// ReadJSONResults, and returns the regressions sorted by IP address, for health gates such as CI checks:
+//
+//   - a host online in the baseline is offline, or missing from the current results;
+//   - the average RTT of a host online in both exceeds its baseline RTT by more than rttTolerance.
+//
+// Hosts offline in the baseline are ignored, so hosts coming online are not regressions.
+// IPv6 addresses may be written in any notation.
+func CompareBaseline(baseline, current map[string]Result, rttTolerance time.Duration) (regressions []string) {
+	normalized := normalizeResults(current)
+	expected := normalizeResults(baseline)
+
+	for _, ip := range sortIPs(expected) {
+		before := expected[ip]
+		if before.PacketsRecv == 0 {
+			continue
+		}
+
+		after, ok := normalized[ip]
+		switch {
+		case !ok:
+			regressions = append(regressions, fmt.Sprintf("%s was online and is missing from the scan", ip))
+		case after.PacketsRecv == 0:
+			regressions = append(regressions, fmt.Sprintf("%s was online and is now offline", ip))
+		case after.AvgRtt-before.AvgRtt > rttTolerance:
+			regressions = append(regressions, fmt.Sprintf("%s RTT regressed from %s to %s, beyond the tolerance of %s",
+				ip, before.AvgRtt, after.AvgRtt, rttTolerance))
+		}
+	}
+
+	return regressions
+}
+
+// ChangedSince returns the current results of the hosts that changed since a previous scan, e.g. read with
+// ReadJSONResults, for incremental reporting:
+//
+//   - a host missing from the previous results;
+//   - a host that came online or went offline;
+//   - a host online in both whose average RTT moved by more than rttTolerance, up or down.
+//
+// The returned results are keyed by normalized IP address. IPv6 addresses may be written in any notation.
+func ChangedSince(previous, current map[string]Result, rttTolerance time.Duration) map[string]Result {
+	before := normalizeResults(previous)
+
+	changed := make(map[string]Result)
+	for ip, after := range normalizeResults(current) {
+		prev, ok := before[ip]
+		wasOnline, isOnline := prev.PacketsRecv > 0, after.PacketsRecv > 0
+
+		delta := after.AvgRtt - prev.AvgRtt
+		if delta < 0 {
+			delta = -delta
+		}
+
+		if !ok || wasOnline != isOnline || (isOnline && delta > rttTolerance) {
+			changed[ip] = after
+		}
+	}
+
+	return changed
+}
+
+// normalizeResults returns a copy of results keyed by normalized IP address.
+func normalizeResults(results map[string]Result) map[string]Result {
+	normalized := make(map[string]Result, len(results))
+	for ip, r := range results {
+		normalized[normalizeKey(ip)] = r
+	}
+
+	return normalized
+}
+
+// ReadJSONResults reads the results of a scan written by the JSONFormatter, e.g. with "subping --output json",
+// whether its hosts are an array or keyed by hostname. Only the average RTT and the packet statistics of each host
+// are restored, and the host names that could not be resolved are skipped.
+func ReadJSONResults(r io.Reader) (map[string]Result, error) {
+	var doc struct {
+		Hosts json.RawMessage `json:"hosts"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON results: %w", err)
+	}
+
+	hosts, err := decodeJSONHosts(doc.Hosts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON results: %w", err)
+	}
+
+	results := make(map[string]Result, len(hosts))
+	for i, host := range hosts {
+		// The host names that could not be resolved were not pinged, so they have no result.
+		if _, ok := host["resolution_error"]; ok {
+			continue
+		}
+
+		var (
+			ip     string
+			result Result
+		)
+
+		fields := []struct {
+			key   string
+			value any
+		}{
+			{"ip", &ip},
+			{"packet_loss", &result.PacketLoss},
+			{"packets_sent", &result.PacketsSent},
+			{"packets_recv", &result.PacketsRecv},
+		}
+		for _, field := range fields {
+			if err := json.Unmarshal(host[field.key], field.value); err != nil {
+				return nil, fmt.Errorf("invalid %q field of host %d: %w", field.key, i+1, err)
+			}
+		}
+
+		for key, value := range host {
+			unit, ok := strings.CutPrefix(key, "avg_latency_")
+			if !ok {
+				continue
+			}
+
+			var latency float64
+			if err := json.Unmarshal(value, &latency); err != nil {
+				return nil, fmt.Errorf("invalid %q field of host %d: %w", key, i+1, err)
+			}
+
+			result.AvgRtt = time.Duration(latency * float64(RTTUnit(unit).duration()))
+		}
+
+		results[normalizeKey(ip)] = result
+	}
+
+	return results, nil
+}
+
+// decodeJSONHosts decodes the fields of each host of the "hosts" value written by the JSONFormatter: an array, or an
+// object keyed by hostname whose arrays are concatenated in the order of their keys.
+func decodeJSONHosts(data json.RawMessage) ([]map[string]json.RawMessage, error) {
+	var hosts []map[string]json.RawMessage
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &hosts); err != nil {
+				return nil, err
+			}
+		}
+
+		return hosts, nil
+	}
+
+	var byKey map[string][]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &byKey); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		hosts = append(hosts, byKey[key]...)
+	}
+
+	return hosts, nil
+}
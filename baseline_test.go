@@ -0,0 +1,166 @@
+package subping_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+)
+
+func TestCompareBaseline(t *testing.T) {
+	online := func(rtt time.Duration) subping.Result {
+		return subping.Result{AvgRtt: rtt, PacketsSent: 1, PacketsRecv: 1}
+	}
+	offline := subping.Result{PacketsSent: 1, PacketLoss: 100}
+
+	tests := []struct {
+		name     string
+		baseline map[string]subping.Result
+		current  map[string]subping.Result
+		want     []string
+	}{
+		{
+			name:     "No regression",
+			baseline: map[string]subping.Result{"10.0.0.1": online(time.Millisecond), "10.0.0.2": offline},
+			current:  map[string]subping.Result{"10.0.0.1": online(2 * time.Millisecond), "10.0.0.2": online(time.Millisecond)},
+		},
+		{
+			name:     "Online host went offline",
+			baseline: map[string]subping.Result{"10.0.0.1": online(time.Millisecond)},
+			current:  map[string]subping.Result{"10.0.0.1": offline},
+			want:     []string{"10.0.0.1 was online and is now offline"},
+		},
+		{
+			name:     "Online host missing from the scan",
+			baseline: map[string]subping.Result{"2001:DB8::1": online(time.Millisecond)},
+			current:  map[string]subping.Result{},
+			want:     []string{"2001:db8::1 was online and is missing from the scan"},
+		},
+		{
+			name:     "RTT regressed beyond the tolerance",
+			baseline: map[string]subping.Result{"10.0.0.1": online(time.Millisecond), "10.0.0.2": online(time.Millisecond)},
+			current:  map[string]subping.Result{"10.0.0.1": online(5 * time.Millisecond), "10.0.0.2": online(3 * time.Millisecond)},
+			want:     []string{"10.0.0.1 RTT regressed from 1ms to 5ms, beyond the tolerance of 2ms"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := subping.CompareBaseline(tt.baseline, tt.current, 2*time.Millisecond)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareBaseline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadJSONResults(t *testing.T) {
+	results := []subping.HostResult{
+		{IP: "10.0.0.1", Result: subping.Result{AvgRtt: 1500 * time.Microsecond, PacketsSent: 2, PacketsRecv: 2}},
+		{IP: "10.0.0.2", Result: subping.Result{PacketLoss: 100, PacketsSent: 2}},
+	}
+
+	want := map[string]subping.Result{"10.0.0.1": results[0].Result, "10.0.0.2": results[1].Result}
+
+	// The hostname of a host is not restored.
+	resolved := append([]subping.HostResult(nil), results...)
+	resolved[0].Result.Hostname = "db.example.com"
+
+	// The host names that could not be resolved have no result.
+	unresolved := append([]subping.HostResult(nil), results...)
+	unresolved = append(unresolved, subping.HostResult{IP: "missing.test", ResolutionError: "no such host"})
+
+	tests := []struct {
+		name      string
+		formatter *subping.JSONFormatter
+		results   []subping.HostResult
+	}{
+		{
+			name:      string(subping.RTTUnitAuto),
+			formatter: &subping.JSONFormatter{RTTUnit: subping.RTTUnitAuto},
+			results:   results,
+		},
+		{
+			name:      string(subping.RTTUnitMicroseconds),
+			formatter: &subping.JSONFormatter{RTTUnit: subping.RTTUnitMicroseconds},
+			results:   results,
+		},
+		{
+			name:      "Keyed by hostname",
+			formatter: &subping.JSONFormatter{KeyByHostname: true},
+			results:   resolved,
+		},
+		{
+			name:      "Unresolved host names",
+			formatter: &subping.JSONFormatter{},
+			results:   unresolved,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.formatter.Format(&buf, tt.results, subping.ScanSummary{}); err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+
+			got, err := subping.ReadJSONResults(&buf)
+			if err != nil {
+				t.Fatalf("ReadJSONResults() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ReadJSONResults() = %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	if _, err := subping.ReadJSONResults(strings.NewReader(`{"hosts":[{"ip":1}]}`)); err == nil {
+		t.Error("ReadJSONResults() of an invalid document error = nil, want an error")
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	prior := `{
+  "hosts": [
+    {"ip": "10.0.0.1", "avg_latency_ms": 1, "packet_loss": 0, "packets_sent": 1, "packets_recv": 1, "online": true},
+    {"ip": "10.0.0.2", "avg_latency_ms": 1, "packet_loss": 0, "packets_sent": 1, "packets_recv": 1, "online": true},
+    {"ip": "10.0.0.3", "avg_latency_ms": 0, "packet_loss": 100, "packets_sent": 1, "packets_recv": 0, "online": false},
+    {"ip": "10.0.0.4", "avg_latency_ms": 0, "packet_loss": 100, "packets_sent": 1, "packets_recv": 0, "online": false},
+    {"ip": "10.0.0.5", "avg_latency_ms": 10, "packet_loss": 0, "packets_sent": 1, "packets_recv": 1, "online": true},
+    {"ip": "2001:db8::1", "avg_latency_ms": 1, "packet_loss": 0, "packets_sent": 1, "packets_recv": 1, "online": true}
+  ]
+}`
+
+	previous, err := subping.ReadJSONResults(strings.NewReader(prior))
+	if err != nil {
+		t.Fatalf("ReadJSONResults() error = %v", err)
+	}
+
+	online := func(rtt time.Duration) subping.Result {
+		return subping.Result{AvgRtt: rtt, PacketsSent: 1, PacketsRecv: 1}
+	}
+	offline := subping.Result{PacketsSent: 1, PacketLoss: 100}
+
+	current := map[string]subping.Result{
+		"10.0.0.1":          online(2 * time.Millisecond),
+		"10.0.0.2":          offline,
+		"10.0.0.3":          online(time.Millisecond),
+		"10.0.0.4":          offline,
+		"10.0.0.5":          online(4 * time.Millisecond),
+		"10.0.0.6":          offline,
+		"2001:0DB8:0::0001": online(time.Millisecond),
+	}
+
+	want := map[string]subping.Result{
+		"10.0.0.2": offline,
+		"10.0.0.3": online(time.Millisecond),
+		"10.0.0.5": online(4 * time.Millisecond),
+		"10.0.0.6": offline,
+	}
+
+	if got := subping.ChangedSince(previous, current, 2*time.Millisecond); !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangedSince() = %+v, want %+v", got, want)
+	}
+}
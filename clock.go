@@ -0,0 +1,30 @@
+package subping
+
+import "time"
+
+// Clock abstracts the passing of time so the pacing of a scan can be controlled in tests.
+type Clock interface {
+	// Sleep pauses the calling goroutine for at least the duration d.
+	Sleep(d time.Duration)
+
+	// Now returns the current time, with which Run measures Elapsed.
+	Now() time.Time
+}
+
+// realClock is the Clock backed by the time package.
+type realClock struct{}
+
+// Sleep pauses the calling goroutine using time.Sleep.
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// Now returns the current time using time.Now.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// now returns the current time of the clock of s.
+func (s *Subping) now() time.Time {
+	return s.clock.Now()
+}
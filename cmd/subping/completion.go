@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+// newCompletionCmd returns the command generating the shell completion script of subping.
+func newCompletionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion [bash|zsh|fish]",
+		Short: "Generate the shell completion script",
+		Long: "Generate the completion script of subping for the given shell and write it to the standard output.\n\n" +
+			"For example, to load the completions in the current bash session:\n\n" +
+			"  source <(subping completion bash)",
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs:             []string{"bash", "zsh", "fish"},
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			out := cmd.OutOrStdout()
+
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(out, true)
+			case "zsh":
+				return root.GenZshCompletion(out)
+			case "fish":
+				return root.GenFishCompletion(out, true)
+			}
+
+			return fmt.Errorf("unsupported shell %q", args[0])
+		},
+	}
+}
+
+// newGenManCmd returns the hidden command generating the man pages of subping into a directory.
+func newGenManCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "gen-man [directory]",
+		Short:  "Generate the man pages",
+		Args:   cobra.ExactArgs(1),
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			header := &doc.GenManHeader{
+				Title:   "SUBPING",
+				Section: "1",
+				Source:  "subping " + subpingVersion,
+			}
+
+			if err := doc.GenManTree(cmd.Root(), header, args[0]); err != nil {
+				return fmt.Errorf("failed to generate the man pages: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompletionCmd(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			var out bytes.Buffer
+
+			cmd := newRootCmd()
+			cmd.SetOut(&out)
+			cmd.SetArgs([]string{"completion", shell})
+
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("completion %s error = %v", shell, err)
+			}
+
+			if !strings.Contains(out.String(), "subping") {
+				t.Errorf("completion %s wrote no completion script for subping:\n%s", shell, out.String())
+			}
+		})
+	}
+}
+
+func TestCompletionCmdUnsupportedShell(t *testing.T) {
+	cmd := newRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"completion", "tcsh"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("completion tcsh error = nil, want an error")
+	}
+}
+
+func TestGenManCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"gen-man", dir})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("gen-man error = %v", err)
+	}
+
+	page, err := os.ReadFile(filepath.Join(dir, "subping.1"))
+	if err != nil {
+		t.Fatalf("gen-man did not write the subping man page: %v", err)
+	}
+
+	if !bytes.Contains(page, []byte("print-config")) {
+		t.Errorf("the subping man page does not document every flag:\n%s", page)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix is the prefix of the environment variables standing in for the command-line flags and arguments.
+const envPrefix = "SUBPING_"
+
+// subnetEnv is the environment variable listing the subnets to scan when none is given as argument.
+const subnetEnv = envPrefix + "SUBNET"
+
+// envName returns the environment variable standing in for the flag with the given name,
+// e.g. SUBPING_MAX_TOTAL_PACKETS for --max-total-packets.
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyEnv sets every flag of flags that is not set on the command line from its environment variable,
+// when that variable is set, so flags take precedence over the environment.
+// The values are validated as if they were given on the command line.
+func applyEnv(flags *pflag.FlagSet, lookup func(string) (string, bool)) error {
+	var err error
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" || f.Name == "version" {
+			return
+		}
+
+		name := envName(f.Name)
+
+		value, ok := lookup(name)
+		if !ok {
+			return
+		}
+
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, name, setErr)
+		}
+	})
+
+	return err
+}
+
+// subnetArgs returns args, or the comma or space separated subnets of SUBPING_SUBNET when args is empty.
+func subnetArgs(args []string, lookup func(string) (string, bool)) []string {
+	if len(args) > 0 {
+		return args
+	}
+
+	value, _ := lookup(subnetEnv)
+
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+// requireSubnet returns a cobra.PositionalArgs requiring at least one subnet, given as argument or in SUBPING_SUBNET,
+// or a hosts file. The arguments are validated before applyEnv runs, so SUBPING_HOSTS_FILE is looked up as well.
+func requireSubnet(lookup func(string) (string, bool)) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		envHostsFile, _ := lookup(envName("hosts-file"))
+
+		if len(subnetArgs(args, lookup)) == 0 && hostsFile == "" && envHostsFile == "" {
+			return fmt.Errorf("requires at least one subnet as argument, in %s or in --hosts-file", subnetEnv)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		flag string
+		want string
+	}{
+		{flag: "count", want: "SUBPING_COUNT"},
+		{flag: "max-total-packets", want: "SUBPING_MAX_TOTAL_PACKETS"},
+		{flag: "i-know-what-im-doing", want: "SUBPING_I_KNOW_WHAT_IM_DOING"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.flag, func(t *testing.T) {
+			if got := envName(tt.flag); got != tt.want {
+				t.Errorf("envName(%q) = %q, want %q", tt.flag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		flags       []string
+		env         map[string]string
+		wantCount   int
+		wantExclude []string
+		wantShuffle bool
+		wantErr     bool
+	}{
+		{
+			name:      "Defaults",
+			wantCount: 1,
+		},
+		{
+			name:        "Environment only",
+			env:         map[string]string{"SUBPING_COUNT": "3", "SUBPING_EXCLUDE": "10.0.0.1,10.0.0.2", "SUBPING_SHUFFLE": "true"},
+			wantCount:   3,
+			wantExclude: []string{"10.0.0.1", "10.0.0.2"},
+			wantShuffle: true,
+		},
+		{
+			name:        "Flags only",
+			flags:       []string{"-c", "5", "--exclude", "10.0.0.9"},
+			wantCount:   5,
+			wantExclude: []string{"10.0.0.9"},
+		},
+		{
+			name:        "Flags take precedence over the environment",
+			flags:       []string{"--count", "5"},
+			env:         map[string]string{"SUBPING_COUNT": "3", "SUBPING_SHUFFLE": "true"},
+			wantCount:   5,
+			wantShuffle: true,
+		},
+		{
+			name:    "Invalid environment value",
+			env:     map[string]string{"SUBPING_COUNT": "three"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newRootCmd()
+			if err := cmd.ParseFlags(tt.flags); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+
+			lookup := func(name string) (string, bool) {
+				value, ok := tt.env[name]
+				return value, ok
+			}
+
+			err := applyEnv(cmd.Flags(), lookup)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyEnv() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if pingCount != tt.wantCount {
+				t.Errorf("count = %d, want %d", pingCount, tt.wantCount)
+			}
+			if len(excludedHosts) != len(tt.wantExclude) || (len(tt.wantExclude) > 0 && !reflect.DeepEqual(excludedHosts, tt.wantExclude)) {
+				t.Errorf("exclude = %v, want %v", excludedHosts, tt.wantExclude)
+			}
+			if shuffleTargets != tt.wantShuffle {
+				t.Errorf("shuffle = %t, want %t", shuffleTargets, tt.wantShuffle)
+			}
+		})
+	}
+}
+
+func TestSubnetArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "Environment only",
+			env:  map[string]string{"SUBPING_SUBNET": "10.0.0.0/24, 10.0.1.0/24"},
+			want: []string{"10.0.0.0/24", "10.0.1.0/24"},
+		},
+		{
+			name: "Arguments only",
+			args: []string{"192.168.1.0/24"},
+			want: []string{"192.168.1.0/24"},
+		},
+		{
+			name: "Arguments take precedence over the environment",
+			args: []string{"192.168.1.0/24"},
+			env:  map[string]string{"SUBPING_SUBNET": "10.0.0.0/24"},
+			want: []string{"192.168.1.0/24"},
+		},
+		{
+			name: "Neither",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(name string) (string, bool) {
+				value, ok := tt.env[name]
+				return value, ok
+			}
+
+			got := subnetArgs(tt.args, lookup)
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("subnetArgs() = %v, want %v", got, tt.want)
+			}
+
+			if err := requireSubnet(lookup)(newRootCmd(), tt.args); (err != nil) != (len(tt.want) == 0) {
+				t.Errorf("requireSubnet() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRequireSubnet(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		wantErr bool
+	}{
+		{name: "Argument", args: []string{"10.0.0.0/24"}},
+		{name: "Subnet environment variable", env: map[string]string{"SUBPING_SUBNET": "10.0.0.0/24"}},
+		{name: "Hosts file environment variable", env: map[string]string{"SUBPING_HOSTS_FILE": "hosts.txt"}},
+		{name: "Neither", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(name string) (string, bool) {
+				value, ok := tt.env[name]
+				return value, ok
+			}
+
+			err := requireSubnet(lookup)(newRootCmd(), tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("requireSubnet() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHostsFileFromEnvironment(t *testing.T) {
+	t.Setenv(subnetEnv, "")
+	t.Setenv("SUBPING_HOSTS_FILE", "hosts.txt")
+
+	// The arguments are validated before PreRunE applies the environment, as when running subping.
+	if err := newRootCmd().ValidateArgs(nil); err != nil {
+		t.Errorf("ValidateArgs() error = %v, want SUBPING_HOSTS_FILE to stand in for the subnets", err)
+	}
+}
@@ -1,39 +1,165 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
-	"sort"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/atotto/clipboard"
 	"github.com/common-nighthawk/go-figure"
 	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/export"
+	"github.com/fadhilyori/subping/pkg/geo"
+	"github.com/fadhilyori/subping/pkg/metrics"
+	"github.com/fadhilyori/subping/pkg/network"
+	"github.com/fadhilyori/subping/pkg/ping"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
 )
 
+// defaultBannerStyle is the go-figure font used for the banner by default.
+const defaultBannerStyle = "larry3d"
+
 var (
 	pingCount           int
+	confirmCount        int
 	pingTimeoutStr      string
 	pingIntervalStr     string
+	intervalJitterStr   string
+	interSubnetDelayStr string
+	maxDurationStr      string
+	pingTimeoutsStr     string
+	pingRetries         int
+	retryBackoffStr     string
 	pingMaxWorkers      int
+	strictWorkers       bool
+	autoTune            bool
+	shuffleTargets      bool
+	shuffleSeed         int64
 	subpingVersion      = "dev"
 	showOfflineHostList bool
+	copyToClipboard     bool
+	showScore           bool
+	showResponder       bool
+	watchIntervalStr    string
+	offlineReminder     int
+	excludedHosts       []string
+	bannerStyle         string
+	quiet               bool
+	expectFile          string
+	baselineFile        string
+	sinceFile           string
+	rttToleranceStr     string
+	minLatencyStr       string
+	maxLatencyStr       string
+	maxLoss             float64
+	knownHostsFile      string
+	hostsFile           string
+	ipv4Only            bool
+	ipv6Only            bool
+	rttUnitStr          string
+	outputFormat        string
+	resolveHostnames    bool
+	resolveTimeoutStr   string
+	keyBy               string
+	sortOrderStr        string
+	tcpPorts            []int
+	tcpPort             int
+	privileged          bool
+	retryOnAllOffline   bool
+	printConfigFlag     bool
+	dryRun              bool
+	fallbackToMock      bool
+	maxTotalPackets     int
+	rateLimit           int
+	adaptive            bool
+	maxHosts            int
+	allowLargeRanges    bool
+	showScanContext     bool
+	streamTo            string
+	csvLogPath          string
+	auditFile           string
+	metricsListen       string
+	showOnlineRuns      bool
+	sourceAddrs         []string
+	useTUI              bool
+	geoDBPath           string
+	progressFD          int
+	scanRetries         int
+	quickScan           bool
+	watchCSVPath        string
+	stopOnFirstReply    bool
+	payloadSize         int
+	ttl                 int
+	tos                 int
+	showTTL             bool
+	streamWait          bool
+
+	// writeClipboard copies text to the system clipboard. It is a variable so tests can replace it.
+	writeClipboard = clipboard.WriteAll
+
+	// runTUI scans s every interval and displays the results live in the interactive terminal UI.
+	// It is nil unless subping is built with the "tui" build tag, which keeps the default build dependency-light.
+	runTUI func(s *subping.Subping, interval time.Duration) error
 )
 
+// csvFlushInterval is the time between two rewrites of the CSV file of watch mode.
+const csvFlushInterval = time.Second
+
+// defaultTUIInterval is the time between two scan rounds in the terminal UI when --watch is not set.
+const defaultTUIInterval = 5 * time.Second
+
 func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newRootCmd returns the subping command with its flags and subcommands.
+func newRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
-		Use:     "subping [flags] [network subnet]",
+		Use:     "subping [flags] [network subnet]...",
 		Version: subpingVersion,
 		Short:   "A tool for pinging IP addresses in a subnet",
-		Long:    "Subping is a command-line tool that allows you to ping IP addresses within a specified subnet range.",
-		Args:    cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-		Run:     runSubping,
-		PreRun: func(cmd *cobra.Command, args []string) {
-			figure.NewFigure("subping", "larry3d", true).Print()
+		Long: "Subping is a command-line tool that allows you to ping IP addresses within one or more subnet ranges.\n\n" +
+			"Every flag can also be set with a SUBPING_* environment variable named after it, e.g. SUBPING_MAX_TOTAL_PACKETS " +
+			"for --max-total-packets, and the subnets with SUBPING_SUBNET. Flags and arguments take precedence.\n\n" +
+			"Subnets are given in CIDR notation, as single IP addresses, as IPv4 wildcards such as 10.0.*.* or as ranges " +
+			"such as 10.0.0.1-20 or 10.0.0.0+500 for 500 consecutive addresses.",
+		Args: cobra.MatchAll(requireSubnet(os.LookupEnv), cobra.OnlyValidArgs),
+		Run:  runSubping,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyEnv(cmd.Flags(), os.LookupEnv); err != nil {
+				return err
+			}
+
+			// The banner would corrupt machine-readable output, e.g. a JSON document kept as a baseline.
+			if !decorated() {
+				return nil
+			}
+
+			if bannerStyle != "none" {
+				figure.NewFigure("subping", bannerFont(bannerStyle), true).Print()
+			}
 			fmt.Println(cmd.Version)
 			fmt.Print("\n\n")
+
+			return nil
 		},
 	}
 
@@ -42,27 +168,303 @@ func main() {
 	flags.IntVarP(&pingCount, "count", "c", 1,
 		"Specifies the number of ping attempts for each IP address.",
 	)
+	flags.IntVar(&confirmCount, "confirm-count", 0,
+		"Specifies the number of ping attempts sent again to the IP addresses that lost packets, to confirm their state.",
+	)
 	flags.IntVarP(&pingMaxWorkers,
 		"job", "n", 128,
 		"Specifies the number of maximum concurrent jobs spawned to perform ping operations.",
 	)
+	flags.BoolVar(&autoTune, "auto-tune", false,
+		"Specify whether to pick the number of workers, up to --job, with short calibration bursts before scanning.",
+	)
+	flags.BoolVar(&adaptive, "adaptive", false,
+		"Specify whether to scale the number of workers pinging at once, from a quarter of --job up to --job, from the ratio of timeouts of the latest pings.",
+	)
+	flags.BoolVar(&strictWorkers, "strict-workers", false,
+		"Specify whether to fail instead of lowering --job, with a warning, when it exceeds the number of IP addresses.",
+	)
 	flags.StringVarP(&pingTimeoutStr, "timeout", "t", "1s",
 		"Specifies the maximum ping timeout duration for each ping request.",
 	)
 	flags.StringVarP(&pingIntervalStr, "interval", "i", "300ms",
 		"Specifies the time duration between each ping request.",
 	)
+	flags.StringVar(&intervalJitterStr, "interval-jitter", "0s",
+		"Specifies the maximum random duration added to or subtracted from the interval, e.g. \"50ms\".",
+	)
+	flags.StringVar(&interSubnetDelayStr, "inter-subnet-delay", "0s",
+		"Specifies the pause between finishing a subnet and starting the next one when several subnets are scanned.",
+	)
+	flags.StringVar(&maxDurationStr, "max-duration", "0s",
+		"Specifies the maximum duration of the whole scan, e.g. \"30s\", after which it stops and reports the hosts pinged so far. Zero means no limit.",
+	)
+	flags.StringVar(&pingTimeoutsStr, "timeouts", "",
+		"Specifies a comma or space separated list of timeouts applied to successive retry attempts, e.g. \"100ms,500ms,2s\".",
+	)
+	flags.IntVar(&pingRetries, "retries", 0,
+		"Specifies the number of extra attempts for each IP address that does not reply or whose ping fails.",
+	)
+	flags.StringVar(&retryBackoffStr, "retry-backoff", subping.DefaultRetryBackoff.String(),
+		"Specifies the pause before retrying a failed ping, doubled before each following attempt.",
+	)
+	flags.IntVar(&scanRetries, "scan-retries", 0,
+		"Specifies the number of times the whole scan is re-run, with exponential backoff, when most IP addresses could not be pinged because of network errors.",
+	)
+	flags.IntVar(&maxHosts, "max-hosts", subping.DefaultMaxHosts,
+		"Specifies the maximum number of hosts in the subnet. Larger subnets are refused as a safety measure.",
+	)
+	flags.BoolVar(&allowLargeRanges, "i-know-what-im-doing", false,
+		"Specify whether to scan subnets larger than the --max-hosts safety threshold.",
+	)
+	flags.IntVar(&maxTotalPackets, "max-total-packets", 0,
+		"Specifies the maximum number of packets sent during the scan. The results are partial once it is reached.",
+	)
+	flags.IntVar(&rateLimit, "rate-limit", 0,
+		"Specifies the maximum number of packets sent per second across all workers. 0 means no limit.",
+	)
+	flags.BoolVar(&showScanContext, "scan-context", false,
+		"Specify whether to report the interface, the default gateway and their MAC addresses along with the results.",
+	)
+	flags.StringVar(&geoDBPath, "geo-db", "",
+		"Specifies the path of a MaxMind GeoLite2 City or Country database to display the location of public IP addresses.",
+	)
+	flags.BoolVar(&quickScan, "quick", false,
+		"Specify whether to only ping the first and last few and the commonly assigned IP addresses of each subnet.",
+	)
+	flags.BoolVar(&stopOnFirstReply, "stop-on-first-reply", false,
+		"Specify whether to stop pinging an IP address as soon as it replies once instead of sending all the -c packets.",
+	)
+	flags.IntVar(&payloadSize, "size", ping.DefaultPayloadSize,
+		"Specifies the size in bytes of the payload of each ICMP echo request, e.g. 1472 to reveal fragmentation on a 1500 bytes MTU.",
+	)
+	flags.IntVar(&ttl, "ttl", 0,
+		"Specifies the time to live, or hop limit over IPv6, of each ICMP echo request, up to 255. Defaults to the system default of 64.",
+	)
+	flags.IntVar(&tos, "tos", 0,
+		"Specifies the type of service of each request, up to 255, e.g. 184 to mark them with the EF DSCP for QoS testing. Only the TCP probes of --ports can be marked.",
+	)
+	flags.BoolVar(&showTTL, "show-ttl", false,
+		"Specify whether to display the TTL of the replies of each online host, which hints at its distance in hops.",
+	)
+	flags.BoolVar(&shuffleTargets, "shuffle", false,
+		"Specify whether to ping the IP addresses in a pseudo-random order.",
+	)
+	flags.Int64Var(&shuffleSeed, "seed", 0,
+		"Specifies the seed of the shuffled order, so a scan can be reproduced. Defaults to a time-based seed.",
+	)
 	flags.BoolVar(&showOfflineHostList, "offline", false,
 		"Specify whether to display the list of offline hosts.",
 	)
-
-	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+	flags.BoolVar(&showOnlineRuns, "online-runs", false,
+		"Specify whether to display the ranges of contiguous online IP addresses after the results.",
+	)
+	flags.BoolVar(&showScore, "score", false,
+		"Specify whether to display the reachability score (0-100) of each online host.",
+	)
+	flags.BoolVar(&showResponder, "show-responder", false,
+		"Specify whether to display the address replies came from when it differs from the pinged IP address.",
+	)
+	flags.StringSliceVar(&sourceAddrs, "source", nil,
+		"Specifies a comma separated list of source addresses in CIDR notation, e.g. \"10.0.1.1/24\". Each IP address is pinged from the source whose subnet contains it.",
+	)
+	flags.StringVar(&streamTo, "stream-to", "",
+		"Specifies a tcp:host:port or unix:/path/to.sock target receiving each result as a length-prefixed JSON frame as soon as it completes, "+
+			"or a fifo:/path/to.fifo named pipe receiving each result as a line of NDJSON.",
+	)
+	flags.StringVar(&csvLogPath, "csv-log", "",
+		"Specifies a CSV file to which the row of each IP address is appended as soon as it is pinged, so a scan that dies still leaves the rows of the pinged hosts on disk.",
+	)
+	flags.StringVar(&auditFile, "audit-file", "",
+		"Specifies a file to which every IP address is appended, with a timestamp, as it is pinged, whether or not it replies.",
+	)
+	flags.StringVar(&metricsListen, "metrics-listen", "",
+		"Specifies an address, e.g. \":9115\", on which to serve the results as Prometheus metrics at /metrics. "+
+			"Without --watch, the metrics are served after the scan until interrupted.",
+	)
+	flags.BoolVar(&streamWait, "stream-wait", true,
+		"Specify whether to wait for a reader to open the FIFO of --stream-to instead of failing when there is none.",
+	)
+	flags.StringVar(&watchIntervalStr, "watch", "",
+		"Specifies the time duration between scan rounds to keep watching the subnet and print host state changes.",
+	)
+	flags.StringVar(&watchCSVPath, "watch-csv", "",
+		"Specifies a CSV file kept up to date in watch mode, holding one row per IP address with its latest result.",
+	)
+	flags.IntVar(&offlineReminder, "offline-reminder", 0,
+		"Specifies the number of consecutive offline rounds between reminders that a host is still offline in watch mode.",
+	)
+	flags.StringSliceVar(&excludedHosts, "exclude", nil,
+		"Specifies a comma separated list of IP addresses within the subnet that are not pinged.",
+	)
+	flags.StringVar(&bannerStyle, "banner-style", defaultBannerStyle,
+		"Specifies the figlet font used for the banner, or \"none\" to disable the banner.",
+	)
+	flags.BoolVarP(&quiet, "quiet", "q", false,
+		"Specify whether to only print the rows of the online hosts, without the banner, the headers, the borders and the summary of the table.",
+	)
+	flags.StringVar(&hostsFile, "hosts-file", "",
+		"Specifies a file listing host names to resolve and ping, one per line, in addition to the subnets.",
+	)
+	flags.BoolVarP(&ipv4Only, "ipv4", "4", false,
+		"Specify whether to only ping the IPv4 addresses of the host names.",
+	)
+	flags.BoolVarP(&ipv6Only, "ipv6", "6", false,
+		"Specify whether to only ping the IPv6 addresses of the host names.",
+	)
+	flags.StringVar(&knownHostsFile, "known-hosts", "",
+		"Specifies a file listing already-known IP addresses, one per line, that are not pinged to only discover new hosts.",
+	)
+	flags.StringVar(&baselineFile, "compare-baseline", "",
+		"Specifies a JSON file written by --output json. Previously online hosts now offline, and RTT regressions beyond --rtt-tolerance, make subping exit with status 1.",
+	)
+	flags.StringVar(&rttToleranceStr, "rtt-tolerance", "10ms",
+		"Specifies the RTT change over the earlier scan tolerated by --compare-baseline and --since.",
+	)
+	flags.StringVar(&minLatencyStr, "min-latency", "0s",
+		"Specifies the minimum average RTT of the online hosts output, e.g. \"50ms\" to only show the slow ones. Zero means no minimum.",
+	)
+	flags.StringVar(&maxLatencyStr, "max-latency", "0s",
+		"Specifies the maximum average RTT of the online hosts output, e.g. \"10ms\" to only show the healthy ones. Zero means no maximum.",
+	)
+	flags.Float64Var(&maxLoss, "max-loss", 100,
+		"Specifies the maximum packet loss, in percent, of the hosts output.",
+	)
+	flags.StringVar(&sinceFile, "since", "",
+		"Specifies a JSON file written by --output json on an earlier scan. Only the hosts that changed since are output.",
+	)
+	flags.StringVar(&expectFile, "expect-file", "",
+		"Specifies a file listing the IP addresses expected to be online, one per line. Exits with status 1 on mismatch.",
+	)
+	flags.StringVar(&rttUnitStr, "rtt-unit", "auto",
+		"Specifies the unit of the displayed latency: auto, ns, us, ms or s. Fixed units are rendered as plain numbers.",
+	)
+	flags.IntSliceVar(&tcpPorts, "ports", nil,
+		"Specifies a comma separated list of TCP ports to probe on each IP address instead of sending ICMP pings.",
+	)
+	flags.IntVar(&tcpPort, "tcp-port", 0,
+		"Specifies a TCP port to probe on each IP address instead of sending ICMP pings, e.g. 443 for hosts that drop ICMP. It is added to --ports.",
+	)
+	flags.BoolVar(&privileged, "privileged", false,
+		"Specify whether to send ICMP echo requests using raw sockets, which requires root privileges.",
+	)
+	flags.BoolVar(&retryOnAllOffline, "retry-on-all-offline", false,
+		"Specify whether to warn and retry the scan once, in privileged mode, when no host replied at all.",
+	)
+	flags.BoolVar(&fallbackToMock, "fallback-to-mock", false,
+		"Specify whether to continue with simulated results, instead of exiting, when sending pings is not permitted.",
+	)
+	flags.StringVarP(&outputFormat, "output", "o", "table",
+		"Specifies the output format: "+strings.Join(subping.FormatterNames(), ", ")+".",
+	)
+	flags.BoolVar(&resolveHostnames, "resolve", false,
+		"Specify whether to resolve the hostname of each online IP address with a reverse DNS lookup after the scan.",
+	)
+	flags.StringVar(&resolveTimeoutStr, "resolve-timeout", subping.DefaultResolveTimeout.String(),
+		"Specifies the time waited for the reverse DNS lookup of each IP address with --resolve.",
+	)
+	flags.StringVar(&keyBy, "key-by", "ip",
+		"Specifies the key of the hosts in the json and csv output: ip, or hostname, which requires --resolve and falls back to the IP address.",
+	)
+	flags.StringVar(&sortOrderStr, "sort", "ip",
+		"Specifies the order of the results: ip, latency, with the offline hosts last, or loss. Ties are sorted by IP address.",
+	)
+	flags.IntVar(&progressFD, "progress-fd", 0,
+		"Specifies an inherited file descriptor, e.g. 3, receiving periodic JSON progress objects during the scan. Zero disables it.",
+	)
+	flags.BoolVar(&printConfigFlag, "print-config", false,
+		"Specify whether to print the effective configuration as JSON before scanning.",
+	)
+	flags.BoolVar(&dryRun, "dry-run", false,
+		"Specify whether to exit after resolving the configuration without pinging any IP address.",
+	)
+	if runTUI != nil {
+		flags.BoolVar(&useTUI, "tui", false,
+			"Specify whether to watch the subnet in an interactive terminal UI, scanning it every --watch interval.",
+		)
 	}
+	flags.BoolVar(&copyToClipboard, "clipboard", false,
+		"Specify whether to copy the results in CSV format to the system clipboard.",
+	)
+
+	rootCmd.AddCommand(newCompletionCmd(), newGenManCmd())
+
+	return rootCmd
 }
 
-func runSubping(_ *cobra.Command, args []string) {
-	subnetString := args[0]
+func runSubping(cmd *cobra.Command, args []string) {
+	targets, err := stdinTargets(subnetArgs(args, os.LookupEnv), os.Stdin)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	subnets, hosts, err := targetSubnets(targets)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if hostsFile != "" {
+		names, err := readHostsFile(hostsFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		hosts = append(hosts, names...)
+	}
+
+	// Host names alone leave the subnet to the first resolved address.
+	var subnetString string
+	var extraSubnets []string
+	if len(subnets) > 0 {
+		subnetString, extraSubnets = subnets[0], subnets[1:]
+	}
+
+	ipFamily, err := ipFamilyFlag(ipv4Only, ipv6Only)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if err := checkTOSFlag(tos); err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if keyBy != "ip" && keyBy != "hostname" {
+		log.Fatalf("invalid --key-by %q, expected ip or hostname", keyBy)
+	}
+
+	if keyBy == "hostname" && !resolveHostnames {
+		log.Fatal("--key-by hostname requires --resolve")
+	}
+
+	sortOrder, err := subping.ParseSortOrder(sortOrderStr)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if outputFormat == "ndjson" {
+		if err := checkNDJSONFlags(cmd); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	// Only warn about more workers than hosts when the number of workers was asked for.
+	if !cmd.Flags().Changed("job") {
+		pingMaxWorkers = capWorkers(pingMaxWorkers, subnets, quickScan)
+	}
+
+	var processors []subping.ResultProcessor
+	if resolveHostnames {
+		resolveTimeout, err := time.ParseDuration(resolveTimeoutStr)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		processors = append(processors, subping.ResolveHostnames{
+			Workers:    pingMaxWorkers,
+			Timeout:    resolveTimeout,
+			OnlineOnly: true,
+		})
+	}
 
 	startTime := time.Now()
 
@@ -76,75 +478,967 @@ func runSubping(_ *cobra.Command, args []string) {
 		log.Fatal(err.Error())
 	}
 
+	intervalJitter, err := time.ParseDuration(intervalJitterStr)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	interSubnetDelay, err := time.ParseDuration(interSubnetDelayStr)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	retryBackoff, err := time.ParseDuration(retryBackoffStr)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	maxDuration, err := time.ParseDuration(maxDurationStr)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	pingTimeouts, err := parseDurationList(pingTimeoutsStr)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	for i := range pingTimeouts {
+		pingTimeouts[i] *= time.Duration(pingCount)
+	}
+
+	rttUnit, err := subping.ParseRTTUnit(rttUnitStr)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	var knownHosts []string
+	if knownHostsFile != "" {
+		knownHosts, err = readHostsFile(knownHostsFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	rttTolerance, err := time.ParseDuration(rttToleranceStr)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	minLatency, err := time.ParseDuration(minLatencyStr)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	maxLatency, err := time.ParseDuration(maxLatencyStr)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	filter := resultFilter(minLatency, maxLatency, maxLoss)
+
+	var baseline map[string]ping.Result
+	if baselineFile != "" {
+		baseline, err = readResultsFile(baselineFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	var previous map[string]ping.Result
+	if sinceFile != "" {
+		previous, err = readResultsFile(sinceFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	var onResult func(ip string, result ping.Result)
+	if streamTo != "" {
+		sink, err := export.DialSink(streamTo, streamWait)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		defer sink.Close()
+
+		sink.RTTUnit = rttUnit
+
+		onResult = streamResult(sink.Write)
+	}
+
+	if csvLogPath != "" {
+		csvLog, err := export.OpenCSVLog(csvLogPath, rttUnit)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		defer csvLog.Close()
+
+		onResult = chainOnResult(onResult, streamResult(csvLog.Write))
+	}
+
+	var onDispatch func(ip string)
+	if auditFile != "" {
+		audit, err := export.OpenAuditLog(auditFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		defer audit.Close()
+
+		onDispatch = recordDispatch(audit)
+	}
+
+	// The geolocation database is only opened here, so that the subping package does not depend on it.
+	var locator subping.Locator
+	if geoDBPath != "" {
+		db, err := geo.Open(geoDBPath)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		locator = db
+	}
+
+	if metricsListen != "" {
+		exporter, err := serveMetrics(metricsListen)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		onResult = chainOnResult(onResult, exporter.Set)
+	}
+
 	s, err := subping.NewSubping(&subping.Options{
-		Subnet:     subnetString,
-		Count:      pingCount,
-		Interval:   pingInterval,
-		Timeout:    pingTimeout * time.Duration(pingCount),
-		MaxWorkers: pingMaxWorkers,
-		LogLevel:   "error",
+		Subnet:               subnetString,
+		Subnets:              extraSubnets,
+		Hosts:                hosts,
+		IPFamily:             ipFamily,
+		Count:                pingCount,
+		ConfirmCount:         confirmCount,
+		Interval:             pingInterval,
+		IntervalJitter:       intervalJitter,
+		InterSubnetDelay:     interSubnetDelay,
+		MaxDuration:          maxDuration,
+		Timeout:              pingTimeout * time.Duration(pingCount),
+		Timeouts:             pingTimeouts,
+		Retries:              pingRetries,
+		RetryBackoff:         retryBackoff,
+		MaxWorkers:           pingMaxWorkers,
+		StrictWorkers:        strictWorkers,
+		Shuffle:              shuffleTargets,
+		Seed:                 shuffleSeed,
+		LogLevel:             "warn",
+		OfflineReminderEvery: offlineReminder,
+		Exclude:              excludedHosts,
+		KnownHosts:           knownHosts,
+		RTTUnit:              rttUnit,
+		Ports:                probedPorts(tcpPorts, tcpPort),
+		Privileged:           privileged,
+		RetryOnAllOffline:    retryOnAllOffline,
+		RetryPrivileged:      retryOnAllOffline,
+		FallbackToMock:       fallbackToMock,
+		MaxTotalPackets:      maxTotalPackets,
+		RateLimit:            rateLimit,
+		Adaptive:             adaptive,
+		MaxHosts:             maxHosts,
+		AllowLargeRanges:     allowLargeRanges,
+		OnResult:             onResult,
+		OnDispatch:           onDispatch,
+		Processors:           processors,
+		Sources:              sourceAddrs,
+		Locator:              locator,
+		ScanRetries:          scanRetries,
+		Quick:                quickScan,
+		StopOnFirstReply:     stopOnFirstReply,
+		PayloadSize:          payloadSize,
+		TTL:                  ttl,
+		TOS:                  tos,
 	})
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
-	fmt.Printf("Network        : %s\n", s.TargetsIterator.IPNet.String())
-	fmt.Printf("IP Ranges      : %s - %s\n",
-		s.TargetsIterator.FirstIP.String(), s.TargetsIterator.LastIP.String(),
-	)
-	fmt.Printf("Total hosts    : %d\n", s.TargetsIterator.TotalHosts)
-	fmt.Printf("Total workers  : %d\n", s.MaxWorkers)
+	for _, host := range hosts {
+		if err, ok := s.UnresolvedHosts[host]; ok {
+			log.Printf("Warning: %v, it is not pinged.", err)
+		}
+	}
+
+	formatter, err := outputFormatter(outputFormat, s)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if autoTune && !dryRun {
+		ctx, stop := notifyInterrupt()
+		err := s.SetMaxWorkers(s.AutoTune(ctx))
+		stop()
+
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	if useTUI {
+		interval := defaultTUIInterval
+		if watchIntervalStr != "" {
+			interval, err = time.ParseDuration(watchIntervalStr)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+		}
+
+		if err := runTUI(s, interval); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+
+	if printConfigFlag {
+		if err := printConfig(os.Stdout, s); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	if decorated() {
+		printScanHeader(s, knownHosts)
+	}
+
+	if dryRun {
+		return
+	}
+
+	if watchIntervalStr != "" {
+		watchInterval, err := time.ParseDuration(watchIntervalStr)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		var csvFile *export.CSVFile
+		if watchCSVPath != "" {
+			csvFile = export.NewCSVFile(watchCSVPath)
+			csvFile.RTTUnit = s.RTTUnit
+			s.OnResult = chainOnResult(s.OnResult, csvFile.Set)
+		}
+
+		runWatch(s, watchInterval, csvFile)
+		return
+	}
+
+	var progress *progressReporter
+	if progressFD != 0 {
+		f, err := openProgressFD(progressFD)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		defer f.Close()
+
+		progress = newProgressReporter(f, s.TotalHosts(), progressInterval)
+		s.OnResult = chainOnResult(s.OnResult, progress.observe)
+	}
+
+	// The ndjson output prints each host as soon as it is pinged rather than once the scan completes.
+	ndjson, streaming := formatter.(*subping.NDJSONFormatter)
+	if streaming {
+		s.OnResult = chainOnResult(s.OnResult, writeNDJSON(ndjson, s, filter))
+	}
+
+	// Ctrl-C stops the scan but still reports the hosts pinged so far. A second Ctrl-C exits immediately.
+	ctx, stop := notifyInterrupt()
+	runErr := s.RunContext(ctx)
+	stop()
+
+	if progress != nil {
+		progress.stop()
+	}
+	if err := s.Err(); err != nil {
+		log.Fatal(err.Error())
+	}
+	if runErr != nil {
+		done, total := s.Progress()
+		reason := "was interrupted"
+		if errors.Is(runErr, context.DeadlineExceeded) {
+			reason = "reached --max-duration " + maxDuration.String()
+		}
+		log.Printf("Warning: the scan %s, the results are partial: %d of %d hosts were pinged.", reason, done, total)
+	}
+
+	summary := s.Summary()
+	summary.Duration = time.Since(startTime)
+
+	if showScanContext {
+		scanContext := s.ScanContext()
+		if f, ok := formatter.(*subping.JSONFormatter); ok {
+			f.Context = &scanContext
+		} else if decorated() {
+			printScanContext(scanContext)
+		}
+	}
+
+	results := s.SortedResults()
+	if previous != nil {
+		results = onlyHosts(results, subping.ChangedSince(previous, s.Results, rttTolerance))
+	}
+	if filter != nil {
+		results = onlyHosts(results, s.FilterResults(filter))
+	}
+	subping.SortHostResults(results, sortOrder)
+
+	if !streaming {
+		if err := formatter.Format(os.Stdout, results, summary); err != nil {
+			log.Fatal(err.Error())
+		}
+	} else if err := ndjson.Format(os.Stdout, unresolvedHosts(results), summary); err != nil {
+		// The host names that could not be resolved are not pinged, so they are only written once the scan completes.
+		log.Fatal(err.Error())
+	}
+
+	if len(s.Subnets) > 1 && decorated() {
+		printSubnetStats(os.Stdout, s.Subnets, s.PerSubnetStats())
+	}
+
+	if showOnlineRuns && decorated() {
+		fmt.Printf("Online runs         : %s\n\n", formatRuns(s.OnlineRuns()))
+	}
+
+	if decorated() {
+		sent, recv := s.TrafficStats()
+		fmt.Printf("Traffic             : %s sent, %s received\n\n", formatBytes(sent), formatBytes(recv))
+	}
+
+	// Keep stdout a single machine-readable document, e.g. for jq, when the output is not a table.
+	reports := reportWriter(outputFormat)
+
+	if copyToClipboard {
+		if err := copyResultsToClipboard(s); err != nil {
+			log.Printf("Warning: failed to copy the results to the clipboard: %v", err)
+		} else {
+			fmt.Fprintln(reports, "Results copied to the clipboard in CSV format.")
+		}
+	}
+
+	failed := false
+
+	if expectFile != "" {
+		expected, err := readHostsFile(expectFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		report := s.VerifyExpectation(expected)
+		for _, ip := range report.MissingHosts {
+			fmt.Fprintf(reports, "Expected online but offline : %s\n", ip)
+		}
+		for _, ip := range report.UnexpectedHosts {
+			fmt.Fprintf(reports, "Unexpected online           : %s\n", ip)
+		}
+
+		failed = failed || !report.OK()
+	}
+
+	if baseline != nil {
+		regressions := subping.CompareBaseline(baseline, s.Results, rttTolerance)
+		for _, regression := range regressions {
+			fmt.Fprintf(reports, "Regression : %s\n", regression)
+		}
+
+		failed = failed || len(regressions) > 0
+	}
+
+	if metricsListen != "" {
+		fmt.Fprintf(reports, "Serving the metrics on %s until interrupted.\n", metricsListen)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		<-ctx.Done()
+		stop()
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// serveMetrics serves the metrics of a new metrics.Exporter over HTTP at /metrics on addr, in the background,
+// and returns the exporter. It returns an error if addr cannot be listened on.
+func serveMetrics(addr string) (*metrics.Exporter, error) {
+	reg := prometheus.NewRegistry()
+
+	exporter, err := metrics.NewExporter(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve the metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Printf("Warning: stopped serving the metrics: %v", err)
+		}
+	}()
+
+	return exporter, nil
+}
+
+// probedPorts returns the TCP ports of --ports followed by the port of --tcp-port, when set and not already listed.
+func probedPorts(ports []int, port int) []int {
+	if port == 0 {
+		return ports
+	}
+
+	for _, p := range ports {
+		if p == port {
+			return ports
+		}
+	}
+
+	return append(ports, port)
+}
+
+// decorated reports whether the banner, the scan header and the reports around the results are printed: only with
+// the table output, and not with --quiet.
+func decorated() bool {
+	return outputFormat == "table" && !quiet
+}
+
+// reportWriter returns where the reports following the results are written for the given output format:
+// stdout for the table, and stderr for the other formats so that stdout only holds the results.
+func reportWriter(format string) io.Writer {
+	if format == "table" {
+		return os.Stdout
+	}
+
+	return os.Stderr
+}
+
+// readResultsFile reads the results of an earlier scan from a JSON file written by --output json.
+func readResultsFile(path string) (map[string]ping.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results, err := subping.ReadJSONResults(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the results of %s: %w", path, err)
+	}
+
+	return results, nil
+}
+
+// onlyHosts returns the results whose IP address is a key of keep, preserving their order.
+func onlyHosts(results []subping.HostResult, keep map[string]ping.Result) []subping.HostResult {
+	filtered := make([]subping.HostResult, 0, len(keep))
+	for _, host := range results {
+		if _, ok := keep[host.IP]; ok {
+			filtered = append(filtered, host)
+		}
+	}
+
+	return filtered
+}
+
+// resultFilter returns the predicate keeping the hosts whose average RTT is within [minLatency, maxLatency] and whose
+// packet loss is at most maxLoss percent, or nil when no bound is set. A zero latency bound is unset. The hosts that
+// did not reply have no latency, so a latency bound filters them out.
+func resultFilter(minLatency, maxLatency time.Duration, maxLoss float64) func(ping.Result) bool {
+	if minLatency == 0 && maxLatency == 0 && maxLoss >= 100 {
+		return nil
+	}
+
+	return func(r ping.Result) bool {
+		if (minLatency > 0 || maxLatency > 0) && r.PacketsRecv == 0 {
+			return false
+		}
+
+		return r.AvgRtt >= minLatency && (maxLatency == 0 || r.AvgRtt <= maxLatency) && r.PacketLoss <= maxLoss
+	}
+}
+
+// outputFormatter returns the formatter registered for the given output format.
+// The built-in formatters are registered again, configured from the command-line flags.
+func outputFormatter(format string, s *subping.Subping) (subping.Formatter, error) {
+	subping.RegisterFormatter("table", &subping.TableFormatter{
+		RTTUnit:       s.RTTUnit,
+		ShowScore:     showScore,
+		ShowOffline:   showOfflineHostList,
+		ShowPorts:     len(s.Ports) > 0,
+		ShowResponder: showResponder,
+		ShowLocation:  geoDBPath != "",
+		ShowHostname:  resolveHostnames,
+		ShowTTL:       showTTL,
+		Quiet:         quiet,
+	})
+	subping.RegisterFormatter("csv", &subping.CSVFormatter{RTTUnit: s.RTTUnit, KeyByHostname: keyBy == "hostname"})
+	subping.RegisterFormatter("json", &subping.JSONFormatter{RTTUnit: s.RTTUnit, KeyByHostname: keyBy == "hostname"})
+	subping.RegisterFormatter("markdown", &subping.MarkdownFormatter{RTTUnit: s.RTTUnit})
+	subping.RegisterFormatter("plain", &subping.PlainFormatter{RTTUnit: s.RTTUnit})
+	subping.RegisterFormatter("ndjson", &subping.NDJSONFormatter{RTTUnit: s.RTTUnit})
+
+	return subping.LookupFormatter(format)
+}
+
+// printConfig writes the effective options of s to w as indented JSON.
+func printConfig(w io.Writer, s *subping.Subping) error {
+	data, err := json.MarshalIndent(s.EffectiveOptions(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the configuration: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+
+	return err
+}
+
+// printSubnetStats writes the online and total host counts of each of the subnets to w, in the given order.
+func printSubnetStats(w io.Writer, subnets []string, stats map[string]subping.SubnetStats) {
+	fmt.Fprintln(w, "Hosts online per subnet :")
+	for _, subnet := range subnets {
+		st := stats[subnet]
+		fmt.Fprintf(w, " - %s\t%d / %d\n", subnet, st.Online, st.Total)
+	}
+	fmt.Fprintln(w)
+}
+
+// printScanHeader prints the parameters of the scan.
+func printScanHeader(s *subping.Subping, knownHosts []string) {
+	fmt.Printf("Network        : %s\n", strings.Join(s.Subnets, ", "))
+	if len(s.Subnets) == 1 {
+		fmt.Printf("IP Ranges      : %s - %s\n",
+			s.TargetsIterator.FirstIP.String(), s.TargetsIterator.LastIP.String(),
+		)
+	}
+	fmt.Printf("Total hosts    : %d\n", s.TotalHosts())
+	if hint := s.RecommendStrategy(); hint != "" {
+		fmt.Printf("Hint           : %s\n", hint)
+	}
+	if s.Quick {
+		fmt.Println("Quick scan     : common hosts only")
+	}
+	if len(knownHosts) > 0 {
+		fmt.Printf("Known hosts    : %d (skipped)\n", len(knownHosts))
+	}
+	if autoTune {
+		fmt.Printf("Total workers  : %d (auto-tuned)\n", s.MaxWorkers)
+	} else {
+		fmt.Printf("Total workers  : %d\n", s.MaxWorkers)
+	}
 	fmt.Printf("Count          : %d\n", s.Count)
 	fmt.Printf("Interval       : %s\n", s.Interval.String())
+	if s.IntervalJitter > 0 {
+		fmt.Printf("Interval jitter: %s\n", s.IntervalJitter.String())
+	}
 	fmt.Printf("Timeout        : %s\n", pingTimeoutStr)
-	fmt.Println(`-------------------------------------------------------------------------------`)
-	fmt.Printf("| %-39s | %-16s | %-14s |\n", "IP Address", "Avg Latency", "Packet Loss")
-	fmt.Println(`-------------------------------------------------------------------------------`)
+	if len(s.Timeouts) > 0 {
+		fmt.Printf("Timeouts       : %s\n", pingTimeoutsStr)
+	}
+	if s.Retries > 0 {
+		fmt.Printf("Retries        : %d\n", s.Retries)
+	}
+	if s.Shuffle {
+		fmt.Printf("Seed           : %d\n", s.Seed)
+	}
+	if len(s.Ports) > 0 {
+		ports := make([]string, 0, len(s.Ports))
+		for _, port := range s.Ports {
+			ports = append(ports, strconv.Itoa(port))
+		}
+		fmt.Printf("TCP ports      : %s\n", strings.Join(ports, ","))
+	}
+}
+
+// capWorkers returns workers lowered to the number of hosts to ping in subnets, or ranges, only counting their
+// common hosts when quick is set.
+func capWorkers(workers int, subnets []string, quick bool) int {
+	hosts := 0
+	for _, subnet := range subnets {
+		spec, err := network.ParseTargetSpec(subnet)
+		if err != nil {
+			return workers
+		}
+
+		for _, ipNet := range spec.IPNets() {
+			if quick {
+				hosts += len(network.NewSubnetHostsIterator(ipNet).CommonHosts())
+			} else {
+				hosts += network.CalculateTotalHosts(ipNet)
+			}
+		}
+	}
 
-	s.Run()
+	if hosts > 0 && hosts < workers {
+		return hosts
+	}
 
-	results, totalHostOnline := s.GetOnlineHosts()
+	return workers
+}
 
-	// Extract keys into a slice
-	keys := make([]net.IP, 0, len(results))
-	for key := range results {
-		keys = append(keys, net.ParseIP(key))
+// chainOnResult returns an OnResult callback calling first, when not nil, then next.
+func chainOnResult(first, next func(ip string, result ping.Result)) func(ip string, result ping.Result) {
+	if first == nil {
+		return next
 	}
 
-	// Sort the keys Based on byte comparison
-	sort.Slice(keys, func(i, j int) bool {
-		return bytes.Compare(keys[i].To16(), keys[j].To16()) < 0
+	return func(ip string, result ping.Result) {
+		first(ip, result)
+		next(ip, result)
+	}
+}
+
+// streamResult returns an OnResult callback writing each result with write, e.g. to a Sink or a CSVLog.
+// A failure to write is only reported once, so a consumer that went away does not flood the log.
+func streamResult(write func(ip string, result ping.Result) error) func(ip string, result ping.Result) {
+	var once sync.Once
+
+	return func(ip string, result ping.Result) {
+		if err := write(ip, result); err != nil {
+			once.Do(func() {
+				log.Printf("Warning: %v", err)
+			})
+		}
+	}
+}
+
+// writeNDJSON returns an OnResult callback writing each result kept by filter, or every result when filter is nil,
+// to stdout as a line of JSON, marked when the results of s are simulated. Each IP address is written once, even
+// when it is pinged again. A failure to write is only reported once, like in streamResult.
+func writeNDJSON(f *subping.NDJSONFormatter, s *subping.Subping, filter func(ping.Result) bool) func(ip string, result ping.Result) {
+	var (
+		mu      sync.Mutex
+		written = make(map[string]bool)
+	)
+
+	return streamResult(func(ip string, result ping.Result) error {
+		if filter != nil && !filter(result) {
+			return nil
+		}
+
+		mu.Lock()
+		seen := written[ip]
+		written[ip] = true
+		mu.Unlock()
+
+		if seen {
+			return nil
+		}
+
+		host := s.Host(ip, result)
+
+		return f.Format(os.Stdout, []subping.HostResult{host}, subping.ScanSummary{Simulated: s.Simulated})
 	})
+}
+
+// checkNDJSONFlags returns an error when a flag of cmd needs the complete results, which the ndjson output does not
+// wait for: it writes each host as soon as it is pinged, before the scan is retried and the results are resolved,
+// compared or sorted.
+func checkNDJSONFlags(cmd *cobra.Command) error {
+	for _, name := range []string{"resolve", "retry-on-all-offline", "scan-retries", "since", "sort"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s cannot be used with the ndjson output, which writes each host once pinged", name)
+		}
+	}
 
-	for _, ip := range keys {
-		// convert bytes to string in each line of IP
-		ipString := ip.String()
-		stats := results[ipString]
-		packetLossPercentageStr := fmt.Sprintf("%.2f %%", stats.PacketLoss)
+	return nil
+}
 
-		fmt.Printf(
-			"| %-39s | %-16s | %-14s |\n",
-			ipString, stats.AvgRtt.String(), packetLossPercentageStr)
+// unresolvedHosts returns the host names of results that could not be resolved.
+func unresolvedHosts(results []subping.HostResult) []subping.HostResult {
+	var unresolved []subping.HostResult
+	for _, host := range results {
+		if host.ResolutionError != "" {
+			unresolved = append(unresolved, host)
+		}
 	}
 
-	fmt.Println(`-------------------------------------------------------------------------------`)
+	return unresolved
+}
+
+// recordDispatch returns an OnDispatch callback appending each IP address to audit.
+// A failure to write is only reported once, like in streamResult.
+func recordDispatch(audit *export.AuditLog) func(ip string) {
+	var once sync.Once
+
+	return func(ip string) {
+		if err := audit.Record(ip); err != nil {
+			once.Do(func() {
+				log.Printf("Warning: %v", err)
+			})
+		}
+	}
+}
+
+// formatBytes returns n bytes in a human-readable binary unit, e.g. "1.5 KiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatRuns returns the IP ranges compactly, e.g. "10.0.0.1-10.0.0.3, 10.0.0.7".
+func formatRuns(runs [][2]net.IP) string {
+	if len(runs) == 0 {
+		return "none"
+	}
+
+	ranges := make([]string, 0, len(runs))
+	for _, run := range runs {
+		if run[0].Equal(run[1]) {
+			ranges = append(ranges, run[0].String())
+		} else {
+			ranges = append(ranges, run[0].String()+"-"+run[1].String())
+		}
+	}
+
+	return strings.Join(ranges, ", ")
+}
+
+// printScanContext prints the network environment of the scan.
+func printScanContext(ctx subping.ScanContext) {
+	fmt.Printf("Interface      : %s (%s, %s)\n", ctx.Interface, ctx.InterfaceIP, ctx.InterfaceMAC)
+	fmt.Printf("Gateway        : %s (%s)\n", ctx.GatewayIP, ctx.GatewayMAC)
+	fmt.Printf("Started at     : %s\n", ctx.Timestamp.Format(time.RFC3339))
+}
+
+// ipFamilyFlag returns the IP family of the host names selected by the -4 and -6 flags, or an error when both are set.
+func ipFamilyFlag(ipv4, ipv6 bool) (subping.IPFamily, error) {
+	switch {
+	case ipv4 && ipv6:
+		return "", errors.New("-4 and -6 cannot be used together")
+	case ipv4:
+		return subping.IPFamilyIPv4, nil
+	case ipv6:
+		return subping.IPFamilyIPv6, nil
+	default:
+		return subping.IPFamilyAny, nil
+	}
+}
+
+// checkTOSFlag checks that the --tos value fits the type of service field of the requests.
+func checkTOSFlag(tos int) error {
+	if tos < 0 || tos > ping.MaxTOS {
+		return fmt.Errorf("--tos must be between 0 and %d, got %d", ping.MaxTOS, tos)
+	}
+
+	return nil
+}
+
+// targetSubnets returns the subnets and the host names of the given targets. Subnets, single IP addresses, IPv4
+// wildcards such as "10.0.*.*" and ranges such as "10.0.0.1-20" or "10.0.0.0+500" are accepted. They are returned in
+// CIDR notation, except the ranges, written in full to be split by subping into the subnets covering them, so that
+// --inter-subnet-delay does not pause within a range. Host names are returned as is to be resolved by subping.
+func targetSubnets(targets []string) (subnets, hosts []string, err error) {
+	subnets = make([]string, 0, len(targets))
+	for _, target := range targets {
+		spec, err := network.ParseTargetSpec(target)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if spec.Kind == network.TargetHostname {
+			hosts = append(hosts, spec.Value)
+
+			continue
+		}
+
+		ipNets := spec.IPNets()
+		if len(ipNets) == 0 {
+			return nil, nil, fmt.Errorf("cannot scan %s %q: give a subnet in CIDR notation, an IP address, "+
+				"a range, a wildcard such as 10.0.*.* or a host name", spec.Kind, spec.Value)
+		}
+
+		if spec.Kind == network.TargetRange {
+			subnets = append(subnets, spec.Value)
+
+			continue
+		}
+
+		subnets = append(subnets, ipNets[0].String())
+	}
+
+	return subnets, hosts, nil
+}
+
+// parseDurationList parses a comma and/or space separated list of durations such as "100ms, 500ms 2s".
+// An empty string yields an empty list.
+func parseDurationList(str string) ([]time.Duration, error) {
+	fields := strings.FieldsFunc(str, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	durations := make([]time.Duration, 0, len(fields))
+	for _, field := range fields {
+		d, err := time.ParseDuration(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q in list: %w", field, err)
+		}
+
+		durations = append(durations, d)
+	}
+
+	return durations, nil
+}
+
+// readHostsFile reads a list of hosts from a file, one per line.
+// Blank lines and lines starting with "#" are ignored.
+func readHostsFile(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readTargets(f)
+}
+
+// stdinTargets returns targets with the "-" target replaced by the targets read from stdin, one per line, like in
+// readHostsFile. "-" may only be given once, and stdin must then hold at least one target.
+func stdinTargets(targets []string, stdin io.Reader) ([]string, error) {
+	expanded := make([]string, 0, len(targets))
+	read := false
+
+	for _, target := range targets {
+		if target != "-" {
+			expanded = append(expanded, target)
+
+			continue
+		}
+
+		if read {
+			return nil, errors.New("the - target, reading the targets from stdin, can only be given once")
+		}
+		read = true
+
+		lines, err := readTargets(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the targets from stdin: %w", err)
+		}
+
+		if len(lines) == 0 {
+			return nil, errors.New("no target read from stdin")
+		}
+
+		expanded = append(expanded, lines...)
+	}
+
+	return expanded, nil
+}
 
-	if showOfflineHostList {
-		fmt.Println("\nOffline hosts :")
-		for ip, stats := range s.Results {
-			if stats.PacketsRecv == 0 {
-				fmt.Printf(
-					" - %s\t(Loss: %s, Latency: %s)\n",
-					ip, fmt.Sprintf("%.2f %%", stats.PacketLoss), stats.AvgRtt.String(),
-				)
+// readTargets reads a list of targets from r, one per line.
+// Blank lines and lines starting with "#" are ignored.
+func readTargets(r io.Reader) ([]string, error) {
+	var hosts []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hosts = append(hosts, line)
+	}
+
+	return hosts, scanner.Err()
+}
+
+// copyResultsToClipboard writes the results of s in CSV format to the system clipboard.
+func copyResultsToClipboard(s *subping.Subping) error {
+	var buf bytes.Buffer
+	if err := s.WriteCSV(&buf); err != nil {
+		return err
+	}
+
+	return writeClipboard(buf.String())
+}
+
+// notifyInterrupt returns a context done on the first Ctrl-C. The default handler is restored as soon as the context
+// is done, so a second Ctrl-C exits immediately instead of waiting for the pings in flight. stop releases the context.
+func notifyInterrupt() (ctx context.Context, stop context.CancelFunc) {
+	ctx, stop = signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return ctx, stop
+}
+
+// runWatch scans the subnet every interval and prints host state changes until interrupted.
+// When csvFile is not nil, it is rewritten every csvFlushInterval with the latest result of each host.
+func runWatch(s *subping.Subping, interval time.Duration, csvFile *export.CSVFile) {
+	ctx, stop := notifyInterrupt()
+	defer stop()
+
+	if csvFile != nil {
+		done := make(chan struct{})
+		defer func() {
+			close(done)
+			flushCSVFile(csvFile)
+		}()
+
+		go func() {
+			ticker := time.NewTicker(csvFlushInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					flushCSVFile(csvFile)
+				}
 			}
+		}()
+	}
+
+	fmt.Printf("Watch interval : %s\n", interval.String())
+	fmt.Println(`-------------------------------------------------------------------------------`)
+
+	for event := range s.Watch(ctx, interval, 0) {
+		switch event.Type {
+		case subping.EventOnline:
+			fmt.Printf("[round %d] %s is online (Latency: %s)\n", event.Round, event.IP, event.Result.AvgRtt.String())
+		case subping.EventOffline:
+			fmt.Printf("[round %d] %s is offline\n", event.Round, event.IP)
+		case subping.EventStillOffline:
+			fmt.Printf("[round %d] %s is still offline for %d rounds\n", event.Round, event.IP, event.OfflineRounds)
 		}
 	}
 
-	elapsed := time.Since(startTime)
-	totalHostOffline := s.TargetsIterator.TotalHosts - totalHostOnline
+	if err := s.Err(); err != nil {
+		stop()
+		log.Fatal(err.Error())
+	}
+}
+
+// flushCSVFile rewrites the CSV file of watch mode, logging a warning when it fails.
+func flushCSVFile(f *export.CSVFile) {
+	if err := f.Flush(); err != nil {
+		log.Printf("Warning: failed to write the CSV file: %v", err)
+	}
+}
+
+// bannerFont returns the go-figure font for the given banner style.
+// It falls back to the default font with a warning when the style is not an available font.
+func bannerFont(style string) string {
+	if _, err := figure.Asset(path.Join("fonts", style+".flf")); err != nil {
+		log.Printf("Warning: banner style %q is not available, using %q", style, defaultBannerStyle)
+		return defaultBannerStyle
+	}
 
-	fmt.Printf("\nTotal Hosts Online  : %d\n", totalHostOnline)
-	fmt.Printf("Total Hosts Offline : %d\n", totalHostOffline)
-	fmt.Printf("Execution time      : %s\n\n", elapsed.String())
+	return style
 }
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// offlinePinger is a ping.Pinger that reports every target as offline.
+type offlinePinger struct{}
+
+func (offlinePinger) Ping(_ string, opts ping.Options) (ping.Result, error) {
+	return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+}
+
+func TestCopyResultsToClipboard(t *testing.T) {
+	s, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 1,
+		Pinger:     offlinePinger{},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	s.Run()
+
+	var copied string
+	original := writeClipboard
+	writeClipboard = func(text string) error {
+		copied = text
+		return nil
+	}
+	defer func() { writeClipboard = original }()
+
+	if err := copyResultsToClipboard(s); err != nil {
+		t.Fatalf("copyResultsToClipboard() error = %v", err)
+	}
+
+	var want bytes.Buffer
+	if err := s.WriteCSV(&want); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	if copied != want.String() {
+		t.Errorf("copyResultsToClipboard() copied =\n%s\nwant =\n%s", copied, want.String())
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	s, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.1/30",
+		Count:      1,
+		MaxWorkers: 1,
+		Pinger:     offlinePinger{},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printConfig(&buf, s); err != nil {
+		t.Fatalf("printConfig() error = %v", err)
+	}
+
+	var got struct {
+		LogLevel     string `json:"log_level"`
+		Subnet       string `json:"subnet"`
+		ScoreWeights struct {
+			LatencyReference string `json:"latency_reference"`
+		} `json:"score_weights"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("printConfig() printed invalid JSON: %v\n%s", err, buf.String())
+	}
+
+	if got.LogLevel != "error" || got.Subnet != "10.0.0.0/30" || got.ScoreWeights.LatencyReference != "100ms" {
+		t.Errorf("printConfig() printed %s, want the defaults applied", buf.String())
+	}
+}
+
+func TestBannerFont(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		want  string
+	}{
+		{
+			name:  "Default font",
+			style: defaultBannerStyle,
+			want:  defaultBannerStyle,
+		},
+		{
+			name:  "Available font",
+			style: "banner",
+			want:  "banner",
+		},
+		{
+			name:  "Unavailable font falls back to the default",
+			style: "not-a-font",
+			want:  defaultBannerStyle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bannerFont(tt.style); got != tt.want {
+				t.Errorf("bannerFont() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadHostsFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "hosts.txt")
+	content := "# gateways\n10.0.0.1\n\n  10.0.0.2  \n# 10.0.0.3\n"
+
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := readHostsFile(name)
+	if err != nil {
+		t.Fatalf("readHostsFile() error = %v", err)
+	}
+
+	want := []string{"10.0.0.1", "10.0.0.2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readHostsFile() = %v, want %v", got, want)
+	}
+}
+
+func TestStdinTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []string
+		stdin   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "Without -",
+			targets: []string{"10.0.0.0/24"},
+			stdin:   "10.0.1.0/24\n",
+			want:    []string{"10.0.0.0/24"},
+		},
+		{
+			name:    "Targets from stdin",
+			targets: []string{"10.0.0.0/24", "-", "db.example.com"},
+			stdin:   "# generated\n10.0.1.0/24\n\n  192.168.1.7  \n",
+			want:    []string{"10.0.0.0/24", "10.0.1.0/24", "192.168.1.7", "db.example.com"},
+		},
+		{
+			name:    "Empty stdin",
+			targets: []string{"-"},
+			stdin:   "# nothing\n\n",
+			wantErr: true,
+		},
+		{
+			name:    "- given twice",
+			targets: []string{"-", "-"},
+			stdin:   "10.0.1.0/24\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stdinTargets(tt.targets, strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("stdinTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stdinTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRuns(t *testing.T) {
+	tests := []struct {
+		name string
+		runs [][2]net.IP
+		want string
+	}{
+		{
+			name: "No run",
+			runs: nil,
+			want: "none",
+		},
+		{
+			name: "Ranges and single hosts",
+			runs: [][2]net.IP{
+				{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.3")},
+				{net.ParseIP("10.0.0.7"), net.ParseIP("10.0.0.7")},
+			},
+			want: "10.0.0.1-10.0.0.3, 10.0.0.7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatRuns(tt.runs); got != tt.want {
+				t.Errorf("formatRuns() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintSubnetStats(t *testing.T) {
+	var buf bytes.Buffer
+	printSubnetStats(&buf, []string{"10.0.0.0/29", "192.168.1.0/28"}, map[string]subping.SubnetStats{
+		"10.0.0.0/29":    {Total: 8, Online: 6, Offline: 2},
+		"192.168.1.0/28": {Total: 16, Online: 1, Offline: 15},
+	})
+
+	want := "Hosts online per subnet :\n - 10.0.0.0/29\t6 / 8\n - 192.168.1.0/28\t1 / 16\n\n"
+	if buf.String() != want {
+		t.Errorf("printSubnetStats() wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTargetSubnets(t *testing.T) {
+	tests := []struct {
+		name      string
+		targets   []string
+		want      []string
+		wantHosts []string
+		wantErr   bool
+	}{
+		{
+			name:    "Subnets, IP addresses and wildcards",
+			targets: []string{"10.0.0.0/24", "192.168.1.7", "172.16.*.*", "2001:db8::1"},
+			want:    []string{"10.0.0.0/24", "192.168.1.7/32", "172.16.0.0/16", "2001:db8::1/128"},
+		},
+		{
+			name:    "Ranges",
+			targets: []string{"10.0.1.1-4", "10.0.2.0+5"},
+			want:    []string{"10.0.1.1-10.0.1.4", "10.0.2.0-10.0.2.4"},
+		},
+		{
+			name:      "Host names",
+			targets:   []string{"DB.example.com.", "10.0.0.0/30", "www.example.com"},
+			want:      []string{"10.0.0.0/30"},
+			wantHosts: []string{"db.example.com", "www.example.com"},
+		},
+		{
+			name:    "Invalid target",
+			targets: []string{"10.0.0.0/33"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hosts, err := targetSubnets(tt.targets)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("targetSubnets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && (!reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(hosts, tt.wantHosts)) {
+				t.Errorf("targetSubnets() = %v, %v, want %v, %v", got, hosts, tt.want, tt.wantHosts)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{n: 0, want: "0 B"},
+		{n: 1023, want: "1023 B"},
+		{n: 1536, want: "1.5 KiB"},
+		{n: 13312, want: "13.0 KiB"},
+		{n: 3 << 20, want: "3.0 MiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCapWorkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnets []string
+		quick   bool
+		want    int
+	}{
+		{name: "Small subnet", subnets: []string{"10.0.0.0/30"}, want: 4},
+		{name: "Several small subnets", subnets: []string{"10.0.0.0/30", "10.0.1.0/29"}, want: 12},
+		{name: "Large subnet", subnets: []string{"10.0.0.0/24"}, want: 128},
+		{name: "Quick scan of a large subnet", subnets: []string{"10.0.0.0/24"}, quick: true, want: 13},
+		{name: "Range", subnets: []string{"10.0.0.1-10.0.0.20"}, want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := capWorkers(128, tt.subnets, tt.quick); got != tt.want {
+				t.Errorf("capWorkers() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnlyHosts(t *testing.T) {
+	results := []subping.HostResult{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}, {IP: "10.0.0.3"}}
+	changed := map[string]ping.Result{"10.0.0.3": {}, "10.0.0.1": {}}
+
+	want := []subping.HostResult{{IP: "10.0.0.1"}, {IP: "10.0.0.3"}}
+	if got := onlyHosts(results, changed); !reflect.DeepEqual(got, want) {
+		t.Errorf("onlyHosts() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReportWriter(t *testing.T) {
+	tests := []struct {
+		format string
+		want   *os.File
+	}{
+		{format: "table", want: os.Stdout},
+		{format: "json", want: os.Stderr},
+		{format: "csv", want: os.Stderr},
+	}
+	for _, tt := range tests {
+		if got := reportWriter(tt.format); got != tt.want {
+			t.Errorf("reportWriter(%q) = %v, want %v", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestTCPPortFlag(t *testing.T) {
+	cmd := newRootCmd()
+	if err := cmd.ParseFlags([]string{"--tcp-port", "443"}); err != nil {
+		t.Fatalf("ParseFlags() error => %v", err)
+	}
+	defer func() { tcpPort = 0 }()
+
+	if want := []int{443}; !reflect.DeepEqual(probedPorts(tcpPorts, tcpPort), want) {
+		t.Errorf("--tcp-port probes the ports %v, want %v", probedPorts(tcpPorts, tcpPort), want)
+	}
+
+	if f := cmd.Flags().Lookup("tcp-port"); f == nil || f.Hidden {
+		t.Error("--tcp-port is not a visible flag")
+	}
+
+	tests := []struct {
+		ports []int
+		port  int
+		want  []int
+	}{
+		{ports: nil, port: 0, want: nil},
+		{ports: []int{22, 80}, port: 0, want: []int{22, 80}},
+		{ports: []int{22, 80}, port: 443, want: []int{22, 80, 443}},
+		{ports: []int{22, 443}, port: 443, want: []int{22, 443}},
+	}
+	for _, tt := range tests {
+		if got := probedPorts(tt.ports, tt.port); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("probedPorts(%v, %d) = %v, want %v", tt.ports, tt.port, got, tt.want)
+		}
+	}
+}
+
+// tosPinger is a ping.Pinger recording the TOS of the requests, reporting every target as offline.
+type tosPinger struct {
+	mu  sync.Mutex
+	tos []int
+}
+
+func (p *tosPinger) Ping(_ string, opts ping.Options) (ping.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tos = append(p.tos, opts.TOS)
+
+	return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+}
+
+func TestTOSFlag(t *testing.T) {
+	cmd := newRootCmd()
+	if err := cmd.ParseFlags([]string{"--tos", "184"}); err != nil {
+		t.Fatalf("ParseFlags() error => %v", err)
+	}
+	defer func() { tos = 0 }()
+
+	if err := checkTOSFlag(tos); err != nil {
+		t.Fatalf("checkTOSFlag(%d) error = %v", tos, err)
+	}
+
+	pinger := &tosPinger{}
+
+	s, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/31",
+		Count:      1,
+		MaxWorkers: 1,
+		TOS:        tos,
+		Pinger:     pinger,
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	s.Run()
+
+	if want := []int{184, 184}; !reflect.DeepEqual(pinger.tos, want) {
+		t.Errorf("--tos 184 pinged with the TOS %v, want %v", pinger.tos, want)
+	}
+
+	for _, tos := range []int{-1, ping.MaxTOS + 1} {
+		if err := checkTOSFlag(tos); err == nil {
+			t.Errorf("checkTOSFlag(%d) did not fail", tos)
+		}
+	}
+}
+
+func TestCheckNDJSONFlags(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{args: nil},
+		{args: []string{"--count", "3", "--max-loss", "50"}},
+		{args: []string{"--sort", "latency"}, wantErr: true},
+		{args: []string{"--scan-retries", "2"}, wantErr: true},
+		{args: []string{"--retry-on-all-offline"}, wantErr: true},
+		{args: []string{"--resolve", "--since", "previous.json"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		cmd := newRootCmd()
+		if err := cmd.ParseFlags(tt.args); err != nil {
+			t.Fatalf("ParseFlags(%v) error = %v", tt.args, err)
+		}
+
+		if err := checkNDJSONFlags(cmd); (err != nil) != tt.wantErr {
+			t.Errorf("checkNDJSONFlags() with %v error = %v, wantErr %v", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	exporter, err := serveMetrics(addr)
+	if err != nil {
+		t.Fatalf("serveMetrics() error = %v", err)
+	}
+
+	exporter.Set("10.0.0.1", ping.Result{PacketsSent: 1, PacketsRecv: 1})
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	if want := `subping_host_up{ip="10.0.0.1"} 1`; !strings.Contains(string(body), want) {
+		t.Errorf("/metrics served %q, want it to hold %q", body, want)
+	}
+
+	if _, err := serveMetrics(addr); err == nil {
+		t.Error("serveMetrics() on an address already in use did not fail")
+	}
+}
+
+func TestIPFamilyFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		ipv4    bool
+		ipv6    bool
+		want    subping.IPFamily
+		wantErr bool
+	}{
+		{name: "Neither", want: subping.IPFamilyAny},
+		{name: "IPv4", ipv4: true, want: subping.IPFamilyIPv4},
+		{name: "IPv6", ipv6: true, want: subping.IPFamilyIPv6},
+		{name: "Both", ipv4: true, ipv6: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ipFamilyFlag(tt.ipv4, tt.ipv6)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ipFamilyFlag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ipFamilyFlag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultFilter(t *testing.T) {
+	fast := ping.Result{AvgRtt: 5 * time.Millisecond, PacketsSent: 4, PacketsRecv: 4}
+	slow := ping.Result{AvgRtt: 80 * time.Millisecond, PacketsSent: 4, PacketsRecv: 4}
+	lossy := ping.Result{AvgRtt: 5 * time.Millisecond, PacketsSent: 4, PacketsRecv: 2, PacketLoss: 50}
+	offline := ping.Result{PacketsSent: 4, PacketLoss: 100}
+
+	tests := []struct {
+		name       string
+		minLatency time.Duration
+		maxLatency time.Duration
+		maxLoss    float64
+		want       []bool
+	}{
+		{name: "Slow hosts", minLatency: 50 * time.Millisecond, maxLoss: 100, want: []bool{false, true, false, false}},
+		{name: "Fast hosts", maxLatency: 10 * time.Millisecond, maxLoss: 100, want: []bool{true, false, true, false}},
+		{name: "Lossless hosts", maxLoss: 0, want: []bool{true, true, false, false}},
+		{name: "Healthy hosts", maxLatency: 10 * time.Millisecond, maxLoss: 10, want: []bool{true, false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := resultFilter(tt.minLatency, tt.maxLatency, tt.maxLoss)
+
+			var got []bool
+			for _, r := range []ping.Result{fast, slow, lossy, offline} {
+				got = append(got, filter(r))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resultFilter() kept %v of fast, slow, lossy and offline, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if resultFilter(0, 0, 100) != nil {
+		t.Error("resultFilter() without bounds is not nil")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// progressInterval is the time between two progress frames.
+const progressInterval = 500 * time.Millisecond
+
+// progressFrame is a machine-readable progress report. ETAMs is -1 until the first target completes.
+type progressFrame struct {
+	Completed int64 `json:"completed"`
+	Total     int   `json:"total"`
+	ETAMs     int64 `json:"eta_ms"`
+}
+
+// progressReporter periodically writes the progress of a scan as JSON objects, one per line, to w.
+type progressReporter struct {
+	w         io.Writer
+	total     int
+	start     time.Time
+	completed atomic.Int64
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// openProgressFD returns the file of the inherited file descriptor fd, e.g. the write end of a pipe
+// set up by a frontend wrapping subping.
+func openProgressFD(fd int) (*os.File, error) {
+	if fd < 3 {
+		return nil, fmt.Errorf("invalid progress file descriptor %d: the standard streams cannot be used", fd)
+	}
+
+	f := os.NewFile(uintptr(fd), "progress")
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("invalid progress file descriptor %d: %w", fd, err)
+	}
+
+	return f, nil
+}
+
+// newProgressReporter returns a progressReporter of a scan of total targets, writing a frame to w every interval
+// until stop is called.
+func newProgressReporter(w io.Writer, total int, interval time.Duration) *progressReporter {
+	p := &progressReporter{
+		w:       w,
+		total:   total,
+		start:   time.Now(),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.write()
+			}
+		}
+	}()
+
+	return p
+}
+
+// observe is an OnResult callback counting the completed targets.
+func (p *progressReporter) observe(string, ping.Result) {
+	p.completed.Add(1)
+}
+
+// write writes the current progress frame. Write errors are ignored, so a frontend closing the
+// file descriptor does not abort the scan.
+func (p *progressReporter) write() {
+	completed := p.completed.Load()
+
+	frame := progressFrame{Completed: completed, Total: p.total, ETAMs: -1}
+	if completed > 0 {
+		remaining := int64(p.total) - completed
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		frame.ETAMs = time.Since(p.start).Milliseconds() * remaining / completed
+	}
+
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return
+	}
+
+	_, _ = p.w.Write(append(data, '\n'))
+}
+
+// stop stops the periodic frames and writes a final frame.
+func (p *progressReporter) stop() {
+	close(p.done)
+	<-p.stopped
+
+	p.write()
+}
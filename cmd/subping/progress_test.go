@@ -0,0 +1,80 @@
+//go:build unix
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestProgressReporter(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	defer r.Close()
+
+	// Hand a duplicate of the write end over, as a frontend would when spawning subping.
+	fd, err := syscall.Dup(int(w.Fd()))
+	if err != nil {
+		t.Fatalf("Dup() error = %v", err)
+	}
+	w.Close()
+
+	f, err := openProgressFD(fd)
+	if err != nil {
+		t.Fatalf("openProgressFD() error = %v", err)
+	}
+
+	frames := make(chan progressFrame)
+	go func() {
+		defer close(frames)
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			var frame progressFrame
+			if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+				t.Errorf("invalid progress frame %q: %v", scanner.Text(), err)
+				return
+			}
+
+			frames <- frame
+		}
+	}()
+
+	p := newProgressReporter(f, 4, 10*time.Millisecond)
+
+	first := <-frames
+	if first.Completed != 0 || first.Total != 4 || first.ETAMs != -1 {
+		t.Errorf("first frame = %+v, want {Completed:0 Total:4 ETAMs:-1}", first)
+	}
+
+	for i := 0; i < 4; i++ {
+		p.observe("10.0.0.1", ping.Result{})
+	}
+	p.stop()
+	f.Close()
+
+	var last progressFrame
+	for frame := range frames {
+		last = frame
+	}
+
+	if last.Completed != 4 || last.Total != 4 || last.ETAMs != 0 {
+		t.Errorf("last frame = %+v, want {Completed:4 Total:4 ETAMs:0}", last)
+	}
+}
+
+func TestOpenProgressFDInvalid(t *testing.T) {
+	for _, fd := range []int{1, 2, 1 << 20} {
+		if _, err := openProgressFD(fd); err == nil {
+			t.Errorf("openProgressFD(%d) error = nil, want an error", fd)
+		}
+	}
+}
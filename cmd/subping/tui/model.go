@@ -0,0 +1,214 @@
+// Package tui implements the interactive terminal UI of subping, a live-updating table of the hosts of a subnet
+// built on bubbletea.
+//
+// The Model is fed with RoundStartedMsg, ResultMsg and RoundFinishedMsg messages, typically sent from the
+// OnResult callback of a subping.Subping scanning the subnet repeatedly.
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// SortKey is the column the hosts are sorted by.
+type SortKey int
+
+const (
+	// SortByIP sorts the hosts by IP address.
+	SortByIP SortKey = iota
+
+	// SortByRTT sorts the hosts by ascending average RTT, the offline hosts last.
+	SortByRTT
+
+	// SortByStatus sorts the online hosts first, then by IP address.
+	SortByStatus
+)
+
+// String returns the name of the sort key.
+func (k SortKey) String() string {
+	switch k {
+	case SortByRTT:
+		return "rtt"
+	case SortByStatus:
+		return "status"
+	default:
+		return "ip"
+	}
+}
+
+// RoundStartedMsg reports that a scan round started.
+type RoundStartedMsg struct {
+	// Round is the one-based number of the round.
+	Round int
+
+	// Total is the number of hosts pinged in the round.
+	Total int
+}
+
+// ResultMsg reports the result of a host as soon as its ping completes.
+type ResultMsg struct {
+	// IP is the address of the host.
+	IP string
+
+	// Result is the ping result of the host.
+	Result ping.Result
+}
+
+// RoundFinishedMsg reports that a scan round finished.
+type RoundFinishedMsg struct {
+	// Elapsed is the time the round took.
+	Elapsed time.Duration
+}
+
+var (
+	onlineStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	offlineStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	headerStyle  = lipgloss.NewStyle().Bold(true)
+)
+
+// Model is the bubbletea model of the live-updating host table.
+type Model struct {
+	// Subnet is the scanned subnet, displayed in the header.
+	Subnet string
+
+	hosts     map[string]ping.Result
+	round     int
+	total     int
+	completed int
+	elapsed   time.Duration
+	sortKey   SortKey
+}
+
+// NewModel returns the model of the host table of the given subnet.
+func NewModel(subnet string) Model {
+	return Model{Subnet: subnet, hosts: make(map[string]ping.Result)}
+}
+
+// Init implements tea.Model. It starts no command.
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model. The "s" key cycles through the sort keys, and "q" or ctrl+c quits.
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case RoundStartedMsg:
+		m.round = msg.Round
+		m.total = msg.Total
+		m.completed = 0
+	case ResultMsg:
+		m.hosts[msg.IP] = msg.Result
+		m.completed++
+	case RoundFinishedMsg:
+		m.total = m.completed
+		m.elapsed = msg.Elapsed
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "s":
+			m.sortKey = (m.sortKey + 1) % 3
+		}
+	}
+
+	return m, nil
+}
+
+// Counts returns the number of online and offline hosts.
+func (m Model) Counts() (online, offline int) {
+	for _, r := range m.hosts {
+		if r.PacketsRecv > 0 {
+			online++
+		} else {
+			offline++
+		}
+	}
+
+	return online, offline
+}
+
+// Progress returns the number of hosts completed in the current round and the number of hosts in the round.
+func (m Model) Progress() (completed, total int) {
+	return m.completed, m.total
+}
+
+// SortKey returns the column the hosts are sorted by.
+func (m Model) SortKey() SortKey {
+	return m.sortKey
+}
+
+// SortedIPs returns the IP addresses of the hosts in display order.
+func (m Model) SortedIPs() []string {
+	ips := make([]string, 0, len(m.hosts))
+	for ip := range m.hosts {
+		ips = append(ips, ip)
+	}
+
+	byIP := func(i, j int) bool {
+		return bytes.Compare(net.ParseIP(ips[i]).To16(), net.ParseIP(ips[j]).To16()) < 0
+	}
+
+	sort.Slice(ips, func(i, j int) bool {
+		a, b := m.hosts[ips[i]], m.hosts[ips[j]]
+		aOnline, bOnline := a.PacketsRecv > 0, b.PacketsRecv > 0
+
+		switch m.sortKey {
+		case SortByRTT:
+			if aOnline != bOnline {
+				return aOnline
+			}
+			if aOnline && a.AvgRtt != b.AvgRtt {
+				return a.AvgRtt < b.AvgRtt
+			}
+		case SortByStatus:
+			if aOnline != bOnline {
+				return aOnline
+			}
+		}
+
+		return byIP(i, j)
+	})
+
+	return ips
+}
+
+// View implements tea.Model.
+func (m Model) View() string {
+	var b strings.Builder
+
+	online, offline := m.Counts()
+
+	fmt.Fprintln(&b, headerStyle.Render(fmt.Sprintf("subping %s - round %d", m.Subnet, m.round)))
+	fmt.Fprintf(&b, "Progress: %d/%d  Online: %s  Offline: %s  Last round: %s  Sort: %s\n\n",
+		m.completed, m.total,
+		onlineStyle.Render(fmt.Sprint(online)), offlineStyle.Render(fmt.Sprint(offline)),
+		m.elapsed.Round(time.Millisecond), m.sortKey)
+
+	fmt.Fprintln(&b, headerStyle.Render(fmt.Sprintf("%-39s  %-8s  %-12s  %s", "IP Address", "Status", "Avg RTT", "Loss")))
+
+	for _, ip := range m.SortedIPs() {
+		r := m.hosts[ip]
+
+		status, style := "offline", offlineStyle
+		rtt := "-"
+		if r.PacketsRecv > 0 {
+			status, style = "online", onlineStyle
+			rtt = r.AvgRtt.String()
+		}
+
+		fmt.Fprintf(&b, "%-39s  %s  %-12s  %.0f%%\n", ip, style.Render(fmt.Sprintf("%-8s", status)), rtt, r.PacketLoss)
+	}
+
+	fmt.Fprintln(&b, "\ns: change sort  q: quit")
+
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package tui_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/fadhilyori/subping/cmd/subping/tui"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// update applies msgs to m in order and returns the resulting model.
+func update(t *testing.T, m tea.Model, msgs ...tea.Msg) tui.Model {
+	t.Helper()
+
+	for _, msg := range msgs {
+		m, _ = m.Update(msg)
+	}
+
+	return m.(tui.Model)
+}
+
+func TestModelUpdate(t *testing.T) {
+	online := func(rtt time.Duration) ping.Result {
+		return ping.Result{AvgRtt: rtt, PacketsSent: 1, PacketsRecv: 1}
+	}
+	offline := ping.Result{PacketLoss: 100, PacketsSent: 1}
+
+	m := update(t, tui.NewModel("10.0.0.0/29"),
+		tui.RoundStartedMsg{Round: 1, Total: 4},
+		tui.ResultMsg{IP: "10.0.0.3", Result: online(5 * time.Millisecond)},
+		tui.ResultMsg{IP: "10.0.0.1", Result: offline},
+		tui.ResultMsg{IP: "10.0.0.2", Result: online(time.Millisecond)},
+	)
+
+	if completed, total := m.Progress(); completed != 3 || total != 4 {
+		t.Errorf("Progress() = %d/%d, want 3/4", completed, total)
+	}
+
+	if on, off := m.Counts(); on != 2 || off != 1 {
+		t.Errorf("Counts() = %d online, %d offline, want 2 online, 1 offline", on, off)
+	}
+
+	sortTests := []struct {
+		key  tui.SortKey
+		want []string
+	}{
+		{key: tui.SortByIP, want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{key: tui.SortByRTT, want: []string{"10.0.0.2", "10.0.0.3", "10.0.0.1"}},
+		{key: tui.SortByStatus, want: []string{"10.0.0.2", "10.0.0.3", "10.0.0.1"}},
+		{key: tui.SortByIP, want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+	}
+	for i, tt := range sortTests {
+		if i > 0 {
+			m = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+		}
+
+		if m.SortKey() != tt.key {
+			t.Fatalf("SortKey() = %v, want %v", m.SortKey(), tt.key)
+		}
+
+		if got := m.SortedIPs(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SortedIPs() sorted by %v = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+
+	// A host changing state in the next round is updated in place.
+	m = update(t, m,
+		tui.RoundFinishedMsg{Elapsed: time.Second},
+		tui.RoundStartedMsg{Round: 2, Total: 4},
+		tui.ResultMsg{IP: "10.0.0.1", Result: online(2 * time.Millisecond)},
+	)
+
+	if completed, total := m.Progress(); completed != 1 || total != 4 {
+		t.Errorf("Progress() = %d/%d in round 2, want 1/4", completed, total)
+	}
+
+	if on, off := m.Counts(); on != 3 || off != 0 {
+		t.Errorf("Counts() = %d online, %d offline in round 2, want 3 online, 0 offline", on, off)
+	}
+}
+
+func TestModelQuit(t *testing.T) {
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("q")},
+		{Type: tea.KeyCtrlC},
+	} {
+		_, cmd := tui.NewModel("10.0.0.0/30").Update(key)
+		if cmd == nil {
+			t.Errorf("Update(%q) returned no command, want tea.Quit", key.String())
+			continue
+		}
+
+		if _, ok := cmd().(tea.QuitMsg); !ok {
+			t.Errorf("Update(%q) did not quit", key.String())
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build tui
+
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/cmd/subping/tui"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func init() {
+	runTUI = watchTUI
+}
+
+// watchTUI scans s every interval and displays the results live in the terminal UI until the user quits.
+func watchTUI(s *subping.Subping, interval time.Duration) error {
+	p := tea.NewProgram(tui.NewModel(strings.Join(s.Subnets, ", ")), tea.WithAltScreen())
+
+	s.OnResult = chainOnResult(s.OnResult, func(ip string, result ping.Result) {
+		p.Send(tui.ResultMsg{IP: ip, Result: result})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		for round := 1; ; round++ {
+			p.Send(tui.RoundStartedMsg{Round: round, Total: s.TotalHosts()})
+
+			// Quitting the TUI cancels ctx, which stops the round in progress. A round interrupted by
+			// MaxDuration instead is finished as usual.
+			if err := s.RunContext(ctx); err != nil && ctx.Err() != nil {
+				return
+			}
+			p.Send(tui.RoundFinishedMsg{Elapsed: s.Elapsed})
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+
+	_, err := p.Run()
+
+	return err
+}
@@ -0,0 +1,58 @@
+package subping
+
+import (
+	"encoding/json"
+)
+
+// EffectiveOptions returns the options as resolved by NewSubping, with the defaults applied:
+// the log level, the canonical subnet, the shuffle seed and the score weights.
+// It allows inspecting the configuration actually used when it comes from several sources.
+func (s *Subping) EffectiveOptions() Options {
+	return s.config
+}
+
+// MarshalJSON encodes the options as JSON, rendering the durations in their string form, e.g. "300ms".
+// The Pinger is omitted.
+func (o Options) MarshalJSON() ([]byte, error) {
+	type options Options
+
+	timeouts := make([]string, 0, len(o.Timeouts))
+	for _, timeout := range o.Timeouts {
+		timeouts = append(timeouts, timeout.String())
+	}
+
+	return json.Marshal(struct {
+		options
+		Interval         string   `json:"interval"`
+		Timeout          string   `json:"timeout"`
+		Timeouts         []string `json:"timeouts"`
+		IntervalJitter   string   `json:"interval_jitter"`
+		ScanRetryBackoff string   `json:"scan_retry_backoff"`
+		RetryBackoff     string   `json:"retry_backoff"`
+		InterSubnetDelay string   `json:"inter_subnet_delay"`
+		MaxDuration      string   `json:"max_duration"`
+	}{
+		options:          options(o),
+		Interval:         o.Interval.String(),
+		Timeout:          o.Timeout.String(),
+		Timeouts:         timeouts,
+		IntervalJitter:   o.IntervalJitter.String(),
+		ScanRetryBackoff: o.ScanRetryBackoff.String(),
+		RetryBackoff:     o.RetryBackoff.String(),
+		InterSubnetDelay: o.InterSubnetDelay.String(),
+		MaxDuration:      o.MaxDuration.String(),
+	})
+}
+
+// MarshalJSON encodes the weights as JSON, rendering LatencyReference in its string form, e.g. "100ms".
+func (w ScoreWeights) MarshalJSON() ([]byte, error) {
+	type scoreWeights ScoreWeights
+
+	return json.Marshal(struct {
+		scoreWeights
+		LatencyReference string `json:"latency_reference"`
+	}{
+		scoreWeights:     scoreWeights(w),
+		LatencyReference: w.LatencyReference.String(),
+	})
+}
@@ -0,0 +1,87 @@
+package subping_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+)
+
+func TestEffectiveOptions(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:           "192.168.1.7/30",
+		Count:            2,
+		Interval:         300 * time.Millisecond,
+		Timeout:          time.Second,
+		Timeouts:         []time.Duration{100 * time.Millisecond, 2 * time.Second},
+		Retries:          1,
+		RetryBackoff:     5 * time.Millisecond,
+		InterSubnetDelay: time.Second,
+		MaxDuration:      30 * time.Second,
+		MaxWorkers:       4,
+		Shuffle:          true,
+		Pinger:           &stubPinger{},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	opts := sp.EffectiveOptions()
+
+	if opts.LogLevel != "error" {
+		t.Errorf("LogLevel = %q, want %q", opts.LogLevel, "error")
+	}
+
+	if opts.Subnet != "192.168.1.4/30" {
+		t.Errorf("Subnet = %q, want %q", opts.Subnet, "192.168.1.4/30")
+	}
+
+	if opts.Seed == 0 || opts.Seed != sp.Seed {
+		t.Errorf("Seed = %d, want the resolved seed %d", opts.Seed, sp.Seed)
+	}
+
+	wantWeights := subping.ScoreWeights{LossWeight: 1, LatencyWeight: 1, LatencyReference: 100 * time.Millisecond}
+	if opts.ScoreWeights != wantWeights {
+		t.Errorf("ScoreWeights = %+v, want %+v", opts.ScoreWeights, wantWeights)
+	}
+
+	data, err := json.Marshal(opts)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"log_level":          "error",
+		"subnet":             "192.168.1.4/30",
+		"count":              float64(2),
+		"interval":           "300ms",
+		"timeout":            "1s",
+		"timeouts":           []interface{}{"100ms", "2s"},
+		"max_workers":        float64(4),
+		"max_hosts":          float64(subping.DefaultMaxHosts),
+		"retry_backoff":      "5ms",
+		"inter_subnet_delay": "1s",
+		"max_duration":       "30s",
+		"score_weights": map[string]interface{}{
+			"loss_weight":       float64(1),
+			"latency_weight":    float64(1),
+			"latency_reference": "100ms",
+		},
+	}
+	for key, value := range want {
+		if !reflect.DeepEqual(got[key], value) {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+
+	if _, ok := got["Pinger"]; ok {
+		t.Errorf("the pinger should not be encoded: %s", data)
+	}
+}
@@ -0,0 +1,67 @@
+package subping
+
+import (
+	"net"
+	"strings"
+)
+
+// Resolver returns the names pointing to an IP address, i.e. its PTR records.
+// net.LookupAddr is a Resolver.
+type Resolver func(addr string) ([]string, error)
+
+// DualStackResult groups the results of the IPv4 and IPv6 addresses of a single host.
+type DualStackResult struct {
+	// Hostname is the name shared by the PTR records of the addresses, without the trailing dot.
+	Hostname string
+
+	// IPv4 holds the results of the IPv4 addresses of the host, sorted by IP address.
+	IPv4 []HostResult
+
+	// IPv6 holds the results of the IPv6 addresses of the host, sorted by IP address.
+	IPv6 []HostResult
+
+	// IPv4Online reports whether at least one IPv4 address of the host replied.
+	IPv4Online bool
+
+	// IPv6Online reports whether at least one IPv6 address of the host replied.
+	IPv6Online bool
+}
+
+// CorrelateByHostname reverse-resolves the IP addresses of results with resolve and groups the IPv4
+// and IPv6 addresses sharing a PTR record, giving a per-host dual-stack reachability view of scans of
+// both the IPv4 and IPv6 ranges of the same hosts. The returned map is keyed by lower-cased hostname.
+//
+// Only the first name returned for an address is used. Addresses that cannot be resolved are left out.
+func CorrelateByHostname(results map[string]Result, resolve Resolver) map[string]DualStackResult {
+	correlated := make(map[string]DualStackResult)
+
+	for _, ip := range sortIPs(results) {
+		names, err := resolve(ip)
+		if err != nil || len(names) == 0 {
+			continue
+		}
+
+		hostname := strings.ToLower(strings.TrimSuffix(names[0], "."))
+		if hostname == "" {
+			continue
+		}
+
+		host := correlated[hostname]
+		host.Hostname = hostname
+
+		result := results[ip]
+		online := result.PacketsRecv > 0
+
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() != nil {
+			host.IPv4 = append(host.IPv4, HostResult{IP: ip, Result: result})
+			host.IPv4Online = host.IPv4Online || online
+		} else {
+			host.IPv6 = append(host.IPv6, HostResult{IP: ip, Result: result})
+			host.IPv6Online = host.IPv6Online || online
+		}
+
+		correlated[hostname] = host
+	}
+
+	return correlated
+}
@@ -0,0 +1,56 @@
+package subping_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+)
+
+func TestCorrelateByHostname(t *testing.T) {
+	ptr := map[string][]string{
+		"192.0.2.10":  {"db.example.com."},
+		"2001:db8::a": {"DB.example.com."},
+		"192.0.2.11":  {"web.example.com."},
+	}
+
+	resolve := func(addr string) ([]string, error) {
+		names, ok := ptr[addr]
+		if !ok {
+			return nil, errors.New("no PTR record")
+		}
+
+		return names, nil
+	}
+
+	online := subping.Result{AvgRtt: time.Millisecond, PacketsSent: 1, PacketsRecv: 1}
+	offline := subping.Result{PacketLoss: 100, PacketsSent: 1}
+
+	results := map[string]subping.Result{
+		"192.0.2.10":  online,
+		"2001:db8::a": offline,
+		"192.0.2.11":  online,
+		"192.0.2.12":  online,
+	}
+
+	want := map[string]subping.DualStackResult{
+		"db.example.com": {
+			Hostname:   "db.example.com",
+			IPv4:       []subping.HostResult{{IP: "192.0.2.10", Result: online}},
+			IPv6:       []subping.HostResult{{IP: "2001:db8::a", Result: offline}},
+			IPv4Online: true,
+			IPv6Online: false,
+		},
+		"web.example.com": {
+			Hostname:   "web.example.com",
+			IPv4:       []subping.HostResult{{IP: "192.0.2.11", Result: online}},
+			IPv4Online: true,
+		},
+	}
+
+	if got := subping.CorrelateByHostname(results, resolve); !reflect.DeepEqual(got, want) {
+		t.Errorf("CorrelateByHostname() =\n%+v\nwant =\n%+v", got, want)
+	}
+}
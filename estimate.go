@@ -0,0 +1,49 @@
+package subping
+
+import "time"
+
+// EstimatedDuration returns the worst-case duration of a run, assuming every target times out on every attempt:
+// the targets are spread evenly over the MaxWorkers workers, and each worker spends the timeouts of Retries+1
+// attempts on a target, then pauses Interval before the next one, and the scan pauses InterSubnetDelay between
+// subnets. Comparing it with Elapsed, see DurationAccuracy, helps tuning MaxWorkers and Interval.
+func (s *Subping) EstimatedDuration() time.Duration {
+	perTarget := s.Interval
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		perTarget += s.attemptTimeout(attempt)
+	}
+
+	workers := s.MaxWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	rounds := (s.TotalHosts() + workers - 1) / workers
+
+	return time.Duration(rounds)*perTarget + time.Duration(s.subnetPauses())*s.InterSubnetDelay
+}
+
+// subnetPauses returns the number of InterSubnetDelay pauses of a run, one before each given subnet or range but the
+// first, see Options.InterSubnetDelay.
+func (s *Subping) subnetPauses() int {
+	pauses := 0
+	previous := s.targetOf[s.TargetsIterator.IPNet.String()]
+	for _, ipNet := range s.extraSubnets {
+		if target := s.targetOf[ipNet.String()]; target != previous {
+			previous = target
+			pauses++
+		}
+	}
+
+	return pauses
+}
+
+// DurationAccuracy returns the ratio of the Elapsed time of the last run to its EstimatedDuration: close to 1 when
+// most targets timed out, and lower when they replied quickly. It returns 0 when the estimate is zero.
+func (s *Subping) DurationAccuracy() float64 {
+	estimated := s.EstimatedDuration()
+	if estimated <= 0 {
+		return 0
+	}
+
+	return float64(s.Elapsed) / float64(estimated)
+}
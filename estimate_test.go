@@ -0,0 +1,81 @@
+package subping_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// timingOutPinger is a ping.Pinger reporting every target offline after waiting for its timeout on clock.
+type timingOutPinger struct {
+	clock *recordingClock
+}
+
+func (p timingOutPinger) Ping(_ string, opts ping.Options) (ping.Result, error) {
+	p.clock.Sleep(opts.Timeout)
+
+	return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+}
+
+func TestDurationAccuracy(t *testing.T) {
+	tests := []struct {
+		name          string
+		retries       int
+		timeouts      []time.Duration
+		wantEstimated time.Duration
+		wantElapsed   time.Duration
+	}{
+		{
+			name: "Single attempt",
+			// 4 targets × (100ms timeout + 10ms interval).
+			wantEstimated: 440 * time.Millisecond,
+			// 4 timeouts and the pauses before the 3 targets following the first.
+			wantElapsed: 430 * time.Millisecond,
+		},
+		{
+			name:     "Retries with per-attempt timeouts",
+			retries:  2,
+			timeouts: []time.Duration{50 * time.Millisecond, 200 * time.Millisecond},
+			// 4 targets × (50ms + 200ms + 200ms timeouts + 10ms interval).
+			wantEstimated: 1840 * time.Millisecond,
+			wantElapsed:   1830 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &recordingClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     "10.0.0.0/30",
+				Count:      1,
+				Interval:   10 * time.Millisecond,
+				Timeout:    100 * time.Millisecond,
+				Timeouts:   tt.timeouts,
+				Retries:    tt.retries,
+				MaxWorkers: 1,
+				Pinger:     timingOutPinger{clock: clock},
+				Clock:      clock,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			if got := sp.EstimatedDuration(); got != tt.wantEstimated {
+				t.Errorf("EstimatedDuration() = %s, want %s", got, tt.wantEstimated)
+			}
+
+			sp.Run()
+
+			if sp.Elapsed != tt.wantElapsed {
+				t.Errorf("Elapsed = %s, want %s", sp.Elapsed, tt.wantElapsed)
+			}
+
+			want := float64(tt.wantElapsed) / float64(tt.wantEstimated)
+			if got := sp.DurationAccuracy(); got != want {
+				t.Errorf("DurationAccuracy() = %v, want %v", got, want)
+			}
+		})
+	}
+}
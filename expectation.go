@@ -0,0 +1,42 @@
+package subping
+
+// ExpectationReport describes how the results of a scan deviate from the expected online hosts.
+type ExpectationReport struct {
+	// MissingHosts lists the expected hosts that are not online, sorted by IP address.
+	MissingHosts []string
+
+	// UnexpectedHosts lists the online hosts that were not expected, sorted by IP address.
+	UnexpectedHosts []string
+}
+
+// OK reports whether the scan matched the expectation.
+func (r ExpectationReport) OK() bool {
+	return len(r.MissingHosts) == 0 && len(r.UnexpectedHosts) == 0
+}
+
+// VerifyExpectation compares the results of the last run with the hosts expected to be online.
+// Expected hosts that are offline or were not scanned are reported as missing, and online hosts
+// absent from expectedOnline are reported as unexpected. IPv6 addresses may be written in any notation.
+func (s *Subping) VerifyExpectation(expectedOnline []string) ExpectationReport {
+	expected := make(map[string]Result, len(expectedOnline))
+	for _, ip := range expectedOnline {
+		expected[normalizeKey(ip)] = Result{}
+	}
+
+	var report ExpectationReport
+
+	for _, ip := range sortIPs(expected) {
+		if r, ok := s.Results[ip]; !ok || r.PacketsRecv == 0 {
+			report.MissingHosts = append(report.MissingHosts, ip)
+		}
+	}
+
+	online, _ := s.GetOnlineHosts()
+	for _, ip := range sortIPs(online) {
+		if _, ok := expected[ip]; !ok {
+			report.UnexpectedHosts = append(report.UnexpectedHosts, ip)
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,76 @@
+package subping_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+)
+
+func TestVerifyExpectation(t *testing.T) {
+	// 10.0.0.0 is expected and up, 10.0.0.1 is expected but down,
+	// 10.0.0.2 is unexpected but up, and 10.0.0.3 is unexpected and down.
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger: &stubPinger{online: map[string]time.Duration{
+			"10.0.0.0": time.Millisecond,
+			"10.0.0.2": time.Millisecond,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	tests := []struct {
+		name     string
+		expected []string
+		want     subping.ExpectationReport
+		wantOK   bool
+	}{
+		{
+			name:     "Expected up and actually up",
+			expected: []string{"10.0.0.0", "10.0.0.2"},
+			want:     subping.ExpectationReport{},
+			wantOK:   true,
+		},
+		{
+			name:     "Expected up but actually down",
+			expected: []string{"10.0.0.0", "10.0.0.1", "10.0.0.2"},
+			want:     subping.ExpectationReport{MissingHosts: []string{"10.0.0.1"}},
+		},
+		{
+			name:     "Not expected but actually up",
+			expected: []string{"10.0.0.0"},
+			want:     subping.ExpectationReport{UnexpectedHosts: []string{"10.0.0.2"}},
+		},
+		{
+			name:     "Not expected and actually down",
+			expected: []string{"10.0.0.0", "10.0.0.2"},
+			want:     subping.ExpectationReport{},
+			wantOK:   true,
+		},
+		{
+			name:     "Expected host outside the subnet",
+			expected: []string{"10.0.0.0", "10.0.0.2", "10.0.1.1"},
+			want:     subping.ExpectationReport{MissingHosts: []string{"10.0.1.1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sp.VerifyExpectation(tt.expected)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VerifyExpectation() = %+v, want %+v", got, tt.want)
+			}
+
+			if got.OK() != tt.wantOK {
+				t.Errorf("VerifyExpectation().OK() = %v, want %v", got.OK(), tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package subping
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sort"
+)
+
+// WriteCSV writes the results of the last run to w in CSV format.
+// It writes a header row followed by one row per host, sorted by IP address.
+// The average latency is written in RTTUnit, defaulting to milliseconds.
+func (s *Subping) WriteCSV(w io.Writer) error {
+	return (&CSVFormatter{RTTUnit: s.RTTUnit}).Format(w, s.SortedResults(), s.Summary())
+}
+
+// numericRTTUnit returns unit, or RTTUnitMilliseconds when unit renders durations automatically.
+func numericRTTUnit(unit RTTUnit) RTTUnit {
+	if unit == RTTUnitAuto || unit == "" {
+		return RTTUnitMilliseconds
+	}
+
+	return unit
+}
+
+// sortIPs returns the keys of results sorted by their byte representation,
+// so IPv4 addresses come before IPv6 addresses and both are in numeric order.
+func sortIPs(results map[string]Result) []string {
+	ips := make([]string, 0, len(results))
+	for ip := range results {
+		ips = append(ips, ip)
+	}
+
+	sort.Slice(ips, func(i, j int) bool {
+		return bytes.Compare(net.ParseIP(ips[i]).To16(), net.ParseIP(ips[j]).To16()) < 0
+	})
+
+	return ips
+}
@@ -0,0 +1,134 @@
+package subping_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+)
+
+func TestWriteCSV(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      2,
+		MaxWorkers: 2,
+		Pinger: &stubPinger{online: map[string]time.Duration{
+			"10.0.0.1": 1500 * time.Microsecond,
+			"10.0.0.2": 20 * time.Millisecond,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	var buf bytes.Buffer
+	if err := sp.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	want := "ip,avg_latency_ms,packet_loss,packets_sent,packets_recv,online\n" +
+		"10.0.0.0,0.000,100.00,2,0,false\n" +
+		"10.0.0.1,1.500,0.00,2,2,true\n" +
+		"10.0.0.2,20.000,0.00,2,2,true\n" +
+		"10.0.0.3,0.000,100.00,2,0,false\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() got =\n%s\nwant =\n%s", got, want)
+	}
+}
+
+func TestWriteCSVWithRTTUnit(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.1/32",
+		Count:      1,
+		MaxWorkers: 1,
+		RTTUnit:    subping.RTTUnitMicroseconds,
+		Pinger:     &stubPinger{online: map[string]time.Duration{"10.0.0.1": 1500 * time.Microsecond}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	var buf bytes.Buffer
+	if err := sp.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	want := "ip,avg_latency_us,packet_loss,packets_sent,packets_recv,online\n" +
+		"10.0.0.1,1500.000,0.00,1,1,true\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() got =\n%s\nwant =\n%s", got, want)
+	}
+}
+
+func TestWriteCSVSortsNumerically(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.8/29",
+		Count:      1,
+		MaxWorkers: 4,
+		Pinger: &stubPinger{online: map[string]time.Duration{
+			"10.0.0.9":  time.Millisecond,
+			"10.0.0.10": time.Millisecond,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	var buf bytes.Buffer
+	if err := sp.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	// 10.0.0.9 sorts after 10.0.0.10 as a string, but before it as an IP address.
+	want := "ip,avg_latency_ms,packet_loss,packets_sent,packets_recv,online\n" +
+		"10.0.0.8,0.000,100.00,1,0,false\n" +
+		"10.0.0.9,1.000,0.00,1,1,true\n" +
+		"10.0.0.10,1.000,0.00,1,1,true\n" +
+		"10.0.0.11,0.000,100.00,1,0,false\n" +
+		"10.0.0.12,0.000,100.00,1,0,false\n" +
+		"10.0.0.13,0.000,100.00,1,0,false\n" +
+		"10.0.0.14,0.000,100.00,1,0,false\n" +
+		"10.0.0.15,0.000,100.00,1,0,false\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() got =\n%s\nwant =\n%s", got, want)
+	}
+}
+
+// failingWriter is an io.Writer whose writes always fail with err.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestWriteCSVWriteError(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.1/32",
+		Count:      1,
+		MaxWorkers: 1,
+		Pinger:     &stubPinger{},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	errDiskFull := errors.New("disk full")
+	if err := sp.WriteCSV(failingWriter{err: errDiskFull}); !errors.Is(err, errDiskFull) {
+		t.Errorf("WriteCSV() error = %v, want %v", err, errDiskFull)
+	}
+}
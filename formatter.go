@@ -0,0 +1,893 @@
+package subping
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostResult pairs a target IP address with its ping result.
+type HostResult struct {
+	// IP is the address of the target.
+	IP string
+
+	// Result is the ping result of the target.
+	Result Result
+
+	// Labels holds the labels attached to the target through Options.Labels. It is nil for unlabeled targets.
+	Labels map[string]string
+
+	// Country is the ISO 3166-1 alpha-2 country code of the target, when Options.Locator is set and locates it.
+	Country string
+
+	// City is the city of the target, when Options.Locator is set and knows it.
+	City string
+
+	// ResolutionError is the error resolving a host name of Options.Hosts that could not be resolved, see
+	// Subping.UnresolvedHosts. IP then holds the host name and Result is zero, since the host was not pinged.
+	ResolutionError string
+}
+
+// ScanSummary holds the aggregated figures of a scan.
+type ScanSummary struct {
+	// TotalHosts is the number of hosts that were pinged.
+	TotalHosts int
+
+	// OnlineHosts is the number of hosts that replied.
+	OnlineHosts int
+
+	// OfflineHosts is the number of hosts that did not reply.
+	OfflineHosts int
+
+	// UnresolvedHosts is the number of host names of Options.Hosts that could not be resolved, so were not pinged.
+	UnresolvedHosts int
+
+	// Duration is the time the scan took.
+	Duration time.Duration
+
+	// EstimatedDuration is the worst-case duration of the scan, see Subping.EstimatedDuration.
+	// It is zero when unknown.
+	EstimatedDuration time.Duration
+
+	// Simulated reports whether the results come from the mock pinger, see Subping.Simulated. Every formatter marks
+	// such results, so they cannot be mistaken for a real scan.
+	Simulated bool
+}
+
+// durationAccuracy returns the ratio of the duration of the scan to its estimated duration, or 0 when unknown.
+func (s ScanSummary) durationAccuracy() float64 {
+	if s.EstimatedDuration <= 0 {
+		return 0
+	}
+
+	return float64(s.Duration) / float64(s.EstimatedDuration)
+}
+
+// Formatter renders the results of a scan in a given output format.
+type Formatter interface {
+	// Format writes the results, sorted as they should be displayed, and the summary of the scan to w.
+	Format(w io.Writer, results []HostResult, summary ScanSummary) error
+}
+
+var (
+	// formattersMu guards formatters.
+	formattersMu sync.RWMutex
+
+	// formatters holds the registered formatters by name.
+	formatters = map[string]Formatter{
+		"table":    &TableFormatter{},
+		"csv":      &CSVFormatter{},
+		"json":     &JSONFormatter{},
+		"markdown": &MarkdownFormatter{},
+		"flat":     &FlatFormatter{},
+		"plain":    &PlainFormatter{},
+		"ndjson":   &NDJSONFormatter{},
+	}
+)
+
+// RegisterFormatter registers f under name, replacing any formatter previously registered with that name.
+// The built-in "table", "csv", "json", "markdown", "flat", "plain" and "ndjson" formatters are registered by default.
+// It is safe to call RegisterFormatter from multiple goroutines.
+func RegisterFormatter(name string, f Formatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+
+	formatters[name] = f
+}
+
+// LookupFormatter returns the formatter registered under name.
+func LookupFormatter(name string) (Formatter, error) {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+
+	f, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q, expected one of %s", name, strings.Join(formatterNames(), ", "))
+	}
+
+	return f, nil
+}
+
+// FormatterNames returns the sorted names of the registered formatters.
+func FormatterNames() []string {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+
+	return formatterNames()
+}
+
+// formatterNames returns the sorted names of the registered formatters. The caller must hold formattersMu.
+func formatterNames() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// SortedResults returns the results of the last run sorted by IP address, followed by the UnresolvedHosts sorted by
+// name, with their ResolutionError.
+func (s *Subping) SortedResults() []HostResult {
+	results := make([]HostResult, 0, len(s.Results)+len(s.UnresolvedHosts))
+	for _, ip := range sortIPs(s.Results) {
+		results = append(results, s.Host(ip, s.Results[ip]))
+	}
+
+	hosts := make([]string, 0, len(s.UnresolvedHosts))
+	for host := range s.UnresolvedHosts {
+		hosts = append(hosts, host)
+	}
+
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		results = append(results, HostResult{IP: host, ResolutionError: s.UnresolvedHosts[host].Error()})
+	}
+
+	return results
+}
+
+// Host returns the result of the target ip annotated with its labels and, when a Locator is set, its location,
+// as in SortedResults.
+func (s *Subping) Host(ip string, result Result) HostResult {
+	host := HostResult{IP: ip, Result: result, Labels: s.Labels[ip]}
+	s.locate(&host)
+
+	return host
+}
+
+// locate annotates host with its geolocation when a Locator is set.
+// Lookup errors are logged and leave host unannotated.
+func (s *Subping) locate(host *HostResult) {
+	if s.locator == nil {
+		return
+	}
+
+	country, city, err := s.locator.Locate(net.ParseIP(host.IP))
+	if err != nil {
+		s.logger.Debug("Failed to geolocate the host.", "ip", host.IP, "error", err)
+		return
+	}
+
+	host.Country = country
+	host.City = city
+}
+
+// Summary returns the summary of the last run.
+func (s *Subping) Summary() ScanSummary {
+	_, online := s.GetOnlineHosts()
+	if s.ResultStore != nil {
+		// Results is left empty by a run writing to the ResultStore.
+		online = int(s.onlineTargets.Load())
+	}
+
+	return ScanSummary{
+		TotalHosts:        s.TotalResults,
+		OnlineHosts:       online,
+		OfflineHosts:      s.TotalResults - online,
+		UnresolvedHosts:   len(s.UnresolvedHosts),
+		Duration:          s.Elapsed,
+		EstimatedDuration: s.EstimatedDuration(),
+		Simulated:         s.Simulated,
+	}
+}
+
+// TableFormatter renders the online hosts as a human-readable table followed by the summary.
+type TableFormatter struct {
+	// RTTUnit is the unit of the latency column.
+	RTTUnit RTTUnit
+
+	// ShowScore adds a column with the reachability score of each host.
+	ShowScore bool
+
+	// ShowOffline lists the offline hosts below the table.
+	ShowOffline bool
+
+	// ShowPorts adds a column with the open TCP ports of each host.
+	ShowPorts bool
+
+	// ShowResponder adds a column with the address the replies came from when it differs from the host,
+	// which reveals NAT, proxy ARP or misrouting.
+	ShowResponder bool
+
+	// ShowLocation adds a column with the country and city of each host, see Options.Locator.
+	ShowLocation bool
+
+	// ShowHostname adds a column with the hostname of each host, found by the ResolveHostnames processor. The
+	// column is blank for the hosts without a PTR record.
+	ShowHostname bool
+
+	// ShowTTL adds a column with the TTL of the replies of each host, which hints at its distance in hops.
+	ShowTTL bool
+
+	// Quiet only writes the rows of the online hosts, with their columns separated by spaces, without the borders,
+	// the header, the offline hosts and the summary, so the table can be piped to other tools. The rows of simulated
+	// results end with a "simulated" column.
+	Quiet bool
+}
+
+// Format writes the table of online hosts and the summary to w.
+func (f *TableFormatter) Format(w io.Writer, results []HostResult, summary ScanSummary) error {
+	var b bytes.Buffer
+
+	border := `-------------------------------------------------------------------------------`
+	if f.ShowHostname {
+		border += `-----------------------------------`
+	}
+	if f.ShowScore {
+		border += `----------`
+	}
+	if f.ShowTTL {
+		border += `------`
+	}
+	if f.ShowPorts {
+		border += `-------------------`
+	}
+	if f.ShowResponder {
+		border += `------------------------------------------`
+	}
+	if f.ShowLocation {
+		border += `---------------------------`
+	}
+
+	latencyHeader := "Avg Latency"
+	if f.RTTUnit != RTTUnitAuto && f.RTTUnit != "" {
+		latencyHeader += " (" + string(f.RTTUnit) + ")"
+	}
+
+	if !f.Quiet {
+		fmt.Fprintln(&b, border)
+
+		header := []string{fmt.Sprintf("%-39s", "IP Address")}
+		if f.ShowHostname {
+			header = append(header, fmt.Sprintf("%-32s", "Hostname"))
+		}
+		header = append(header, fmt.Sprintf("%-16s", latencyHeader), fmt.Sprintf("%-14s", "Packet Loss"))
+		if f.ShowScore {
+			header = append(header, fmt.Sprintf("%-7s", "Score"))
+		}
+		if f.ShowTTL {
+			header = append(header, fmt.Sprintf("%-3s", "TTL"))
+		}
+		if f.ShowPorts {
+			header = append(header, fmt.Sprintf("%-16s", "Open Ports"))
+		}
+		if f.ShowResponder {
+			header = append(header, fmt.Sprintf("%-39s", "Responded From"))
+		}
+		if f.ShowLocation {
+			header = append(header, fmt.Sprintf("%-24s", "Location"))
+		}
+		f.writeRow(&b, header)
+
+		fmt.Fprintln(&b, border)
+	}
+
+	for _, host := range results {
+		if host.Result.PacketsRecv == 0 {
+			continue
+		}
+
+		row := []string{fmt.Sprintf("%-39s", host.IP)}
+		if f.ShowHostname {
+			row = append(row, fmt.Sprintf("%-32s", host.Result.Hostname))
+		}
+		row = append(row,
+			fmt.Sprintf("%-16s", f.RTTUnit.Format(host.Result.AvgRtt)),
+			fmt.Sprintf("%-14s", fmt.Sprintf("%.2f %%", host.Result.PacketLoss)),
+		)
+		if f.ShowScore {
+			row = append(row, fmt.Sprintf("%7.2f", host.Result.Score))
+		}
+		if f.ShowTTL {
+			row = append(row, fmt.Sprintf("%3d", host.Result.TTL))
+		}
+		if f.ShowPorts {
+			row = append(row, fmt.Sprintf("%-16s", openPorts(host.Result)))
+		}
+		if f.ShowResponder {
+			row = append(row, fmt.Sprintf("%-39s", host.Result.RespondedFrom))
+		}
+		if f.ShowLocation {
+			row = append(row, fmt.Sprintf("%-24s", location(host)))
+		}
+		if f.Quiet && summary.Simulated {
+			row = append(row, "simulated")
+		}
+		f.writeRow(&b, row)
+	}
+
+	if f.Quiet {
+		_, err := w.Write(b.Bytes())
+
+		return err
+	}
+
+	fmt.Fprintln(&b, border)
+
+	if f.ShowOffline {
+		fmt.Fprintln(&b, "\nOffline hosts :")
+		for _, host := range results {
+			if host.Result.PacketsRecv == 0 && host.ResolutionError == "" {
+				fmt.Fprintf(&b, " - %s\t(Loss: %s, Latency: %s)\n",
+					host.IP, fmt.Sprintf("%.2f %%", host.Result.PacketLoss), f.RTTUnit.Format(host.Result.AvgRtt))
+			}
+		}
+	}
+
+	if summary.UnresolvedHosts > 0 {
+		fmt.Fprintln(&b, "\nUnresolved hosts :")
+		for _, host := range results {
+			if host.ResolutionError != "" {
+				fmt.Fprintf(&b, " - %s\t(%s)\n", host.IP, host.ResolutionError)
+			}
+		}
+	}
+
+	if summary.Simulated {
+		fmt.Fprintf(&b, "\n%s\n", simulatedNotice)
+	}
+
+	fmt.Fprintf(&b, "\nTotal Hosts Online  : %d\n", summary.OnlineHosts)
+	fmt.Fprintf(&b, "Total Hosts Offline : %d\n", summary.OfflineHosts)
+	if summary.UnresolvedHosts > 0 {
+		fmt.Fprintf(&b, "Total Unresolved    : %d\n", summary.UnresolvedHosts)
+	}
+	fmt.Fprintf(&b, "Execution time      : %s\n", summary.Duration.String())
+	if summary.EstimatedDuration > 0 {
+		fmt.Fprintf(&b, "Estimated time      : %s (actual/estimated: %.2f)\n",
+			summary.EstimatedDuration.String(), summary.durationAccuracy())
+	}
+	fmt.Fprintln(&b)
+
+	_, err := w.Write(b.Bytes())
+
+	return err
+}
+
+// simulatedNotice marks the output of simulated results in the formats with a summary.
+const simulatedNotice = "SIMULATED RESULTS: the mock pinger was used, no packet was sent."
+
+// writeRow writes the padded cells of a row to b, between borders or, when Quiet, separated by spaces.
+func (f *TableFormatter) writeRow(b *bytes.Buffer, cells []string) {
+	if f.Quiet {
+		fmt.Fprintln(b, strings.TrimRight(strings.Join(cells, " "), " "))
+
+		return
+	}
+
+	fmt.Fprintf(b, "| %s |\n", strings.Join(cells, " | "))
+}
+
+// location returns the "country, city" location of host, or only its country when the city is unknown.
+func location(host HostResult) string {
+	if host.City == "" {
+		return host.Country
+	}
+
+	return host.Country + ", " + host.City
+}
+
+// openPorts returns the sorted, comma separated list of the open ports of r.
+func openPorts(r Result) string {
+	var ports []int
+	for port, open := range r.Ports {
+		if open {
+			ports = append(ports, port)
+		}
+	}
+
+	sort.Ints(ports)
+
+	list := make([]string, 0, len(ports))
+	for _, port := range ports {
+		list = append(list, strconv.Itoa(port))
+	}
+
+	return strings.Join(list, ",")
+}
+
+// CSVFormatter renders every host as a CSV row below a header row. The summary is not written.
+// The labels of the hosts are flattened into one "label_<key>" column per label key, left blank for
+// the hosts without that label. When some host names could not be resolved, a "resolution_error" column holds the
+// error of each unresolved host. Simulated results have a trailing "simulated" column set to true.
+type CSVFormatter struct {
+	// RTTUnit is the unit of the average latency column, defaulting to milliseconds.
+	RTTUnit RTTUnit
+
+	// KeyByHostname adds a leading "host" column holding the hostname of each host, or its IP address when it has
+	// none, see HostKey. The rows of the hosts sharing a hostname are grouped together.
+	KeyByHostname bool
+}
+
+// Format writes the results to w in CSV format.
+func (f *CSVFormatter) Format(w io.Writer, results []HostResult, summary ScanSummary) error {
+	cw := csv.NewWriter(w)
+
+	keys := labelKeys(results)
+
+	header := CSVHeader(f.RTTUnit)
+	if f.KeyByHostname {
+		header = append([]string{"host"}, header...)
+		results = groupByHostKey(results)
+	}
+
+	for _, key := range keys {
+		header = append(header, "label_"+key)
+	}
+
+	if summary.UnresolvedHosts > 0 {
+		header = append(header, "resolution_error")
+	}
+
+	if summary.Simulated {
+		header = append(header, "simulated")
+	}
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, host := range results {
+		record := CSVRow(host.IP, host.Result, f.RTTUnit)
+		if f.KeyByHostname {
+			record = append([]string{HostKey(host)}, record...)
+		}
+
+		for _, key := range keys {
+			record = append(record, host.Labels[key])
+		}
+
+		if summary.UnresolvedHosts > 0 {
+			record = append(record, host.ResolutionError)
+		}
+
+		if summary.Simulated {
+			record = append(record, "true")
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// CSVHeader returns the columns of a CSV row, see CSVRow, with the average latency in unit, defaulting to milliseconds,
+// e.g. "avg_latency_ms".
+func CSVHeader(unit RTTUnit) []string {
+	return []string{"ip", "avg_latency_" + string(numericRTTUnit(unit)), "packet_loss", "packets_sent", "packets_recv",
+		"online"}
+}
+
+// CSVRow returns the CSV row of the result of the host ip, in the order of CSVHeader, with the average latency in unit,
+// defaulting to milliseconds. It is shared by CSVFormatter and the CSV encodings of pkg/export.
+func CSVRow(ip string, result Result, unit RTTUnit) []string {
+	return []string{
+		ip,
+		numericRTTUnit(unit).Format(result.AvgRtt),
+		strconv.FormatFloat(result.PacketLoss, 'f', 2, 64),
+		strconv.Itoa(result.PacketsSent),
+		strconv.Itoa(result.PacketsRecv),
+		strconv.FormatBool(result.PacketsRecv > 0),
+	}
+}
+
+// HostKey returns the hostname of host, found by the ResolveHostnames processor, or its IP address when it has none.
+func HostKey(host HostResult) string {
+	if host.Result.Hostname != "" {
+		return host.Result.Hostname
+	}
+
+	return host.IP
+}
+
+// groupByHostKey returns results reordered so that the hosts sharing a HostKey are adjacent, in the order of the
+// first host of each key.
+func groupByHostKey(results []HostResult) []HostResult {
+	var keys []string
+	groups := make(map[string][]HostResult)
+
+	for _, host := range results {
+		key := HostKey(host)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		groups[key] = append(groups[key], host)
+	}
+
+	grouped := make([]HostResult, 0, len(results))
+	for _, key := range keys {
+		grouped = append(grouped, groups[key]...)
+	}
+
+	return grouped
+}
+
+// labelKeys returns the sorted union of the label keys of results.
+func labelKeys(results []HostResult) []string {
+	seen := make(map[string]struct{})
+	for _, host := range results {
+		for key := range host.Labels {
+			seen[key] = struct{}{}
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// MarkdownFormatter renders every host as a row of a GitHub-flavored Markdown table followed by a summary line,
+// for pasting the results into issues or wikis. The RTT of the host names that could not be resolved is "unresolved".
+type MarkdownFormatter struct {
+	// RTTUnit is the unit of the average RTT column.
+	RTTUnit RTTUnit
+}
+
+// Format writes the Markdown table of the results and the summary to w.
+func (f *MarkdownFormatter) Format(w io.Writer, results []HostResult, summary ScanSummary) error {
+	var b bytes.Buffer
+
+	rttHeader := "Avg RTT"
+	if f.RTTUnit != RTTUnitAuto && f.RTTUnit != "" {
+		rttHeader += " (" + string(f.RTTUnit) + ")"
+	}
+
+	fmt.Fprintf(&b, "| IP | Loss | %s |\n", rttHeader)
+	fmt.Fprintln(&b, "| --- | ---: | ---: |")
+
+	for _, host := range results {
+		if host.ResolutionError != "" {
+			fmt.Fprintf(&b, "| %s | - | unresolved |\n", host.IP)
+
+			continue
+		}
+
+		rtt := "-"
+		if host.Result.PacketsRecv > 0 {
+			rtt = f.RTTUnit.Format(host.Result.AvgRtt)
+		}
+
+		fmt.Fprintf(&b, "| %s | %.2f %% | %s |\n", host.IP, host.Result.PacketLoss, rtt)
+	}
+
+	var unresolved string
+	if summary.UnresolvedHosts > 0 {
+		unresolved = fmt.Sprintf(", **%d** unresolved", summary.UnresolvedHosts)
+	}
+
+	fmt.Fprintf(&b, "\n**%d** hosts online, **%d** offline%s, scanned in %s.\n",
+		summary.OnlineHosts, summary.OfflineHosts, unresolved, summary.Duration.String())
+	if summary.Simulated {
+		fmt.Fprintf(&b, "\n**%s**\n", simulatedNotice)
+	}
+
+	_, err := w.Write(b.Bytes())
+
+	return err
+}
+
+// flatKeySeparator separates the IP address from the metric name in the keys of FlatFormatter. Neither IPv4 nor
+// IPv6 addresses contain it, unlike the dot and the colon, so keys split unambiguously.
+const flatKeySeparator = "/"
+
+// FlatFormatter renders every host as a line of space separated key=value pairs, e.g.
+// "10.0.0.5/up=1 10.0.0.5/loss=0.0 10.0.0.5/rtt_ms=1.200", for legacy monitoring that parses flat key=value lines.
+// The rtt_ms pair is omitted for the hosts that did not reply, an unresolved=1 pair is added to the host names that
+// could not be resolved, and a simulated=1 pair is added to simulated results. The summary is not written.
+type FlatFormatter struct{}
+
+// Format writes the results to w as flat key=value lines.
+func (f *FlatFormatter) Format(w io.Writer, results []HostResult, summary ScanSummary) error {
+	var b bytes.Buffer
+
+	for _, host := range results {
+		key := host.IP + flatKeySeparator
+
+		up := 0
+		if host.Result.PacketsRecv > 0 {
+			up = 1
+		}
+
+		fmt.Fprintf(&b, "%sup=%d %sloss=%.1f", key, up, key, host.Result.PacketLoss)
+		if up == 1 {
+			fmt.Fprintf(&b, " %srtt_ms=%s", key, RTTUnitMilliseconds.Format(host.Result.AvgRtt))
+		}
+		if host.ResolutionError != "" {
+			fmt.Fprintf(&b, " %sunresolved=1", key)
+		}
+		if summary.Simulated {
+			fmt.Fprintf(&b, " %ssimulated=1", key)
+		}
+		b.WriteByte('\n')
+	}
+
+	_, err := w.Write(b.Bytes())
+
+	return err
+}
+
+// plainColumnGap separates the columns of PlainFormatter.
+const plainColumnGap = "   "
+
+// PlainFormatter renders every host as a line of fixed-width columns without borders, e.g.
+// "10.0.0.5   1.2ms    0.00%   up", to be filtered with grep or awk. The IP address and latency columns are as
+// wide as their longest value, so the lines stay aligned when IPv4 and IPv6 addresses are mixed.
+// The latency of the hosts that did not reply is "-", the status of the host names that could not be resolved is
+// "unresolved", and the lines of simulated results end with a "simulated" column. The summary is not written.
+type PlainFormatter struct {
+	// RTTUnit is the unit of the latency column.
+	RTTUnit RTTUnit
+}
+
+// Format writes the results to w as fixed-width lines.
+func (f *PlainFormatter) Format(w io.Writer, results []HostResult, summary ScanSummary) error {
+	latencies := make([]string, len(results))
+	ipWidth, latencyWidth := 0, 0
+
+	for i, host := range results {
+		latencies[i] = "-"
+		if host.Result.PacketsRecv > 0 {
+			latencies[i] = f.RTTUnit.Format(host.Result.AvgRtt)
+		}
+
+		if len(host.IP) > ipWidth {
+			ipWidth = len(host.IP)
+		}
+		if len(latencies[i]) > latencyWidth {
+			latencyWidth = len(latencies[i])
+		}
+	}
+
+	var b bytes.Buffer
+
+	for i, host := range results {
+		status := "down"
+		if host.Result.PacketsRecv > 0 {
+			status = "up"
+		}
+		if host.ResolutionError != "" {
+			status = "unresolved"
+		}
+		if summary.Simulated {
+			status += plainColumnGap + "simulated"
+		}
+
+		fmt.Fprintf(&b, "%-*s%s%-*s%s%7s%s%s\n", ipWidth, host.IP, plainColumnGap, latencyWidth, latencies[i],
+			plainColumnGap, fmt.Sprintf("%.2f%%", host.Result.PacketLoss), plainColumnGap, status)
+	}
+
+	_, err := w.Write(b.Bytes())
+
+	return err
+}
+
+// JSONFormatter renders the summary and every host as a single JSON document.
+// The labels of each host are written as a nested "labels" object, empty for unlabeled hosts, and the
+// "country" and "city" fields of each geolocated host, like the "hostname" field of each resolved host,
+// are written when set. The host names that could not be resolved have a "resolution_error" field, and their number
+// is written under "unresolved_hosts". A "simulated" field set to true marks simulated results.
+type JSONFormatter struct {
+	// RTTUnit is the unit of the average latency field, defaulting to milliseconds.
+	RTTUnit RTTUnit
+
+	// Context is written under the "context" key when set.
+	Context *ScanContext
+
+	// KeyByHostname writes "hosts" as an object keyed by the hostname of each host, or its IP address when it has
+	// none, see HostKey, instead of an array. Each key holds the array of the hosts sharing it.
+	KeyByHostname bool
+}
+
+// jsonHost is the JSON representation of the result of a single host. Its average latency is named after its unit,
+// e.g. "avg_latency_ms": only the field of that unit is set.
+type jsonHost struct {
+	IP              string            `json:"ip"`
+	AvgLatencyNs    *float64          `json:"avg_latency_ns,omitempty"`
+	AvgLatencyUs    *float64          `json:"avg_latency_us,omitempty"`
+	AvgLatencyMs    *float64          `json:"avg_latency_ms,omitempty"`
+	AvgLatencyS     *float64          `json:"avg_latency_s,omitempty"`
+	PacketLoss      float64           `json:"packet_loss"`
+	PacketsSent     int               `json:"packets_sent"`
+	PacketsRecv     int               `json:"packets_recv"`
+	Online          bool              `json:"online"`
+	Labels          map[string]string `json:"labels"`
+	Count           int               `json:"count,omitempty"`
+	Simulated       bool              `json:"simulated,omitempty"`
+	Hostname        string            `json:"hostname,omitempty"`
+	Country         string            `json:"country,omitempty"`
+	City            string            `json:"city,omitempty"`
+	ResolutionError string            `json:"resolution_error,omitempty"`
+}
+
+// newJSONHost returns the JSON representation of host, with its average latency in unit, one of the numeric units.
+func newJSONHost(host HostResult, unit RTTUnit) jsonHost {
+	labels := host.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	h := jsonHost{
+		IP:              host.IP,
+		PacketLoss:      host.Result.PacketLoss,
+		PacketsSent:     host.Result.PacketsSent,
+		PacketsRecv:     host.Result.PacketsRecv,
+		Count:           host.Result.Count,
+		Online:          host.Result.PacketsRecv > 0,
+		Labels:          labels,
+		Country:         host.Country,
+		City:            host.City,
+		Hostname:        host.Result.Hostname,
+		ResolutionError: host.ResolutionError,
+	}
+
+	latency := float64(host.Result.AvgRtt) / float64(unit.duration())
+	switch unit {
+	case RTTUnitNanoseconds:
+		h.AvgLatencyNs = &latency
+	case RTTUnitMicroseconds:
+		h.AvgLatencyUs = &latency
+	case RTTUnitSeconds:
+		h.AvgLatencyS = &latency
+	default:
+		h.AvgLatencyMs = &latency
+	}
+
+	return h
+}
+
+// jsonDocument is the JSON representation of a scan.
+type jsonDocument struct {
+	TotalHosts      int          `json:"total_hosts"`
+	OnlineHosts     int          `json:"online_hosts"`
+	OfflineHosts    int          `json:"offline_hosts"`
+	UnresolvedHosts int          `json:"unresolved_hosts,omitempty"`
+	ExecutionTimeMs float64      `json:"execution_time_ms"`
+	EstimatedTimeMs float64      `json:"estimated_time_ms,omitempty"`
+	Accuracy        float64      `json:"duration_accuracy,omitempty"`
+	Simulated       bool         `json:"simulated,omitempty"`
+	Context         *ScanContext `json:"context,omitempty"`
+
+	Hosts jsonHosts `json:"hosts"`
+}
+
+// jsonHosts is the JSON representation of the hosts of a scan: an array or, with JSONFormatter.KeyByHostname, an
+// object keyed by the HostKey of each host, each key holding the array of the hosts sharing it.
+type jsonHosts struct {
+	list  []jsonHost
+	byKey map[string][]jsonHost
+}
+
+// MarshalJSON encodes the hosts as an object when they are keyed, and as an array otherwise.
+func (h jsonHosts) MarshalJSON() ([]byte, error) {
+	if h.byKey != nil {
+		return json.Marshal(h.byKey)
+	}
+
+	return json.Marshal(h.list)
+}
+
+// Format writes the summary and the results to w as a single JSON document.
+func (f *JSONFormatter) Format(w io.Writer, results []HostResult, summary ScanSummary) error {
+	unit := numericRTTUnit(f.RTTUnit)
+
+	doc := jsonDocument{
+		TotalHosts:      summary.TotalHosts,
+		OnlineHosts:     summary.OnlineHosts,
+		OfflineHosts:    summary.OfflineHosts,
+		UnresolvedHosts: summary.UnresolvedHosts,
+		ExecutionTimeMs: float64(summary.Duration) / float64(time.Millisecond),
+		EstimatedTimeMs: float64(summary.EstimatedDuration) / float64(time.Millisecond),
+		Accuracy:        summary.durationAccuracy(),
+		Simulated:       summary.Simulated,
+		Context:         f.Context,
+	}
+
+	doc.Hosts.list = make([]jsonHost, 0, len(results))
+	if f.KeyByHostname {
+		doc.Hosts.byKey = make(map[string][]jsonHost)
+	}
+
+	for _, host := range results {
+		h := newJSONHost(host, unit)
+
+		if f.KeyByHostname {
+			key := HostKey(host)
+			doc.Hosts.byKey[key] = append(doc.Hosts.byKey[key], h)
+		} else {
+			doc.Hosts.list = append(doc.Hosts.list, h)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(doc)
+}
+
+// NDJSONFormatter renders every host as a JSON object on its own line, with the fields of JSONFormatter and without
+// the summary, so the output can be piped into log processors. Each line of simulated results has a "simulated" field
+// set to true. WriteHost writes a single host, e.g. from
+// Options.OnResult to print each host as soon as its result completes.
+type NDJSONFormatter struct {
+	// RTTUnit is the unit of the average latency field, defaulting to milliseconds.
+	RTTUnit RTTUnit
+
+	// mu serializes the lines written by WriteHost.
+	mu sync.Mutex
+}
+
+// Format writes each of the results to w as a line of JSON.
+func (f *NDJSONFormatter) Format(w io.Writer, results []HostResult, summary ScanSummary) error {
+	for _, host := range results {
+		if err := f.writeHost(w, host, summary.Simulated); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteHost writes host to w as a single line of JSON. It is safe to call WriteHost from multiple goroutines.
+func (f *NDJSONFormatter) WriteHost(w io.Writer, host HostResult) error {
+	return f.writeHost(w, host, false)
+}
+
+// writeHost implements WriteHost, marking the line as simulated when simulated is set.
+func (f *NDJSONFormatter) writeHost(w io.Writer, host HostResult, simulated bool) error {
+	h := newJSONHost(host, numericRTTUnit(f.RTTUnit))
+	h.Simulated = simulated
+
+	line, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err = w.Write(append(line, '\n'))
+
+	return err
+}
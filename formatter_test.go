@@ -0,0 +1,685 @@
+package subping_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/geo"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// onlineListFormatter is a custom formatter writing the online hosts on a single line.
+type onlineListFormatter struct{}
+
+func (onlineListFormatter) Format(w io.Writer, results []subping.HostResult, summary subping.ScanSummary) error {
+	var online []string
+	for _, host := range results {
+		if host.Result.PacketsRecv > 0 {
+			online = append(online, host.IP)
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d/%d up: %s\n", summary.OnlineHosts, summary.TotalHosts, strings.Join(online, " "))
+
+	return err
+}
+
+func newFormatterTestSubping(t *testing.T) *subping.Subping {
+	t.Helper()
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger: &stubPinger{online: map[string]time.Duration{
+			"10.0.0.1": 2 * time.Millisecond,
+			"10.0.0.2": 4 * time.Millisecond,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	return sp
+}
+
+func TestRegisterFormatter(t *testing.T) {
+	sp := newFormatterTestSubping(t)
+
+	subping.RegisterFormatter("online-list", onlineListFormatter{})
+
+	f, err := subping.LookupFormatter("online-list")
+	if err != nil {
+		t.Fatalf("LookupFormatter() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, sp.SortedResults(), sp.Summary()); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	if want := "2/4 up: 10.0.0.1 10.0.0.2\n"; buf.String() != want {
+		t.Errorf("Format() = %q, want %q", buf.String(), want)
+	}
+
+	if _, err := subping.LookupFormatter("not-registered"); err == nil {
+		t.Errorf("LookupFormatter() of an unregistered name should return an error")
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	sp := newFormatterTestSubping(t)
+
+	f, err := subping.LookupFormatter("json")
+	if err != nil {
+		t.Fatalf("LookupFormatter() error = %v", err)
+	}
+
+	summary := sp.Summary()
+	summary.Duration = 100 * time.Millisecond
+	summary.EstimatedDuration = 400 * time.Millisecond
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, sp.SortedResults(), summary); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	var doc struct {
+		TotalHosts       int     `json:"total_hosts"`
+		OnlineHosts      int     `json:"online_hosts"`
+		OfflineHosts     int     `json:"offline_hosts"`
+		EstimatedTimeMs  float64 `json:"estimated_time_ms"`
+		DurationAccuracy float64 `json:"duration_accuracy"`
+		Hosts            []struct {
+			IP           string  `json:"ip"`
+			AvgLatencyMs float64 `json:"avg_latency_ms"`
+			Count        int     `json:"count"`
+			Online       bool    `json:"online"`
+		} `json:"hosts"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Format() produced invalid JSON: %v\n%s", err, buf.String())
+	}
+
+	if doc.TotalHosts != 4 || doc.OnlineHosts != 2 || doc.OfflineHosts != 2 || len(doc.Hosts) != 4 {
+		t.Errorf("Format() summary = %+v, want 4 hosts with 2 online", doc)
+	}
+
+	if h := doc.Hosts[2]; h.IP != "10.0.0.2" || h.AvgLatencyMs != 4 || h.Count != 1 || !h.Online {
+		t.Errorf("Format() host = %+v, want 10.0.0.2 online with 4ms and a count of 1", h)
+	}
+
+	if doc.EstimatedTimeMs != 400 || doc.DurationAccuracy != 0.25 {
+		t.Errorf("Format() estimate = %vms with accuracy %v, want 400ms with accuracy 0.25",
+			doc.EstimatedTimeMs, doc.DurationAccuracy)
+	}
+
+	// The average latency field is named after the unit, and only that field is written.
+	buf.Reset()
+	if err := (&subping.JSONFormatter{RTTUnit: subping.RTTUnitMicroseconds}).Format(&buf, sp.SortedResults(),
+		summary); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	var usDoc struct {
+		Hosts []map[string]interface{} `json:"hosts"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &usDoc); err != nil {
+		t.Fatalf("Format() produced invalid JSON: %v\n%s", err, buf.String())
+	}
+
+	if h := usDoc.Hosts[2]; h["avg_latency_us"] != float64(4000) || h["avg_latency_ms"] != nil {
+		t.Errorf("Format() host in microseconds = %v, want only avg_latency_us of 4000", h)
+	}
+}
+
+func TestMarkdownFormatter(t *testing.T) {
+	sp := newFormatterTestSubping(t)
+
+	f, err := subping.LookupFormatter("markdown")
+	if err != nil {
+		t.Fatalf("LookupFormatter() error = %v", err)
+	}
+
+	summary := sp.Summary()
+	summary.Duration = 1500 * time.Millisecond
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, sp.SortedResults(), summary); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	want := "| IP | Loss | Avg RTT |\n" +
+		"| --- | ---: | ---: |\n" +
+		"| 10.0.0.0 | 100.00 % | - |\n" +
+		"| 10.0.0.1 | 0.00 % | 2ms |\n" +
+		"| 10.0.0.2 | 0.00 % | 4ms |\n" +
+		"| 10.0.0.3 | 100.00 % | - |\n" +
+		"\n**2** hosts online, **2** offline, scanned in 1.5s.\n"
+	if buf.String() != want {
+		t.Errorf("Format() =\n%s\nwant =\n%s", buf.String(), want)
+	}
+}
+
+func TestFlatFormatter(t *testing.T) {
+	results := []subping.HostResult{
+		{IP: "10.0.0.5", Result: subping.Result{AvgRtt: 1200 * time.Microsecond, PacketsSent: 2, PacketsRecv: 2}},
+		{IP: "10.0.0.6", Result: subping.Result{PacketLoss: 100, PacketsSent: 2}},
+		{IP: "2001:db8::1", Result: subping.Result{AvgRtt: 3 * time.Millisecond, PacketLoss: 50, PacketsSent: 2, PacketsRecv: 1}},
+	}
+
+	f, err := subping.LookupFormatter("flat")
+	if err != nil {
+		t.Fatalf("LookupFormatter() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, results, subping.ScanSummary{}); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	want := "10.0.0.5/up=1 10.0.0.5/loss=0.0 10.0.0.5/rtt_ms=1.200\n" +
+		"10.0.0.6/up=0 10.0.0.6/loss=100.0\n" +
+		"2001:db8::1/up=1 2001:db8::1/loss=50.0 2001:db8::1/rtt_ms=3.000\n"
+	if buf.String() != want {
+		t.Errorf("Format() =\n%s\nwant =\n%s", buf.String(), want)
+	}
+}
+
+func TestPlainFormatter(t *testing.T) {
+	results := []subping.HostResult{
+		{IP: "10.0.0.5", Result: subping.Result{AvgRtt: 1200 * time.Microsecond, PacketsSent: 2, PacketsRecv: 2}},
+		{IP: "10.0.0.6", Result: subping.Result{PacketLoss: 100, PacketsSent: 2}},
+		{IP: "2001:db8::1", Result: subping.Result{AvgRtt: 13 * time.Millisecond, PacketLoss: 50, PacketsSent: 2, PacketsRecv: 1}},
+	}
+
+	f, err := subping.LookupFormatter("plain")
+	if err != nil {
+		t.Fatalf("LookupFormatter() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, results, subping.ScanSummary{}); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	want := "10.0.0.5      1.2ms     0.00%   up\n" +
+		"10.0.0.6      -       100.00%   down\n" +
+		"2001:db8::1   13ms     50.00%   up\n"
+	if buf.String() != want {
+		t.Errorf("Format() =\n%s\nwant =\n%s", buf.String(), want)
+	}
+
+	// The latency column starts, and the packet loss column ends, at the same offset on every line.
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for _, line := range lines {
+		if line[13:15] == "  " || strings.Index(line, "%") != strings.Index(lines[0], "%") {
+			t.Errorf("line %q is not aligned with %q", line, lines[0])
+		}
+	}
+}
+
+func TestNDJSONFormatter(t *testing.T) {
+	sp := newFormatterTestSubping(t)
+
+	f, err := subping.LookupFormatter("ndjson")
+	if err != nil {
+		t.Fatalf("LookupFormatter() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, sp.SortedResults(), sp.Summary()); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Format() wrote %d lines, want one per host:\n%s", len(lines), buf.String())
+	}
+
+	for i, line := range lines {
+		var host struct {
+			IP     string `json:"ip"`
+			Online bool   `json:"online"`
+		}
+		if err := json.Unmarshal([]byte(line), &host); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", line, err)
+		}
+
+		if want := sp.SortedResults()[i].IP; host.IP != want {
+			t.Errorf("line %d is the host %s, want %s", i, host.IP, want)
+		}
+	}
+
+	// WriteHost writes the same line as Format for a single host.
+	var single bytes.Buffer
+	if err := f.(*subping.NDJSONFormatter).WriteHost(&single, sp.SortedResults()[2]); err != nil {
+		t.Fatalf("WriteHost() error = %v", err)
+	}
+
+	if single.String() != lines[2]+"\n" {
+		t.Errorf("WriteHost() = %q, want %q", single.String(), lines[2]+"\n")
+	}
+}
+
+func TestSimulatedResults(t *testing.T) {
+	results := []subping.HostResult{
+		{IP: "10.0.0.1", Result: subping.Result{AvgRtt: 2 * time.Millisecond, PacketsSent: 1, PacketsRecv: 1}},
+	}
+
+	tests := []struct {
+		name      string
+		formatter subping.Formatter
+	}{
+		{name: "Table", formatter: &subping.TableFormatter{}},
+		{name: "Quiet table", formatter: &subping.TableFormatter{Quiet: true}},
+		{name: "CSV", formatter: &subping.CSVFormatter{}},
+		{name: "JSON", formatter: &subping.JSONFormatter{}},
+		{name: "Markdown", formatter: &subping.MarkdownFormatter{}},
+		{name: "Flat", formatter: &subping.FlatFormatter{}},
+		{name: "Plain", formatter: &subping.PlainFormatter{}},
+		{name: "NDJSON", formatter: &subping.NDJSONFormatter{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, simulated := range []bool{false, true} {
+				var buf bytes.Buffer
+				if err := tt.formatter.Format(&buf, results, subping.ScanSummary{Simulated: simulated}); err != nil {
+					t.Fatalf("Format() error = %v", err)
+				}
+
+				if got := strings.Contains(strings.ToLower(buf.String()), "simulated"); got != simulated {
+					t.Errorf("Format() with Simulated = %v marks the output as simulated = %v:\n%s",
+						simulated, got, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestUnresolvedHosts(t *testing.T) {
+	results := []subping.HostResult{
+		{IP: "10.0.0.1", Result: subping.Result{AvgRtt: 2 * time.Millisecond, PacketsSent: 1, PacketsRecv: 1}},
+		{IP: "missing.test", ResolutionError: "failed to resolve missing.test: no such host"},
+	}
+	summary := subping.ScanSummary{TotalHosts: 1, OnlineHosts: 1, UnresolvedHosts: 1}
+
+	tests := []struct {
+		name      string
+		formatter subping.Formatter
+		want      []string
+	}{
+		{name: "Table", formatter: &subping.TableFormatter{ShowOffline: true},
+			want: []string{"Unresolved hosts :\n - missing.test\t(failed to resolve missing.test: no such host)",
+				"Total Unresolved    : 1"}},
+		{name: "CSV", formatter: &subping.CSVFormatter{},
+			want: []string{",resolution_error\n", "missing.test,0.000,0.00,0,0,false,failed to resolve"}},
+		{name: "JSON", formatter: &subping.JSONFormatter{},
+			want: []string{`"unresolved_hosts": 1`, `"resolution_error": "failed to resolve missing.test`}},
+		{name: "Markdown", formatter: &subping.MarkdownFormatter{},
+			want: []string{"| missing.test | - | unresolved |", "**1** unresolved"}},
+		{name: "Flat", formatter: &subping.FlatFormatter{}, want: []string{"missing.test/unresolved=1"}},
+		{name: "Plain", formatter: &subping.PlainFormatter{}, want: []string{"unresolved\n"}},
+		{name: "NDJSON", formatter: &subping.NDJSONFormatter{},
+			want: []string{`"resolution_error":"failed to resolve missing.test`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.formatter.Format(&buf, results, summary); err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Format() does not contain %q:\n%s", want, buf.String())
+				}
+			}
+
+			if tt.name == "Table" && strings.Contains(buf.String(), " - missing.test\t(Loss") {
+				t.Errorf("Format() lists the unresolved host among the offline hosts:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestTableFormatterShowResponder(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger: ping.NewMockPinger(map[string]ping.MockHostConfig{
+			"10.0.0.1": {Online: true, Latency: time.Millisecond},
+			"10.0.0.2": {Online: true, Latency: time.Millisecond, RespondFrom: "192.168.1.1"},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	if got := sp.Results["10.0.0.1"].RespondedFrom; got != "" {
+		t.Errorf("RespondedFrom of 10.0.0.1 = %q, want empty", got)
+	}
+
+	if got := sp.Results["10.0.0.2"].RespondedFrom; got != "192.168.1.1" {
+		t.Errorf("RespondedFrom of 10.0.0.2 = %q, want %q", got, "192.168.1.1")
+	}
+
+	var buf bytes.Buffer
+	f := &subping.TableFormatter{ShowResponder: true}
+	if err := f.Format(&buf, sp.SortedResults(), sp.Summary()); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "Responded From") {
+		t.Errorf("Format() has no responder column:\n%s", buf.String())
+	}
+
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "| 10.0.0.2 ") && !strings.Contains(line, "| 192.168.1.1 ") {
+			t.Errorf("Format() row of 10.0.0.2 does not show the responder: %s", line)
+		}
+	}
+}
+
+func TestTableFormatterQuiet(t *testing.T) {
+	results := []subping.HostResult{
+		{IP: "10.0.0.1", Result: subping.Result{AvgRtt: 2 * time.Millisecond, PacketsSent: 1, PacketsRecv: 1, TTL: 64}},
+		{IP: "10.0.0.2", Result: subping.Result{PacketLoss: 100, PacketsSent: 1}},
+	}
+
+	var buf bytes.Buffer
+	f := &subping.TableFormatter{RTTUnit: subping.RTTUnitMilliseconds, ShowTTL: true, ShowOffline: true, Quiet: true}
+	if err := f.Format(&buf, results, subping.ScanSummary{TotalHosts: 2, OnlineHosts: 1, OfflineHosts: 1}); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	want := fmt.Sprintf("%-39s %-16s %-14s %3d\n", "10.0.0.1", "2.000", "0.00 %", 64)
+	if buf.String() != want {
+		t.Errorf("Format() =\n%q\nwant only the row of the online host =\n%q", buf.String(), want)
+	}
+}
+
+func TestTableFormatterShowHostname(t *testing.T) {
+	results := []subping.HostResult{
+		{IP: "10.0.0.1", Result: subping.Result{PacketsSent: 1, PacketsRecv: 1, Hostname: "gw.example.com"}},
+		{IP: "10.0.0.2", Result: subping.Result{PacketsSent: 1, PacketsRecv: 1}},
+	}
+
+	var buf bytes.Buffer
+	f := &subping.TableFormatter{ShowHostname: true}
+	if err := f.Format(&buf, results, subping.ScanSummary{OnlineHosts: 2}); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	wantRows := map[string]string{
+		"10.0.0.1": "| gw.example.com ",
+		"10.0.0.2": "| " + strings.Repeat(" ", 32) + " |",
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, "IP Address") && !strings.Contains(line, "| Hostname ") {
+			t.Errorf("Format() header has no hostname column: %s", line)
+		}
+
+		for ip, want := range wantRows {
+			if strings.HasPrefix(line, "| "+ip+" ") && !strings.Contains(line, want) {
+				t.Errorf("Format() row of %s = %q, want it to hold %q", ip, line, want)
+			}
+		}
+	}
+}
+
+func TestTableFormatterShowTTL(t *testing.T) {
+	results := []subping.HostResult{
+		{IP: "10.0.0.1", Result: subping.Result{PacketsSent: 1, PacketsRecv: 1, TTL: 61}},
+	}
+
+	var buf bytes.Buffer
+	f := &subping.TableFormatter{ShowTTL: true}
+	if err := f.Format(&buf, results, subping.ScanSummary{OnlineHosts: 1}); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "| TTL |") {
+		t.Errorf("Format() has no TTL column:\n%s", buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "|  61 |") {
+		t.Errorf("Format() row of 10.0.0.1 does not show its TTL:\n%s", buf.String())
+	}
+}
+
+func TestLabels(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger: &stubPinger{online: map[string]time.Duration{
+			"10.0.0.1": 2 * time.Millisecond,
+		}},
+		Labels: map[string]map[string]string{
+			"10.0.0.1": {"role": "db"},
+			"10.0.0.2": {"role": "web", "rack": "r1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := (&subping.CSVFormatter{}).Format(&buf, sp.SortedResults(), sp.Summary()); err != nil {
+			t.Fatalf("Format() error = %v", err)
+		}
+
+		want := "ip,avg_latency_ms,packet_loss,packets_sent,packets_recv,online,label_rack,label_role\n" +
+			"10.0.0.0,0.000,100.00,1,0,false,,\n" +
+			"10.0.0.1,2.000,0.00,1,1,true,,db\n" +
+			"10.0.0.2,0.000,100.00,1,0,false,r1,web\n" +
+			"10.0.0.3,0.000,100.00,1,0,false,,\n"
+		if buf.String() != want {
+			t.Errorf("Format() =\n%s\nwant =\n%s", buf.String(), want)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := (&subping.JSONFormatter{}).Format(&buf, sp.SortedResults(), sp.Summary()); err != nil {
+			t.Fatalf("Format() error = %v", err)
+		}
+
+		var doc struct {
+			Hosts []struct {
+				IP     string            `json:"ip"`
+				Labels map[string]string `json:"labels"`
+			} `json:"hosts"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("Format() wrote invalid JSON: %v\n%s", err, buf.String())
+		}
+
+		want := map[string]map[string]string{
+			"10.0.0.0": {},
+			"10.0.0.1": {"role": "db"},
+			"10.0.0.2": {"role": "web", "rack": "r1"},
+			"10.0.0.3": {},
+		}
+		for _, host := range doc.Hosts {
+			if !reflect.DeepEqual(host.Labels, want[host.IP]) {
+				t.Errorf("labels of %s = %v, want %v", host.IP, host.Labels, want[host.IP])
+			}
+		}
+	})
+}
+
+func TestGeoLocation(t *testing.T) {
+	geoDB, err := geo.Open(filepath.Join("pkg", "geo", "testdata", "GeoLite2-City-Test.mmdb"))
+	if err != nil {
+		t.Fatalf("geo.Open() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		subnet string
+		want   map[string][2]string
+	}{
+		{
+			name:   "Public subnet",
+			subnet: "81.2.69.140/31",
+			want: map[string][2]string{
+				"81.2.69.140": {"GB", "London"},
+				"81.2.69.141": {"GB", "London"},
+			},
+		},
+		{
+			name:   "Private subnet is not located",
+			subnet: "10.0.0.0/31",
+			want: map[string][2]string{
+				"10.0.0.0": {},
+				"10.0.0.1": {},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     tt.subnet,
+				Count:      1,
+				MaxWorkers: 2,
+				Pinger:     &stubPinger{},
+				Locator:    geoDB,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			got := make(map[string][2]string)
+			for _, host := range sp.SortedResults() {
+				got[host.IP] = [2]string{host.Country, host.City}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortedResults() locations = %v, want %v", got, tt.want)
+			}
+
+			var buf bytes.Buffer
+			if err := (&subping.JSONFormatter{}).Format(&buf, sp.SortedResults(), sp.Summary()); err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+
+			var doc struct {
+				Hosts []struct {
+					IP      string `json:"ip"`
+					Country string `json:"country"`
+					City    string `json:"city"`
+				} `json:"hosts"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatalf("Format() wrote invalid JSON: %v\n%s", err, buf.String())
+			}
+
+			for _, host := range doc.Hosts {
+				if location := [2]string{host.Country, host.City}; location != tt.want[host.IP] {
+					t.Errorf("JSON location of %s = %v, want %v", host.IP, location, tt.want[host.IP])
+				}
+			}
+		})
+	}
+}
+
+func TestKeyByHostname(t *testing.T) {
+	// 10.0.0.1 and 10.0.0.3 share a PTR record, and 10.0.0.2 has none.
+	ptr := map[string]string{"10.0.0.1": "gw.example.com.", "10.0.0.3": "gw.example.com.", "10.0.0.0": "net.example.com."}
+	resolve := func(_ context.Context, addr string) ([]string, error) {
+		if name, ok := ptr[addr]; ok {
+			return []string{name}, nil
+		}
+
+		return nil, errors.New("no PTR record")
+	}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger: &stubPinger{online: map[string]time.Duration{
+			"10.0.0.1": time.Millisecond,
+			"10.0.0.2": 2 * time.Millisecond,
+			"10.0.0.3": 3 * time.Millisecond,
+		}},
+		Processors: []subping.ResultProcessor{subping.ResolveHostnames{Resolve: resolve}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		f := &subping.JSONFormatter{KeyByHostname: true}
+		if err := f.Format(&buf, sp.SortedResults(), sp.Summary()); err != nil {
+			t.Fatalf("Format() error = %v", err)
+		}
+
+		var doc struct {
+			Hosts map[string][]struct {
+				IP string `json:"ip"`
+			} `json:"hosts"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("Format() produced invalid JSON: %v\n%s", err, buf.String())
+		}
+
+		got := make(map[string][]string)
+		for key, hosts := range doc.Hosts {
+			for _, h := range hosts {
+				got[key] = append(got[key], h.IP)
+			}
+		}
+
+		want := map[string][]string{
+			"net.example.com": {"10.0.0.0"},
+			"gw.example.com":  {"10.0.0.1", "10.0.0.3"},
+			"10.0.0.2":        {"10.0.0.2"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Format() hosts = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		f := &subping.CSVFormatter{KeyByHostname: true}
+		if err := f.Format(&buf, sp.SortedResults(), sp.Summary()); err != nil {
+			t.Fatalf("Format() error = %v", err)
+		}
+
+		want := "host,ip,avg_latency_ms,packet_loss,packets_sent,packets_recv,online\n" +
+			"net.example.com,10.0.0.0,0.000,100.00,1,0,false\n" +
+			"gw.example.com,10.0.0.1,1.000,0.00,1,1,true\n" +
+			"gw.example.com,10.0.0.3,3.000,0.00,1,1,true\n" +
+			"10.0.0.2,10.0.0.2,2.000,0.00,1,1,true\n"
+		if buf.String() != want {
+			t.Errorf("Format() =\n%s\nwant =\n%s", buf.String(), want)
+		}
+	})
+}
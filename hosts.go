@@ -0,0 +1,128 @@
+package subping
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// errNoAddress is recorded in UnresolvedHosts for the hosts resolving to no IP address.
+var errNoAddress = errors.New("no IP address")
+
+// IPFamily is the family of the addresses the host names of Options.Hosts are pinged at.
+type IPFamily string
+
+const (
+	// IPFamilyAny pings the host names at all their IPv4 and IPv6 addresses.
+	IPFamilyAny IPFamily = "any"
+
+	// IPFamilyIPv4 pings the host names at their IPv4 addresses only.
+	IPFamilyIPv4 IPFamily = "ipv4"
+
+	// IPFamilyIPv6 pings the host names at their IPv6 addresses only.
+	IPFamilyIPv6 IPFamily = "ipv6"
+)
+
+// ParseIPFamily returns the IPFamily named s, or an error if there is none. An empty s is IPFamilyAny.
+func ParseIPFamily(s string) (IPFamily, error) {
+	switch family := IPFamily(s); family {
+	case "":
+		return IPFamilyAny, nil
+	case IPFamilyAny, IPFamilyIPv4, IPFamilyIPv6:
+		return family, nil
+	default:
+		return "", fmt.Errorf("invalid IP family %q, must be one of %s, %s or %s",
+			s, IPFamilyAny, IPFamilyIPv4, IPFamilyIPv6)
+	}
+}
+
+// Contains reports whether ip belongs to the family f.
+func (f IPFamily) Contains(ip net.IP) bool {
+	switch f {
+	case IPFamilyIPv4:
+		return ip.To4() != nil
+	case IPFamilyIPv6:
+		return ip.To4() == nil
+	default:
+		return true
+	}
+}
+
+// resolveHosts resolves hosts to their addresses of the given family with lookup, or net.LookupIP when it is nil.
+// It returns the addresses in order without duplicates, and the errors of the hosts that could not be resolved,
+// keyed by host. The hosts resolving to addresses of other families only are reported as unresolved.
+func resolveHosts(hosts []string, family IPFamily, lookup func(string) ([]net.IP, error)) ([]net.IP, map[string]error) {
+	if lookup == nil {
+		lookup = net.LookupIP
+	}
+
+	var ips []net.IP
+	unresolved := make(map[string]error)
+	seen := make(map[string]bool)
+
+	for _, host := range hosts {
+		addrs, err := lookup(host)
+		if err == nil && len(addrs) == 0 {
+			err = errNoAddress
+		}
+
+		if err == nil {
+			addrs = filterFamily(addrs, family)
+			if len(addrs) == 0 {
+				err = fmt.Errorf("%w of family %s", errNoAddress, family)
+			}
+		}
+
+		if err != nil {
+			unresolved[host] = fmt.Errorf("failed to resolve %s: %w", host, err)
+
+			continue
+		}
+
+		for _, ip := range addrs {
+			if key := ip.String(); !seen[key] {
+				seen[key] = true
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	return ips, unresolved
+}
+
+// filterFamily returns the addresses of ips belonging to family.
+func filterFamily(ips []net.IP, family IPFamily) []net.IP {
+	var kept []net.IP
+	for _, ip := range ips {
+		if family.Contains(ip) {
+			kept = append(kept, ip)
+		}
+	}
+
+	return kept
+}
+
+// hostSubnet returns the subnet holding ip alone, a /32 for IPv4 and a /128 for IPv6.
+func hostSubnet(ip net.IP) *net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+
+	return &net.IPNet{IP: ip.To16(), Mask: net.CIDRMask(128, 128)}
+}
+
+// appendHostSubnets appends to extra the subnets of the given IP addresses that neither first nor extra hold yet.
+func appendHostSubnets(first *net.IPNet, extra []*net.IPNet, ips []net.IP) []*net.IPNet {
+	for _, ip := range ips {
+		covered := first.Contains(ip)
+		for _, ipNet := range extra {
+			covered = covered || ipNet.Contains(ip)
+		}
+
+		if !covered {
+			extra = append(extra, hostSubnet(ip))
+		}
+	}
+
+	return extra
+}
@@ -0,0 +1,152 @@
+package subping_test
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/fadhilyori/subping"
+)
+
+// lookupIP is a fake net.LookupIP resolving the names of a static zone.
+func lookupIP(host string) ([]net.IP, error) {
+	zone := map[string][]net.IP{
+		"db.example.com":     {net.ParseIP("192.0.2.10"), net.ParseIP("2001:db8::10")},
+		"db-alias.test":      {net.ParseIP("192.0.2.10")},
+		"gw.example.com":     {net.ParseIP("10.0.0.1")},
+		"nothing.test":       {},
+		"www.example.com":    {net.ParseIP("192.0.2.20")},
+		"router.example.com": {net.ParseIP("10.0.1.1")},
+	}
+
+	ips, ok := zone[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return ips, nil
+}
+
+func TestHosts(t *testing.T) {
+	tests := []struct {
+		name           string
+		subnet         string
+		hosts          []string
+		ipFamily       subping.IPFamily
+		wantSubnets    []string
+		wantUnresolved []string
+		wantErr        bool
+	}{
+		{
+			name:        "Hosts are scanned after the subnet",
+			subnet:      "10.0.0.0/30",
+			hosts:       []string{"db.example.com", "www.example.com"},
+			wantSubnets: []string{"10.0.0.0/30", "192.0.2.10/32", "2001:db8::10/128", "192.0.2.20/32"},
+		},
+		{
+			name:        "Addresses already scanned are skipped",
+			subnet:      "10.0.0.0/30",
+			hosts:       []string{"gw.example.com", "db.example.com", "db-alias.test"},
+			wantSubnets: []string{"10.0.0.0/30", "192.0.2.10/32", "2001:db8::10/128"},
+		},
+		{
+			name:           "Hosts alone, with resolution failures",
+			hosts:          []string{"missing.test", "www.example.com", "nothing.test", "router.example.com"},
+			wantSubnets:    []string{"192.0.2.20/32", "10.0.1.1/32"},
+			wantUnresolved: []string{"missing.test", "nothing.test"},
+		},
+		{
+			name:    "No host resolved",
+			hosts:   []string{"missing.test"},
+			wantErr: true,
+		},
+		{
+			name:        "IPv4 addresses only",
+			hosts:       []string{"db.example.com", "www.example.com"},
+			ipFamily:    subping.IPFamilyIPv4,
+			wantSubnets: []string{"192.0.2.10/32", "192.0.2.20/32"},
+		},
+		{
+			name:           "IPv6 addresses only",
+			hosts:          []string{"db.example.com", "www.example.com"},
+			ipFamily:       subping.IPFamilyIPv6,
+			wantSubnets:    []string{"2001:db8::10/128"},
+			wantUnresolved: []string{"www.example.com"},
+		},
+		{
+			name:     "No host has an address of the family",
+			hosts:    []string{"www.example.com", "gw.example.com"},
+			ipFamily: subping.IPFamilyIPv6,
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid family",
+			subnet:   "10.0.0.0/30",
+			ipFamily: "ipv5",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     tt.subnet,
+				Hosts:      tt.hosts,
+				IPFamily:   tt.ipFamily,
+				LookupIP:   lookupIP,
+				Count:      1,
+				MaxWorkers: 1,
+				Pinger:     &stubPinger{},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(sp.Subnets, tt.wantSubnets) {
+				t.Errorf("Subnets = %v, want %v", sp.Subnets, tt.wantSubnets)
+			}
+
+			var unresolved []string
+			for _, host := range tt.hosts {
+				if err, ok := sp.UnresolvedHosts[host]; ok {
+					unresolved = append(unresolved, host)
+
+					var dnsErr *net.DNSError
+					if host == "missing.test" && !errors.As(err, &dnsErr) {
+						t.Errorf("UnresolvedHosts[%q] = %v, want a *net.DNSError", host, err)
+					}
+				}
+			}
+
+			if !reflect.DeepEqual(unresolved, tt.wantUnresolved) {
+				t.Errorf("UnresolvedHosts holds %v, want %v", unresolved, tt.wantUnresolved)
+			}
+
+			sp.Run()
+
+			if len(sp.Results) != sp.TotalHosts() {
+				t.Errorf("Run() produced %d results, want %d", len(sp.Results), sp.TotalHosts())
+			}
+
+			// The unresolved hosts follow the results, sorted by name.
+			var reported []string
+			for _, host := range sp.SortedResults() {
+				if host.ResolutionError != "" {
+					reported = append(reported, host.IP)
+				}
+			}
+
+			if !reflect.DeepEqual(reported, tt.wantUnresolved) {
+				t.Errorf("SortedResults() reports %v as unresolved, want %v", reported, tt.wantUnresolved)
+			}
+
+			if got := sp.Summary().UnresolvedHosts; got != len(tt.wantUnresolved) {
+				t.Errorf("Summary().UnresolvedHosts = %d, want %d", got, len(tt.wantUnresolved))
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package subping
+
+import "net"
+
+// Locator geolocates IP addresses, e.g. a *geo.DB opened from an offline MaxMind GeoLite2 database. It is an
+// interface so that the scan does not depend on a geolocation database unless one is given, see Options.Locator.
+type Locator interface {
+	// Locate returns the ISO 3166-1 alpha-2 country code and the city of ip, or empty strings when ip cannot be
+	// located, e.g. because it is a private address. The city is empty when only the country is known.
+	Locate(ip net.IP) (country, city string, err error)
+}
@@ -0,0 +1,81 @@
+package subping
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is a structured logger receiving the messages of subping, see Options.Logger. The args alternate keys and
+// values, e.g. "target", "10.0.0.1". A *slog.Logger is a Logger, so subping can log through the logger of the
+// application embedding it.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// tracer is implemented by the Loggers having a level more verbose than Debug.
+type tracer interface {
+	Trace(msg string, args ...any)
+}
+
+// logrusLogger is the Logger writing to logrus, used when Options.Logger is nil.
+type logrusLogger struct {
+	logger *logrus.Logger
+}
+
+// newLogrusLogger returns a logrusLogger writing the messages of the given level and above to os.Stderr.
+func newLogrusLogger(level logrus.Level) logrusLogger {
+	logger := logrus.New()
+	logger.SetLevel(level)
+
+	return logrusLogger{logger: logger}
+}
+
+// Trace logs msg at the trace level.
+func (l logrusLogger) Trace(msg string, args ...any) {
+	l.entry(args).Trace(msg)
+}
+
+// Debug logs msg at the debug level.
+func (l logrusLogger) Debug(msg string, args ...any) {
+	l.entry(args).Debug(msg)
+}
+
+// Info logs msg at the info level.
+func (l logrusLogger) Info(msg string, args ...any) {
+	l.entry(args).Info(msg)
+}
+
+// Warn logs msg at the warning level.
+func (l logrusLogger) Warn(msg string, args ...any) {
+	l.entry(args).Warn(msg)
+}
+
+// Error logs msg at the error level.
+func (l logrusLogger) Error(msg string, args ...any) {
+	l.entry(args).Error(msg)
+}
+
+// entry returns a logrus entry holding args as fields. A trailing key without a value is ignored.
+func (l logrusLogger) entry(args []any) *logrus.Entry {
+	fields := make(logrus.Fields, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		fields[fmt.Sprint(args[i])] = args[i+1]
+	}
+
+	return l.logger.WithFields(fields)
+}
+
+// trace logs msg at the trace level when the logger of s has one, and at the debug level otherwise.
+func (s *Subping) trace(msg string, args ...any) {
+	if t, ok := s.logger.(tracer); ok {
+		t.Trace(msg, args...)
+
+		return
+	}
+
+	s.logger.Debug(msg, args...)
+}
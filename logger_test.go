@@ -0,0 +1,98 @@
+package subping_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fadhilyori/subping"
+)
+
+// logRecord is a message logged to a recordingLogger.
+type logRecord struct {
+	level string
+	msg   string
+	args  []any
+}
+
+// recordingLogger is a subping.Logger recording every message, with the method set of a *slog.Logger.
+type recordingLogger struct {
+	mu      sync.Mutex
+	records []logRecord
+}
+
+func (l *recordingLogger) log(level, msg string, args []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, logRecord{level: level, msg: msg, args: args})
+}
+
+func (l *recordingLogger) Debug(msg string, args ...any) { l.log("debug", msg, args) }
+func (l *recordingLogger) Info(msg string, args ...any)  { l.log("info", msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...any)  { l.log("warn", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...any) { l.log("error", msg, args) }
+
+// find returns the first record of the given level whose message starts with prefix.
+func (l *recordingLogger) find(level, prefix string) (logRecord, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, r := range l.records {
+		if r.level == level && strings.HasPrefix(r.msg, prefix) {
+			return r, true
+		}
+	}
+
+	return logRecord{}, false
+}
+
+func TestLogger(t *testing.T) {
+	logger := &recordingLogger{}
+
+	var sp *subping.Subping
+	var err error
+
+	logs := captureStderr(t, func() {
+		sp, err = subping.NewSubping(&subping.Options{
+			Subnet:     "10.0.0.0/31",
+			Count:      1,
+			MaxWorkers: 8,
+			Retries:    1,
+			LogLevel:   "trace",
+			Logger:     logger,
+			Clock:      &recordingClock{},
+			Pinger:     &errorPinger{},
+		})
+		if err != nil {
+			return
+		}
+
+		sp.Run()
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	if logs != "" {
+		t.Errorf("Run() wrote %q to stderr, want every message sent to the Logger", logs)
+	}
+
+	if _, ok := logger.find("warn", "Requested 8 workers for 2 hosts; using 2."); !ok {
+		t.Errorf("Logger got %+v, want the warning about the number of workers", logger.records)
+	}
+
+	r, ok := logger.find("debug", "Attempt 1 failed.")
+	if !ok {
+		t.Fatalf("Logger got %+v, want the failed attempts at the debug level", logger.records)
+	}
+
+	if len(r.args) < 2 || r.args[0] != "target" {
+		t.Errorf("Logger got the args %v for a failed attempt, want the target first", r.args)
+	}
+
+	// The Logger has no Trace method, so the trace messages are logged at the debug level.
+	if _, ok := logger.find("debug", "Got task."); !ok {
+		t.Errorf("Logger got %+v, want the trace messages at the debug level", logger.records)
+	}
+}
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditLog is an append-only log of the probed addresses, one "<RFC 3339 timestamp> <ip>" line per address in the
+// order they are probed, e.g. to prove which addresses a scan covered. Every line is appended with a single
+// unbuffered write, so the log is complete up to the last probed address even if the scan is interrupted. It is safe
+// for concurrent use.
+type AuditLog struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// OpenAuditLog opens the audit log at path for appending, creating it if it does not exist.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit log: %w", err)
+	}
+
+	return &AuditLog{f: f}, nil
+}
+
+// Record appends the address ip to the log, timestamped with the current time.
+func (l *AuditLog) Record(ip string) error {
+	line := time.Now().UTC().Format(time.RFC3339Nano) + " " + ip + "\n"
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.f.WriteString(line); err != nil {
+		return fmt.Errorf("failed to record %s in the audit log: %w", ip, err)
+	}
+
+	return nil
+}
+
+// Close closes the log file.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.f.Close()
+}
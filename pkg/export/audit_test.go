@@ -0,0 +1,68 @@
+package export_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping/pkg/export"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// The second open appends to the lines of the first one.
+	for _, ips := range [][]string{{"10.0.0.1", "10.0.0.2"}, {"2001:db8::1"}} {
+		l, err := export.OpenAuditLog(path)
+		if err != nil {
+			t.Fatalf("OpenAuditLog() error = %v", err)
+		}
+
+		for _, ip := range ips {
+			if err := l.Record(ip); err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+		}
+
+		if err := l.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	want := []string{"10.0.0.1", "10.0.0.2", "2001:db8::1"}
+	if len(lines) != len(want) {
+		t.Fatalf("audit log has %d lines, want %d:\n%s", len(lines), len(want), data)
+	}
+
+	var previous time.Time
+	for i, line := range lines {
+		timestamp, ip, _ := strings.Cut(line, " ")
+		if ip != want[i] {
+			t.Errorf("line %d records %q, want %q", i, ip, want[i])
+		}
+
+		at, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			t.Errorf("line %d has an invalid timestamp: %v", i, err)
+		}
+
+		if at.Before(previous) {
+			t.Errorf("line %d is timestamped %s, before the previous line at %s", i, at, previous)
+		}
+		previous = at
+	}
+}
+
+func TestOpenAuditLogError(t *testing.T) {
+	if _, err := export.OpenAuditLog(filepath.Join(t.TempDir(), "missing", "audit.log")); err == nil {
+		t.Error("OpenAuditLog() error = nil, want an error for a missing directory")
+	}
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// CSVFile is a CSV file holding a single row per host with its latest result, e.g. to follow the rounds of Watch
+// in a spreadsheet. Every flush rewrites the whole file into a temporary file that then atomically replaces it,
+// so readers always see a complete file with one row per host sorted by IP address. It is safe for concurrent use.
+type CSVFile struct {
+	// RTTUnit is the unit of the average latency column, defaulting to milliseconds.
+	RTTUnit subping.RTTUnit
+
+	// mu guards results.
+	mu sync.Mutex
+
+	// flushMu serializes the flushes, so an older snapshot of results never replaces a newer one.
+	flushMu sync.Mutex
+
+	path    string
+	results map[string]ping.Result
+}
+
+// NewCSVFile returns a CSVFile writing to path. The file is only created or replaced on the first flush.
+func NewCSVFile(path string) *CSVFile {
+	return &CSVFile{path: path, results: make(map[string]ping.Result)}
+}
+
+// Set records the latest result of the host ip, replacing its previous result. The file is not rewritten
+// until the next flush. Its signature matches the OnResult callback of subping.
+func (f *CSVFile) Set(ip string, result ping.Result) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.results[ip] = result
+}
+
+// Update records the latest results of the hosts in results, keeping the rows of the other hosts,
+// and flushes the file.
+func (f *CSVFile) Update(results map[string]ping.Result) error {
+	f.mu.Lock()
+	for ip, result := range results {
+		f.results[ip] = result
+	}
+	f.mu.Unlock()
+
+	return f.Flush()
+}
+
+// Flush rewrites the file with the latest result of every host. The file keeps the permissions of the file it
+// replaces, or gets 0644 when it is created.
+func (f *CSVFile) Flush() error {
+	f.flushMu.Lock()
+	defer f.flushMu.Unlock()
+
+	f.mu.Lock()
+	results := make(map[string]ping.Result, len(f.results))
+	for ip, result := range f.results {
+		results[ip] = result
+	}
+	f.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := encodeCSV(w, results, f.RTTUnit); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	// os.CreateTemp creates the file with mode 0600, which os.Rename would keep.
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(f.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
@@ -0,0 +1,154 @@
+package export_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/export"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestCSVFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.csv")
+	f := export.NewCSVFile(path)
+
+	rounds := []map[string]ping.Result{
+		{
+			"10.0.0.2":  {AvgRtt: 2 * time.Millisecond, PacketsSent: 1, PacketsRecv: 1},
+			"10.0.0.10": {PacketsSent: 1, PacketLoss: 100},
+		},
+		{
+			"10.0.0.2":  {PacketsSent: 1, PacketLoss: 100},
+			"10.0.0.10": {AvgRtt: 5 * time.Millisecond, PacketsSent: 1, PacketsRecv: 1},
+		},
+	}
+
+	want := []string{
+		"ip,avg_latency_ms,packet_loss,packets_sent,packets_recv,online\n" +
+			"10.0.0.2,2.000,0.00,1,1,true\n" +
+			"10.0.0.10,0.000,100.00,1,0,false\n",
+		"ip,avg_latency_ms,packet_loss,packets_sent,packets_recv,online\n" +
+			"10.0.0.2,0.000,100.00,1,0,false\n" +
+			"10.0.0.10,5.000,0.00,1,1,true\n",
+	}
+
+	for i, results := range rounds {
+		if err := f.Update(results); err != nil {
+			t.Fatalf("Update() of round %d error = %v", i+1, err)
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+
+		if string(got) != want[i] {
+			t.Errorf("file after round %d =\n%s\nwant =\n%s", i+1, got, want[i])
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the CSV file", len(entries))
+	}
+}
+
+func TestCSVFilePermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing os.FileMode
+		want     os.FileMode
+	}{
+		{name: "New file", want: 0o644},
+		{name: "Existing file", existing: 0o640, want: 0o640},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "watch.csv")
+			if tt.existing != 0 {
+				if err := os.WriteFile(path, nil, tt.existing); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+				// Clear the umask from the permissions the file was created with.
+				if err := os.Chmod(path, tt.existing); err != nil {
+					t.Fatalf("Chmod() error = %v", err)
+				}
+			}
+
+			if err := export.NewCSVFile(path).Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Stat() error = %v", err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("file mode = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVFileConcurrentUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.csv")
+	f := export.NewCSVFile(path)
+
+	var wg sync.WaitGroup
+	for round := 0; round < 4; round++ {
+		wg.Add(1)
+		go func(round int) {
+			defer wg.Done()
+
+			for host := 0; host < 16; host++ {
+				f.Set(fmt.Sprintf("10.0.0.%d", host), ping.Result{PacketsSent: round + 1})
+			}
+
+			if err := f.Flush(); err != nil {
+				t.Errorf("Flush() error = %v", err)
+			}
+		}(round)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	if rows := bytes.Count(data, []byte("\n")); rows != 17 {
+		t.Errorf("file holds %d rows, want a header and one row per host", rows)
+	}
+}
+
+func TestCSVFileRTTUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.csv")
+	f := export.NewCSVFile(path)
+	f.RTTUnit = subping.RTTUnitMicroseconds
+
+	if err := f.Update(map[string]ping.Result{
+		"10.0.0.2": {AvgRtt: 2 * time.Millisecond, PacketsSent: 1, PacketsRecv: 1},
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	want := "ip,avg_latency_us,packet_loss,packets_sent,packets_recv,online\n" +
+		"10.0.0.2,2000.000,0.00,1,1,true\n"
+	if string(got) != want {
+		t.Errorf("CSV file =\n%s\nwant =\n%s", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// CSVLog is a CSV file to which the row of each host is appended as soon as its result completes, with the header
+// and columns of Encode. Every row is flushed to the file before Write returns, so a scan that dies at host 40000
+// still leaves the rows of the first 40000 hosts on disk. The rows are in completion order, not sorted. It is safe for
+// concurrent use.
+type CSVLog struct {
+	mu   sync.Mutex
+	f    *os.File
+	w    *csv.Writer
+	unit subping.RTTUnit
+}
+
+// OpenCSVLog creates the CSV file at path, replacing any existing file, and writes its header. The average latency
+// is written in unit, defaulting to milliseconds.
+func OpenCSVLog(path string, unit subping.RTTUnit) (*CSVLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the CSV log: %w", err)
+	}
+
+	l := &CSVLog{f: f, w: csv.NewWriter(f), unit: unit}
+	if err := l.writeRow(subping.CSVHeader(unit)); err != nil {
+		f.Close()
+
+		return nil, fmt.Errorf("failed to write the header of the CSV log: %w", err)
+	}
+
+	return l, nil
+}
+
+// Write appends the row of the host ip to the file.
+func (l *CSVLog) Write(ip string, result ping.Result) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.writeRow(subping.CSVRow(ip, result, l.unit)); err != nil {
+		return fmt.Errorf("failed to write the result of %s to the CSV log: %w", ip, err)
+	}
+
+	return nil
+}
+
+// writeRow writes row and flushes it to the file.
+func (l *CSVLog) writeRow(row []string) error {
+	if err := l.w.Write(row); err != nil {
+		return err
+	}
+
+	l.w.Flush()
+
+	return l.w.Error()
+}
+
+// Close closes the file.
+func (l *CSVLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.f.Close()
+}
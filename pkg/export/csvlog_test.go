@@ -0,0 +1,66 @@
+package export_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/export"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestCSVLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+
+	l, err := export.OpenCSVLog(path, subping.RTTUnitMicroseconds)
+	if err != nil {
+		t.Fatalf("OpenCSVLog() error = %v", err)
+	}
+
+	results := []struct {
+		ip     string
+		result ping.Result
+		want   string
+	}{
+		{
+			ip:     "10.0.0.2",
+			result: ping.Result{AvgRtt: 1500 * time.Microsecond, PacketsSent: 2, PacketsRecv: 2},
+			want:   "10.0.0.2,1500.000,0.00,2,2,true",
+		},
+		{
+			ip:     "10.0.0.1",
+			result: ping.Result{PacketLoss: 100, PacketsSent: 2},
+			want:   "10.0.0.1,0.000,100.00,2,0,false",
+		},
+	}
+
+	want := []string{"ip,avg_latency_us,packet_loss,packets_sent,packets_recv,online"}
+	for _, r := range results {
+		if err := l.Write(r.ip, r.result); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+
+		want = append(want, r.want)
+
+		// Every row is on disk as soon as Write returns, before the log is closed.
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+
+		if got := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("CSV log = %q, want %q", got, want)
+		}
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if err := l.Write("10.0.0.3", ping.Result{}); err == nil {
+		t.Error("Write() error = nil after Close, want an error")
+	}
+}
@@ -0,0 +1,213 @@
+// Package export provides functionality for encoding subping results and delivering them to external storage.
+//
+// The package includes encoders for the JSON and CSV formats, an uploader that writes the encoded results
+// to an S3-compatible object store, and a Sink streaming each result over a TCP or Unix socket as it completes.
+//
+// Example:
+//
+//	err := export.UploadResults(ctx, "https://s3.amazonaws.com", "scans", "office/latest.json", sp.Results,
+//		export.FormatJSON)
+//	if err != nil {
+//		fmt.Println("Error:", err)
+//		return
+//	}
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+const (
+	// FormatJSON encodes the results as a JSON array of host objects.
+	FormatJSON = "json"
+
+	// FormatCSV encodes the results as CSV with a header row.
+	FormatCSV = "csv"
+)
+
+// hostRecord is the exported representation of the result of a single host. Its average latency is named after its
+// unit, e.g. "avg_latency_ms": only the field of that unit is set.
+type hostRecord struct {
+	IP           string   `json:"ip"`
+	AvgLatencyNs *float64 `json:"avg_latency_ns,omitempty"`
+	AvgLatencyUs *float64 `json:"avg_latency_us,omitempty"`
+	AvgLatencyMs *float64 `json:"avg_latency_ms,omitempty"`
+	AvgLatencyS  *float64 `json:"avg_latency_s,omitempty"`
+	PacketLoss   float64  `json:"packet_loss"`
+	PacketsSent  int      `json:"packets_sent"`
+	PacketsRecv  int      `json:"packets_recv"`
+	Online       bool     `json:"online"`
+}
+
+// Encode writes the results to w in the given format, one entry per host sorted by IP address, with the average
+// latency in milliseconds.
+func Encode(w io.Writer, results map[string]ping.Result, format string) error {
+	unit := subping.RTTUnitMilliseconds
+	records := toRecords(results, unit)
+
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(records)
+	case FormatCSV:
+		return encodeCSV(w, results, unit)
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// UploadResults encodes the results in the given format and writes them to key in bucket of the
+// S3-compatible object store at endpoint. The endpoint is a URL such as "https://s3.amazonaws.com";
+// a bare host defaults to HTTPS.
+//
+// Credentials are read from the standard AWS (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN)
+// or MinIO (MINIO_ACCESS_KEY, MINIO_SECRET_KEY) environment variables, or from the shared AWS credentials file.
+// The region is read from AWS_REGION and detected from the bucket when unset.
+func UploadResults(ctx context.Context, endpoint, bucket, key string, results map[string]ping.Result, format string) error {
+	var buf bytes.Buffer
+	if err := Encode(&buf, results, format); err != nil {
+		return err
+	}
+
+	host, secure, err := parseEndpoint(endpoint)
+	if err != nil {
+		return err
+	}
+
+	client, err := minio.New(host, &minio.Options{
+		Creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{},
+		}),
+		Secure: secure,
+		Region: os.Getenv("AWS_REGION"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create object store client: %w", err)
+	}
+
+	_, err = client.PutObject(ctx, bucket, key, &buf, int64(buf.Len()), minio.PutObjectOptions{
+		ContentType: contentType(format),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload results to %s/%s: %w", bucket, key, err)
+	}
+
+	return nil
+}
+
+// parseEndpoint splits an endpoint URL into the host expected by the object store client
+// and whether TLS should be used.
+func parseEndpoint(endpoint string) (string, bool, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint, true, nil
+	}
+
+	switch u.Scheme {
+	case "https":
+		return u.Host, true, nil
+	case "http":
+		return u.Host, false, nil
+	default:
+		return "", false, fmt.Errorf("unsupported endpoint scheme %q", u.Scheme)
+	}
+}
+
+// contentType returns the MIME type of the given export format.
+func contentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv"
+	}
+
+	return "application/json"
+}
+
+// toRecords converts the results to records sorted by IP address, with the average latency in unit.
+func toRecords(results map[string]ping.Result, unit subping.RTTUnit) []hostRecord {
+	records := make([]hostRecord, 0, len(results))
+	for _, ip := range sortedIPs(results) {
+		records = append(records, newRecord(ip, results[ip], unit))
+	}
+
+	return records
+}
+
+// sortedIPs returns the IP addresses of results sorted by their byte representation.
+func sortedIPs(results map[string]ping.Result) []string {
+	ips := make([]string, 0, len(results))
+	for ip := range results {
+		ips = append(ips, ip)
+	}
+
+	sort.Slice(ips, func(i, j int) bool {
+		return bytes.Compare(net.ParseIP(ips[i]).To16(), net.ParseIP(ips[j]).To16()) < 0
+	})
+
+	return ips
+}
+
+// newRecord converts the result of the host ip to a record, with the average latency in unit, defaulting to
+// milliseconds.
+func newRecord(ip string, r ping.Result, unit subping.RTTUnit) hostRecord {
+	record := hostRecord{
+		IP:          ip,
+		PacketLoss:  r.PacketLoss,
+		PacketsSent: r.PacketsSent,
+		PacketsRecv: r.PacketsRecv,
+		Online:      r.PacketsRecv > 0,
+	}
+
+	switch unit {
+	case subping.RTTUnitNanoseconds:
+		latency := float64(r.AvgRtt)
+		record.AvgLatencyNs = &latency
+	case subping.RTTUnitMicroseconds:
+		latency := float64(r.AvgRtt) / float64(time.Microsecond)
+		record.AvgLatencyUs = &latency
+	case subping.RTTUnitSeconds:
+		latency := r.AvgRtt.Seconds()
+		record.AvgLatencyS = &latency
+	default:
+		latency := float64(r.AvgRtt) / float64(time.Millisecond)
+		record.AvgLatencyMs = &latency
+	}
+
+	return record
+}
+
+// encodeCSV writes the results to w as CSV with a header row, one row per host sorted by IP address, in the columns
+// of subping.CSVFormatter with the average latency in unit.
+func encodeCSV(w io.Writer, results map[string]ping.Result, unit subping.RTTUnit) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(subping.CSVHeader(unit)); err != nil {
+		return err
+	}
+
+	for _, ip := range sortedIPs(results) {
+		if err := cw.Write(subping.CSVRow(ip, results[ip], unit)); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
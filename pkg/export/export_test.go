@@ -0,0 +1,127 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping/pkg/export"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// fakeObjectStore is an in-memory S3-compatible server that stores the objects written with PUT.
+type fakeObjectStore struct {
+	mu      sync.Mutex
+	objects map[string]string
+	types   map[string]string
+}
+
+func (s *fakeObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Has("location"):
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = io.WriteString(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+	case r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body = decodeAWSChunked(body)
+		}
+		s.objects[r.URL.Path] = string(body)
+		s.types[r.URL.Path] = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"fake"`)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// decodeAWSChunked strips the chunk headers of a body sent with the aws-chunked content encoding.
+func decodeAWSChunked(body []byte) []byte {
+	var decoded []byte
+
+	for len(body) > 0 {
+		header, rest, _ := bytes.Cut(body, []byte("\r\n"))
+		sizeHex, _, _ := bytes.Cut(header, []byte(";"))
+
+		size, err := strconv.ParseInt(string(sizeHex), 16, 64)
+		if err != nil || size == 0 || int(size) > len(rest) {
+			break
+		}
+
+		decoded = append(decoded, rest[:size]...)
+		body = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+
+	return decoded
+}
+
+func TestUploadResults(t *testing.T) {
+	results := map[string]ping.Result{
+		"10.0.0.2": {PacketLoss: 100, PacketsSent: 1},
+		"10.0.0.1": {AvgRtt: 1500 * time.Microsecond, PacketsSent: 1, PacketsRecv: 1},
+	}
+
+	tests := []struct {
+		name            string
+		format          string
+		wantContentType string
+		want            string
+		wantErr         bool
+	}{
+		{
+			name:            "JSON",
+			format:          export.FormatJSON,
+			wantContentType: "application/json",
+			want: `[{"ip":"10.0.0.1","avg_latency_ms":1.5,"packet_loss":0,"packets_sent":1,"packets_recv":1,"online":true},` +
+				`{"ip":"10.0.0.2","avg_latency_ms":0,"packet_loss":100,"packets_sent":1,"packets_recv":0,"online":false}]` + "\n",
+		},
+		{
+			name:            "CSV",
+			format:          export.FormatCSV,
+			wantContentType: "text/csv",
+			want: "ip,avg_latency_ms,packet_loss,packets_sent,packets_recv,online\n" +
+				"10.0.0.1,1.500,0.00,1,1,true\n" +
+				"10.0.0.2,0.000,100.00,1,0,false\n",
+		},
+		{
+			name:    "Unsupported format",
+			format:  "xml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AWS_ACCESS_KEY_ID", "test")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+			store := &fakeObjectStore{objects: make(map[string]string), types: make(map[string]string)}
+			server := httptest.NewServer(store)
+			defer server.Close()
+
+			err := export.UploadResults(context.Background(), server.URL, "scans", "office/latest", results, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UploadResults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := store.objects["/scans/office/latest"]; got != tt.want {
+				t.Errorf("UploadResults() object =\n%s\nwant =\n%s", got, tt.want)
+			}
+
+			if got := store.types["/scans/office/latest"]; got != tt.wantContentType {
+				t.Errorf("UploadResults() content type = %v, want %v", got, tt.wantContentType)
+			}
+		})
+	}
+}
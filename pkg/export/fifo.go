@@ -0,0 +1,37 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// OpenFIFOSink opens the named pipe at path for writing and returns a Sink writing every result to it as a line of
+// NDJSON, so another process on the same host can consume the results without a network socket.
+// When wait is set, OpenFIFOSink blocks until a reader opens the FIFO; otherwise it fails when there is no reader.
+func OpenFIFOSink(path string, wait bool) (*Sink, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the FIFO %s: %w", path, err)
+	}
+
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		return nil, fmt.Errorf("%s is not a FIFO", path)
+	}
+
+	flag := os.O_WRONLY
+	if !wait {
+		flag |= syscall.O_NONBLOCK
+	}
+
+	f, err := os.OpenFile(path, flag, 0)
+	if errors.Is(err, syscall.ENXIO) {
+		return nil, fmt.Errorf("no reader has opened the FIFO %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the FIFO %s: %w", path, err)
+	}
+
+	return NewNDJSONSink(f), nil
+}
@@ -0,0 +1,129 @@
+//go:build unix
+
+package export_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping/pkg/export"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// makeFIFO creates a named pipe in a temporary directory and returns its path.
+func makeFIFO(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "subping.fifo")
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		t.Fatalf("Mkfifo() error = %v", err)
+	}
+
+	return path
+}
+
+func TestDialSinkFIFO(t *testing.T) {
+	path := makeFIFO(t)
+
+	received := make(chan []streamFrame, 1)
+	go func() {
+		var frames []streamFrame
+		defer func() { received <- frames }()
+
+		f, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var frame streamFrame
+			if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+				return
+			}
+
+			frames = append(frames, frame)
+		}
+	}()
+
+	sink, err := export.DialSink("fifo:"+path, true)
+	if err != nil {
+		t.Fatalf("DialSink() error = %v", err)
+	}
+
+	if err := sink.Write("10.0.0.1", ping.Result{AvgRtt: 2 * time.Millisecond, PacketsSent: 1, PacketsRecv: 1}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if err := sink.Write("10.0.0.2", ping.Result{PacketLoss: 100, PacketsSent: 1}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := []streamFrame{
+		{IP: "10.0.0.1", AvgLatencyMs: 2, Online: true},
+		{IP: "10.0.0.2", AvgLatencyMs: 0, Online: false},
+	}
+
+	got := <-received
+	if len(got) != len(want) {
+		t.Fatalf("received %d lines, want %d: %+v", len(got), len(want), got)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDialSinkFIFONoWait(t *testing.T) {
+	path := makeFIFO(t)
+
+	if sink, err := export.DialSink("fifo:"+path, false); err == nil {
+		sink.Close()
+		t.Errorf("DialSink() without waiting and without a reader error = nil, want an error")
+	}
+}
+
+func TestOpenFIFOSinkErrors(t *testing.T) {
+	regular := filepath.Join(t.TempDir(), "results.ndjson")
+	if err := os.WriteFile(regular, nil, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "No reader without waiting",
+			path: makeFIFO(t),
+		},
+		{
+			name: "Regular file",
+			path: regular,
+		},
+		{
+			name: "Missing file",
+			path: filepath.Join(t.TempDir(), "missing.fifo"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sink, err := export.OpenFIFOSink(tt.path, false); err == nil {
+				sink.Close()
+				t.Errorf("OpenFIFOSink(%q) error = nil, want an error", tt.path)
+			}
+		})
+	}
+}
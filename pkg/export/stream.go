@@ -0,0 +1,101 @@
+package export
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// Sink streams the result of each host to a consumer as soon as it completes.
+// Every result is written as a frame made of its JSON object prefixed by its length,
+// a 4-byte big-endian unsigned integer, or as a line of NDJSON for the sinks returned by NewNDJSONSink.
+// It is safe for concurrent use.
+type Sink struct {
+	// RTTUnit is the unit of the average latency field, defaulting to milliseconds. It must be set before the first
+	// Write.
+	RTTUnit subping.RTTUnit
+
+	mu sync.Mutex
+	w  io.WriteCloser
+
+	// ndjson writes every result as a newline-terminated JSON object instead of a length-prefixed frame.
+	ndjson bool
+}
+
+// NewSink returns a Sink writing the frames to w.
+func NewSink(w io.WriteCloser) *Sink {
+	return &Sink{w: w}
+}
+
+// NewNDJSONSink returns a Sink writing every result to w as a line of newline-delimited JSON.
+func NewNDJSONSink(w io.WriteCloser) *Sink {
+	return &Sink{w: w, ndjson: true}
+}
+
+// DialSink connects to the stream target and returns a Sink writing to it. The target is the network
+// followed by the address, either "tcp:host:port", "unix:/path/to.sock" or "fifo:/path/to.fifo".
+// A FIFO receives NDJSON rather than length-prefixed frames and is opened with OpenFIFOSink, which blocks until a
+// reader opens it when wait is set. The sockets ignore wait.
+func DialSink(target string, wait bool) (*Sink, error) {
+	network, address, ok := strings.Cut(target, ":")
+	if !ok || address == "" {
+		return nil, fmt.Errorf("invalid stream target %q, expected tcp:host:port, unix:/path/to.sock "+
+			"or fifo:/path/to.fifo", target)
+	}
+
+	switch network {
+	case "tcp", "unix":
+	case "fifo":
+		return OpenFIFOSink(address, wait)
+	default:
+		return nil, fmt.Errorf("unsupported stream network %q in %q, expected tcp, unix or fifo", network, target)
+	}
+
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the stream target %s: %w", target, err)
+	}
+
+	return NewSink(conn), nil
+}
+
+// Write sends the result of the host ip as a single frame.
+func (s *Sink) Write(ip string, result ping.Result) error {
+	payload, err := json.Marshal(newRecord(ip, result, s.RTTUnit))
+	if err != nil {
+		return err
+	}
+
+	var frame []byte
+	if s.ndjson {
+		frame = append(payload, '\n')
+	} else {
+		frame = make([]byte, 4+len(payload))
+		binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+		copy(frame[4:], payload)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write(frame); err != nil {
+		return fmt.Errorf("failed to stream the result of %s: %w", ip, err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying connection.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.w.Close()
+}
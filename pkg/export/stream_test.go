@@ -0,0 +1,158 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/export"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// streamFrame is the decoded JSON object of a stream frame.
+type streamFrame struct {
+	IP           string  `json:"ip"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	Online       bool    `json:"online"`
+}
+
+// readFrames accepts a single connection on l and decodes every frame sent on it until it is closed.
+func readFrames(t *testing.T, l net.Listener) <-chan []streamFrame {
+	t.Helper()
+
+	received := make(chan []streamFrame, 1)
+
+	go func() {
+		var frames []streamFrame
+		defer func() { received <- frames }()
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var length [4]byte
+			if _, err := io.ReadFull(conn, length[:]); err != nil {
+				return
+			}
+
+			payload := make([]byte, binary.BigEndian.Uint32(length[:]))
+			if _, err := io.ReadFull(conn, payload); err != nil {
+				return
+			}
+
+			var frame streamFrame
+			if err := json.Unmarshal(payload, &frame); err != nil {
+				return
+			}
+
+			frames = append(frames, frame)
+		}
+	}()
+
+	return received
+}
+
+func TestDialSink(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "subping.sock")
+
+	tests := []struct {
+		name    string
+		network string
+		address string
+	}{
+		{
+			name:    "Unix domain socket",
+			network: "unix",
+			address: socket,
+		},
+		{
+			name:    "TCP",
+			network: "tcp",
+			address: "127.0.0.1:0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := net.Listen(tt.network, tt.address)
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+			defer l.Close()
+
+			received := readFrames(t, l)
+
+			sink, err := export.DialSink(tt.network+":"+l.Addr().String(), false)
+			if err != nil {
+				t.Fatalf("DialSink() error = %v", err)
+			}
+
+			if err := sink.Write("10.0.0.1", ping.Result{AvgRtt: 2 * time.Millisecond, PacketsSent: 1, PacketsRecv: 1}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			if err := sink.Write("10.0.0.2", ping.Result{PacketLoss: 100, PacketsSent: 1}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			if err := sink.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			want := []streamFrame{
+				{IP: "10.0.0.1", AvgLatencyMs: 2, Online: true},
+				{IP: "10.0.0.2", AvgLatencyMs: 0, Online: false},
+			}
+
+			got := <-received
+			if len(got) != len(want) {
+				t.Fatalf("received %d frames, want %d: %+v", len(got), len(want), got)
+			}
+
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("frame %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDialSinkInvalidTarget(t *testing.T) {
+	for _, target := range []string{"", "/path/to.sock", "udp:127.0.0.1:9000", "unix:"} {
+		if _, err := export.DialSink(target, false); err == nil {
+			t.Errorf("DialSink(%q) error = nil, want an error", target)
+		}
+	}
+}
+
+// bufferCloser is an io.WriteCloser writing to a buffer.
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error { return nil }
+
+func TestSinkRTTUnit(t *testing.T) {
+	var buf bufferCloser
+
+	sink := export.NewNDJSONSink(&buf)
+	sink.RTTUnit = subping.RTTUnitMicroseconds
+
+	if err := sink.Write("10.0.0.1", ping.Result{AvgRtt: 2 * time.Millisecond, PacketsSent: 1, PacketsRecv: 1}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := `{"ip":"10.0.0.1","avg_latency_us":2000,"packet_loss":0,"packets_sent":1,"packets_recv":1,"online":true}` + "\n"
+	if buf.String() != want {
+		t.Errorf("Write() wrote %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,102 @@
+// Package geo provides a rough geolocation of public IP addresses from an offline MaxMind GeoLite2 City or Country
+// database, such as GeoLite2-City.mmdb.
+//
+// The database is read in memory once, so lookups do not touch the disk and the DB does not need to be closed.
+// Private, loopback, link-local and other non-routable addresses are never looked up.
+//
+// Example:
+//
+//	db, err := geo.Open("/usr/share/GeoIP/GeoLite2-City.mmdb")
+//	if err != nil {
+//		fmt.Println("Error:", err)
+//		return
+//	}
+//
+//	location, ok, err := db.Lookup(net.ParseIP("81.2.69.142"))
+//	if err != nil {
+//		fmt.Println("Error:", err)
+//		return
+//	}
+//	if ok {
+//		fmt.Println(location.Country, location.City)
+//	}
+package geo
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is the rough geolocation of an IP address.
+type Location struct {
+	// Country is the ISO 3166-1 alpha-2 code of the country, e.g. "GB".
+	Country string
+
+	// City is the English name of the city. It is empty when the database has no city-level data.
+	City string
+}
+
+// record is the subset of a GeoLite2 City or Country record decoded by Lookup.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// DB is an offline geolocation database. It is safe for concurrent use.
+type DB struct {
+	reader *maxminddb.Reader
+}
+
+// Open reads the MaxMind database at path in memory.
+func Open(path string) (*DB, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := maxminddb.FromBytes(buf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid geolocation database %s: %w", path, err)
+	}
+
+	return &DB{reader: reader}, nil
+}
+
+// Lookup returns the location of ip. The boolean is false when ip is not a public address or is missing
+// from the database.
+func (db *DB) Lookup(ip net.IP) (Location, bool, error) {
+	if !IsPublic(ip) {
+		return Location{}, false, nil
+	}
+
+	var r record
+
+	_, ok, err := db.reader.LookupNetwork(ip, &r)
+	if err != nil || !ok {
+		return Location{}, false, err
+	}
+
+	return Location{Country: r.Country.ISOCode, City: r.City.Names["en"]}, true, nil
+}
+
+// Locate returns the country and city of ip, or empty strings when ip is not a public address or is missing from the
+// database. It implements subping.Locator, so a DB can be set as Options.Locator.
+func (db *DB) Locate(ip net.IP) (country, city string, err error) {
+	location, _, err := db.Lookup(ip)
+
+	return location.Country, location.City, err
+}
+
+// IsPublic reports whether ip is a globally routable unicast address, i.e. neither private, loopback,
+// link-local, multicast nor unspecified.
+func IsPublic(ip net.IP) bool {
+	return ip != nil && ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
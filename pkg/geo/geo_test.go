@@ -0,0 +1,78 @@
+package geo_test
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/fadhilyori/subping/pkg/geo"
+)
+
+// fixture is a tiny GeoLite2 City database holding 81.2.69.0/24 (GB, London), 2001:218::/32 (JP, Tokyo)
+// and 10.0.0.0/8 (ZZ, Private), the latter to check that private addresses are skipped.
+var fixture = filepath.Join("testdata", "GeoLite2-City-Test.mmdb")
+
+func TestLookup(t *testing.T) {
+	db, err := geo.Open(fixture)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		ip     string
+		want   geo.Location
+		wantOk bool
+	}{
+		{
+			name:   "Public IPv4 address",
+			ip:     "81.2.69.142",
+			want:   geo.Location{Country: "GB", City: "London"},
+			wantOk: true,
+		},
+		{
+			name:   "Public IPv6 address",
+			ip:     "2001:218::1",
+			want:   geo.Location{Country: "JP", City: "Tokyo"},
+			wantOk: true,
+		},
+		{
+			name: "Public address missing from the database",
+			ip:   "81.2.70.1",
+		},
+		{
+			name: "Private address present in the database is skipped",
+			ip:   "10.1.2.3",
+		},
+		{
+			name: "Loopback address",
+			ip:   "127.0.0.1",
+		},
+		{
+			name: "Link-local address",
+			ip:   "fe80::1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := db.Lookup(net.ParseIP(tt.ip))
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("Lookup() = %+v, %t, want %+v, %t", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestOpenInvalidDatabase(t *testing.T) {
+	if _, err := geo.Open(filepath.Join("testdata", "missing.mmdb")); err == nil {
+		t.Error("Open() of a missing file error = nil, want an error")
+	}
+
+	if _, err := geo.Open("geo.go"); err == nil {
+		t.Error("Open() of a non-database file error = nil, want an error")
+	}
+}
@@ -0,0 +1,137 @@
+// Package history provides persistence of scan results in a SQL database and reports computed from the stored runs.
+//
+// The package stores each scan as a run holding the scanned subnet, its start time and the per-host results,
+// and computes aggregates such as the average utilization of a subnet over time.
+// The schema uses portable column types and "?" placeholders, as supported by SQLite and MySQL drivers.
+//
+// Example:
+//
+//	if err := history.Migrate(db); err != nil {
+//		fmt.Println("Error:", err)
+//		return
+//	}
+//
+//	if _, err := history.SaveRun(db, "192.168.0.0/24", startTime, sp.Results); err != nil {
+//		fmt.Println("Error:", err)
+//		return
+//	}
+//
+//	utilization, err := history.ComputeUtilization(db, "192.168.0.0/24", time.Now().AddDate(0, -1, 0))
+//	if err != nil {
+//		fmt.Println("Error:", err)
+//		return
+//	}
+//	fmt.Printf("Utilization: %.0f%%\n", utilization*100)
+package history
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// ErrNoRuns is returned when no stored run matches a query.
+var ErrNoRuns = errors.New("no stored runs match the query")
+
+// schema holds the statements creating the tables used to store the runs.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS subping_runs (
+		id INTEGER PRIMARY KEY,
+		cidr TEXT NOT NULL,
+		started_at INTEGER NOT NULL,
+		total_hosts INTEGER NOT NULL,
+		online_hosts INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS subping_results (
+		run_id INTEGER NOT NULL REFERENCES subping_runs (id),
+		ip TEXT NOT NULL,
+		avg_rtt_ns INTEGER NOT NULL,
+		packet_loss REAL NOT NULL,
+		packets_sent INTEGER NOT NULL,
+		packets_recv INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS subping_runs_cidr_started_at ON subping_runs (cidr, started_at)`,
+}
+
+// Migrate creates the tables used to store the runs if they do not exist yet.
+func Migrate(db *sql.DB) error {
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to migrate the history schema: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveRun stores the results of a scan of cidr started at startedAt and returns the ID of the stored run.
+func SaveRun(db *sql.DB, cidr string, startedAt time.Time, results map[string]ping.Result) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	online := 0
+	for _, r := range results {
+		if r.PacketsRecv > 0 {
+			online++
+		}
+	}
+
+	res, err := tx.Exec(
+		`INSERT INTO subping_runs (cidr, started_at, total_hosts, online_hosts) VALUES (?, ?, ?, ?)`,
+		cidr, startedAt.UnixNano(), len(results), online,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to store the run: %w", err)
+	}
+
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for ip, r := range results {
+		_, err := tx.Exec(
+			`INSERT INTO subping_results (run_id, ip, avg_rtt_ns, packet_loss, packets_sent, packets_recv) VALUES (?, ?, ?, ?, ?, ?)`,
+			runID, ip, int64(r.AvgRtt), r.PacketLoss, r.PacketsSent, r.PacketsRecv,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to store the result of %s: %w", ip, err)
+		}
+	}
+
+	return runID, tx.Commit()
+}
+
+// ComputeUtilization returns the average fraction of hosts online, between 0 and 1, across the runs
+// of cidr stored since the given time. A zero since includes every stored run.
+// It returns ErrNoRuns when no run matches.
+func ComputeUtilization(db *sql.DB, cidr string, since time.Time) (float64, error) {
+	var utilization sql.NullFloat64
+
+	sinceNs := int64(math.MinInt64)
+	if !since.IsZero() {
+		sinceNs = since.UnixNano()
+	}
+
+	err := db.QueryRow(
+		`SELECT AVG(CAST(online_hosts AS REAL) / total_hosts) FROM subping_runs
+		WHERE cidr = ? AND started_at >= ? AND total_hosts > 0`,
+		cidr, sinceNs,
+	).Scan(&utilization)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute the utilization of %s: %w", cidr, err)
+	}
+
+	if !utilization.Valid {
+		return 0, ErrNoRuns
+	}
+
+	return utilization.Float64, nil
+}
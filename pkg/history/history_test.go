@@ -0,0 +1,105 @@
+package history_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/fadhilyori/subping/pkg/history"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// makeResults returns results for total hosts of 10.0.0.0/24 with the first online hosts replying.
+func makeResults(total, online int) map[string]ping.Result {
+	results := make(map[string]ping.Result, total)
+	for i := 0; i < total; i++ {
+		r := ping.Result{PacketsSent: 1, PacketLoss: 100}
+		if i < online {
+			r = ping.Result{AvgRtt: time.Millisecond, PacketsSent: 1, PacketsRecv: 1}
+		}
+		results[fmt.Sprintf("10.0.0.%d", i)] = r
+	}
+
+	return results
+}
+
+func TestComputeUtilization(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := history.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	runs := []struct {
+		cidr      string
+		startedAt time.Time
+		total     int
+		online    int
+	}{
+		{cidr: "10.0.0.0/24", startedAt: now.AddDate(0, -2, 0), total: 10, online: 10},
+		{cidr: "10.0.0.0/24", startedAt: now.AddDate(0, 0, -20), total: 10, online: 2},
+		{cidr: "10.0.0.0/24", startedAt: now.AddDate(0, 0, -10), total: 10, online: 6},
+		{cidr: "10.0.1.0/24", startedAt: now.AddDate(0, 0, -5), total: 10, online: 9},
+	}
+	for _, run := range runs {
+		if _, err := history.SaveRun(db, run.cidr, run.startedAt, makeResults(run.total, run.online)); err != nil {
+			t.Fatalf("SaveRun() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		cidr    string
+		since   time.Time
+		want    float64
+		wantErr error
+	}{
+		{
+			name:  "Last month",
+			cidr:  "10.0.0.0/24",
+			since: now.AddDate(0, -1, 0),
+			want:  0.4,
+		},
+		{
+			name:  "All runs",
+			cidr:  "10.0.0.0/24",
+			since: time.Time{},
+			want:  0.6,
+		},
+		{
+			name:  "Other subnet",
+			cidr:  "10.0.1.0/24",
+			since: now.AddDate(0, -1, 0),
+			want:  0.9,
+		},
+		{
+			name:    "No runs",
+			cidr:    "10.0.2.0/24",
+			since:   now.AddDate(0, -1, 0),
+			wantErr: history.ErrNoRuns,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := history.ComputeUtilization(db, tt.cidr, tt.since)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ComputeUtilization() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ComputeUtilization() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Package metrics exposes subping results as Prometheus metrics, making subping usable as a black-box prober.
+//
+// Every host is reported by three gauges labeled with its IP address: subping_host_up, subping_avg_rtt_seconds
+// and subping_packet_loss.
+//
+// Example:
+//
+//	reg := prometheus.NewRegistry()
+//	if _, err := metrics.Register(reg, sp.Results); err != nil {
+//		fmt.Println("Error:", err)
+//		return
+//	}
+//	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// Exporter holds the gauges reporting the results of the pinged hosts. It is safe for concurrent use.
+type Exporter struct {
+	up         *prometheus.GaugeVec
+	avgRTT     *prometheus.GaugeVec
+	packetLoss *prometheus.GaugeVec
+}
+
+// NewExporter returns an Exporter whose gauges are registered on reg. It returns an error if they cannot be
+// registered, e.g. because reg already holds gauges of the same names.
+func NewExporter(reg prometheus.Registerer) (*Exporter, error) {
+	e := &Exporter{
+		up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "subping_host_up",
+			Help: "Whether the host replied to at least one ping request of its last scan (1) or not (0).",
+		}, []string{"ip"}),
+		avgRTT: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "subping_avg_rtt_seconds",
+			Help: "Average round-trip time of the ping requests of the last scan of the host.",
+		}, []string{"ip"}),
+		packetLoss: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "subping_packet_loss",
+			Help: "Percentage, from 0 to 100, of the ping requests of the last scan of the host left unanswered.",
+		}, []string{"ip"}),
+	}
+
+	for _, collector := range []prometheus.Collector{e.up, e.avgRTT, e.packetLoss} {
+		if err := reg.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// Register returns an Exporter registered on reg and reporting the given results, e.g. the Results of a
+// Subping instance after Run.
+func Register(reg prometheus.Registerer, results map[string]ping.Result) (*Exporter, error) {
+	e, err := NewExporter(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Update(results)
+
+	return e, nil
+}
+
+// Set reports the result of the host ip, replacing its previous result.
+func (e *Exporter) Set(ip string, result ping.Result) {
+	var up float64
+	if result.PacketsRecv > 0 {
+		up = 1
+	}
+
+	e.up.WithLabelValues(ip).Set(up)
+	e.avgRTT.WithLabelValues(ip).Set(result.AvgRtt.Seconds())
+	e.packetLoss.WithLabelValues(ip).Set(result.PacketLoss)
+}
+
+// Update reports every given result, replacing the previous results of the same hosts.
+func (e *Exporter) Update(results map[string]ping.Result) {
+	for ip, result := range results {
+		e.Set(ip, result)
+	}
+}
@@ -0,0 +1,50 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/fadhilyori/subping/pkg/metrics"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestRegister(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	e, err := metrics.Register(reg, map[string]ping.Result{
+		"10.0.0.1": {AvgRtt: 20 * time.Millisecond, PacketLoss: 50, PacketsSent: 2, PacketsRecv: 1},
+		"10.0.0.2": {PacketLoss: 100, PacketsSent: 2},
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// A later result replaces the previous one of the same host.
+	e.Set("10.0.0.2", ping.Result{AvgRtt: time.Millisecond, PacketsSent: 2, PacketsRecv: 2})
+
+	want := `
+# HELP subping_avg_rtt_seconds Average round-trip time of the ping requests of the last scan of the host.
+# TYPE subping_avg_rtt_seconds gauge
+subping_avg_rtt_seconds{ip="10.0.0.1"} 0.02
+subping_avg_rtt_seconds{ip="10.0.0.2"} 0.001
+# HELP subping_host_up Whether the host replied to at least one ping request of its last scan (1) or not (0).
+# TYPE subping_host_up gauge
+subping_host_up{ip="10.0.0.1"} 1
+subping_host_up{ip="10.0.0.2"} 1
+# HELP subping_packet_loss Percentage, from 0 to 100, of the ping requests of the last scan of the host left unanswered.
+# TYPE subping_packet_loss gauge
+subping_packet_loss{ip="10.0.0.1"} 50
+subping_packet_loss{ip="10.0.0.2"} 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Errorf("GatherAndCompare() error = %v", err)
+	}
+
+	if _, err := metrics.NewExporter(reg); err == nil {
+		t.Error("NewExporter() on a registry already holding the gauges did not fail")
+	}
+}
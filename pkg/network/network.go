@@ -1,7 +1,8 @@
 // Package network provides functionality for working with IP networks and subnet hosts.
 //
 // The package includes functions for iterating over hosts within a subnet, calculating the total number of hosts
-// in a subnet, parsing CIDR notation, and obtaining the first and last IP addresses from an IP network.
+// in a subnet, parsing CIDR notation, and obtaining the first and last IP addresses, or the network and broadcast
+// addresses, from an IP network.
 //
 // Examples:
 //
@@ -29,8 +30,11 @@ package network
 
 import (
 	"errors"
+	"fmt"
 	"math"
+	"math/rand"
 	"net"
+	"strconv"
 	"sync"
 )
 
@@ -51,6 +55,23 @@ type SubnetHostsIterator struct {
 	// TotalHosts represents the total number of hosts in the subnet.
 	TotalHosts int
 
+	// order holds the offsets of the hosts to yield, in order, when the iterator is shuffled or restricted to its
+	// common hosts. It is nil when every host is yielded in ascending order.
+	order []int
+
+	// shuffled reports whether the hosts are yielded in the pseudo-random order derived from seed, see Shuffle.
+	shuffled bool
+	seed     int64
+
+	// commonOnly reports whether only the common hosts are yielded, see RestrictToCommonHosts.
+	commonOnly bool
+
+	// position is the index of the next offset in order to yield.
+	position int
+
+	// usableOnly reports whether FirstIP and LastIP exclude the network and broadcast addresses of IPNet.
+	usableOnly bool
+
 	// mu is a mutex used for thread-safety.
 	mu sync.Mutex
 }
@@ -79,35 +100,383 @@ func NewSubnetHostsIterator(ipNet *net.IPNet) *SubnetHostsIterator {
 	}
 }
 
-// Next returns the next host IP in the subnet. It locks the iterator for thread-safety.
+// NewSubnetHostsIteratorUsable creates a new SubnetHostsIterator for the given IP network that skips the network and
+// broadcast addresses of IPv4 subnets shorter than /31, so FirstIP, LastIP and TotalHosts only cover the usable hosts.
+// IPv4 /31 and /32 subnets and IPv6 subnets are iterated in full, as with NewSubnetHostsIterator.
+func NewSubnetHostsIteratorUsable(ipNet *net.IPNet) *SubnetHostsIterator {
+	it := NewSubnetHostsIterator(ipNet)
+
+	broadcast, err := BroadcastAddress(ipNet)
+	if err != nil {
+		return it
+	}
+
+	it.FirstIP = ipAtOffset(NetworkAddress(ipNet), 1)
+	it.LastIP = broadcast
+	it.LastIP[len(it.LastIP)-1]--
+	it.TotalHosts -= 2
+	it.usableOnly = true
+
+	return it
+}
+
+// Next returns the next host IP in the subnet. It locks the iterator for thread-safety, so several goroutines
+// may call Next on the same iterator concurrently: each host is yielded exactly once, and every call returns an
+// independent copy of the IP that later calls never modify.
 // If it's the first call to Next, it returns the first host IP in the subnet.
-// If there are no more hosts in the subnet or if the current IP is outside the subnet,
-// it returns nil.
+// If there are no more hosts in the subnet, it returns nil.
 func (it *SubnetHostsIterator) Next() *net.IP {
 	it.mu.Lock()
 	defer it.mu.Unlock()
 
+	if !it.advance() {
+		return nil
+	}
+
+	return cloneIP(*it.CurrentIP)
+}
+
+// NextInto is like Next, but copies the next host IP into buf, reusing its storage when it is large enough, and
+// returns it. Passing the IP returned by the previous call as buf avoids allocating memory for every host, which
+// matters when iterating over large IPv6 subnets. It returns nil if there are no more hosts in the subnet.
+func (it *SubnetHostsIterator) NextInto(buf net.IP) net.IP {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if !it.advance() {
+		return nil
+	}
+
+	return append(buf[:0], *it.CurrentIP...)
+}
+
+// advance moves CurrentIP to the next host IP, updating it in place, and reports whether there was one.
+// The caller must hold mu.
+func (it *SubnetHostsIterator) advance() bool {
+	if it.order != nil {
+		if it.position >= len(it.order) {
+			return false
+		}
+
+		it.setCurrentIP(it.FirstIP)
+		addOffset(*it.CurrentIP, it.order[it.position])
+		it.position++
+
+		return true
+	}
+
 	if it.CurrentIP == nil {
-		currentIP := make(net.IP, len(it.FirstIP))
-		copy(currentIP, it.FirstIP)
+		it.setCurrentIP(it.FirstIP)
+
+		return true
+	}
+
+	if it.CurrentIP.Equal(it.LastIP) {
+		return false
+	}
+
+	addOffset(*it.CurrentIP, 1)
+
+	return true
+}
+
+// setCurrentIP copies ip into CurrentIP, only allocating it on the first call of an iteration.
+func (it *SubnetHostsIterator) setCurrentIP(ip net.IP) {
+	if it.CurrentIP == nil || len(*it.CurrentIP) != len(ip) {
+		currentIP := make(net.IP, len(ip))
 		it.CurrentIP = &currentIP
-		return it.CurrentIP
 	}
 
-	currentIP := *it.CurrentIP
+	copy(*it.CurrentIP, ip)
+}
+
+// Reset restarts the iteration, so the next call to Next returns the first host again. The order set by Shuffle or
+// RestrictToCommonHosts is kept.
+func (it *SubnetHostsIterator) Reset() {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	it.position = 0
+	it.CurrentIP = nil
+}
+
+// Shuffle makes the iterator yield the hosts of the subnet in a pseudo-random order derived from seed,
+// restarting the iteration. The same seed always produces the same order. Once restricted by RestrictToCommonHosts,
+// only the common hosts are shuffled.
+// The permutation is held in memory, which costs one int per host in the subnet.
+func (it *SubnetHostsIterator) Shuffle(seed int64) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	it.shuffled = true
+	it.seed = seed
+	it.updateOrder()
+}
+
+// CommonHosts returns a curated subset of the hosts of the subnet, in ascending order: the first and last handful of
+// addresses, excluding the network and broadcast addresses, and the commonly assigned .10, .100 and .200 offsets.
+// It allows a near-instant check of whether anyone is home in a large subnet. Subnets of at most 16 hosts are returned
+// in full.
+func (it *SubnetHostsIterator) CommonHosts() []net.IP {
+	offsets := it.commonHostOffsets()
+
+	hosts := make([]net.IP, 0, len(offsets))
+	for _, offset := range offsets {
+		hosts = append(hosts, ipAtOffset(it.FirstIP, offset))
+	}
+
+	return hosts
+}
+
+// RestrictToCommonHosts makes the iterator yield only the CommonHosts of the subnet, restarting the iteration. They
+// are yielded in ascending order, or in the pseudo-random order of the seed of Shuffle once it is shuffled.
+func (it *SubnetHostsIterator) RestrictToCommonHosts() {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	it.commonOnly = true
+	it.updateOrder()
+}
 
-	for i := len(currentIP) - 1; i >= 0; i-- {
-		currentIP[i]++
-		if currentIP[i] > 0 {
-			break
+// updateOrder computes the offsets yielded by the iterator from the settings of Shuffle and RestrictToCommonHosts,
+// restarting the iteration. The caller must hold mu.
+func (it *SubnetHostsIterator) updateOrder() {
+	switch {
+	case it.commonOnly:
+		it.order = it.commonHostOffsets()
+		if it.shuffled {
+			rand.New(rand.NewSource(it.seed)).Shuffle(len(it.order), func(i, j int) {
+				it.order[i], it.order[j] = it.order[j], it.order[i]
+			})
 		}
+	case it.shuffled:
+		it.order = rand.New(rand.NewSource(it.seed)).Perm(it.TotalHosts)
 	}
 
-	if !it.IPNet.Contains(currentIP) {
-		return nil
+	it.position = 0
+	it.CurrentIP = nil
+}
+
+// MaxEnumeratedHosts is the largest number of hosts listed by All and EnumerateHosts: the hosts of an IPv4 /16,
+// whose list takes a few megabytes of memory.
+const MaxEnumeratedHosts = 1 << 16
+
+// ErrTooManyToEnumerate is returned when listing the hosts of a subnet of more than MaxEnumeratedHosts hosts.
+var ErrTooManyToEnumerate = errors.New("too many hosts to enumerate")
+
+// All returns every host the iterator yields, in the order it yields them, without pinging them nor affecting the
+// iteration, e.g. to preview the targets of a scan. Every host is allocated, so it returns an error wrapping
+// ErrTooManyToEnumerate instead when there are more than MaxEnumeratedHosts of them.
+func (it *SubnetHostsIterator) All() ([]net.IP, error) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	total := it.TotalHosts
+	if it.order != nil {
+		total = len(it.order)
+	}
+
+	if total > MaxEnumeratedHosts {
+		return nil, fmt.Errorf("subnet %s has more than %d hosts: %w", it.IPNet, MaxEnumeratedHosts, ErrTooManyToEnumerate)
+	}
+
+	hosts := make([]net.IP, total)
+	for i := range hosts {
+		offset := i
+		if it.order != nil {
+			offset = it.order[i]
+		}
+
+		hosts[i] = ipAtOffset(it.FirstIP, offset)
+	}
+
+	return hosts, nil
+}
+
+// EnumerateHosts returns every host of the subnet in CIDR notation, in ascending order, as the scan of the subnet
+// would ping them. It returns an error wrapping ErrTooManyToEnumerate when the subnet, e.g. an IPv4 /15, has more
+// than MaxEnumeratedHosts hosts.
+func EnumerateHosts(cidr string) ([]string, error) {
+	it, err := NewSubnetHostsIteratorFromCIDRString(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := it.All()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, len(ips))
+	for i, ip := range ips {
+		hosts[i] = ip.String()
+	}
+
+	return hosts, nil
+}
+
+// commonHostOffsets returns the ascending offsets from FirstIP of the common hosts of the subnet. When the iterator
+// skips the network and broadcast addresses, the offsets are those of the whole subnet, shifted past the network
+// address and without the two skipped addresses.
+func (it *SubnetHostsIterator) commonHostOffsets() []int {
+	if !it.usableOnly {
+		return commonHostOffsets(it.TotalHosts)
+	}
+
+	offsets := make([]int, 0, it.TotalHosts)
+	for _, offset := range commonHostOffsets(it.TotalHosts + 2) {
+		if offset >= 1 && offset <= it.TotalHosts {
+			offsets = append(offsets, offset-1)
+		}
+	}
+
+	return offsets
+}
+
+// commonHostOffsets returns the ascending offsets of the common hosts of a subnet of total hosts.
+func commonHostOffsets(total int) []int {
+	const edge = 5
+
+	if total <= 16 {
+		offsets := make([]int, total)
+		for i := range offsets {
+			offsets[i] = i
+		}
+
+		return offsets
+	}
+
+	offsets := make([]int, 0, 2*edge+3)
+	for offset := 1; offset <= edge; offset++ {
+		offsets = append(offsets, offset)
+	}
+
+	for _, offset := range []int{10, 100, 200} {
+		if offset < total-1-edge {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	for offset := total - 1 - edge; offset < total-1; offset++ {
+		if offset > offsets[len(offsets)-1] {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	return offsets
+}
+
+// MultiSubnetHostsIterator iterates over the hosts of several subnets in turn, tagging each host with the
+// subnet it belongs to.
+type MultiSubnetHostsIterator struct {
+	// Iterators holds the iterators of the subnets, in iteration order.
+	Iterators []*SubnetHostsIterator
+
+	// TotalHosts represents the total number of hosts in all the subnets.
+	TotalHosts int
+
+	// current is the index in Iterators of the iterator yielding the next host.
+	current int
+
+	// mu is a mutex used for thread-safety.
+	mu sync.Mutex
+}
+
+// NewMultiSubnetHostsIterator creates a new MultiSubnetHostsIterator yielding the hosts of every given iterator,
+// one iterator after the other.
+func NewMultiSubnetHostsIterator(iterators ...*SubnetHostsIterator) *MultiSubnetHostsIterator {
+	it := &MultiSubnetHostsIterator{Iterators: iterators}
+	for _, sub := range iterators {
+		it.TotalHosts += sub.TotalHosts
+	}
+
+	return it
+}
+
+// NewRangeHostsIterator creates a new MultiSubnetHostsIterator yielding the hosts from first to last inclusive,
+// in ascending order, through the subnets returned by RangeToIPNets.
+func NewRangeHostsIterator(first, last net.IP) *MultiSubnetHostsIterator {
+	ipNets := RangeToIPNets(first, last)
+
+	iterators := make([]*SubnetHostsIterator, 0, len(ipNets))
+	for _, ipNet := range ipNets {
+		iterators = append(iterators, NewSubnetHostsIterator(ipNet))
+	}
+
+	return NewMultiSubnetHostsIterator(iterators...)
+}
+
+// NewSubnetHostsIteratorFromRange creates a new MultiSubnetHostsIterator yielding every host from start to end
+// inclusive, e.g. from 192.168.1.10 to 192.168.1.50. For IPv4, end may also be the last octet of the end of the range.
+// It returns an error if either address is invalid, if they are of different IP families or if end precedes start.
+func NewSubnetHostsIteratorFromRange(start, end string) (*MultiSubnetHostsIterator, error) {
+	first := net.ParseIP(start)
+	if first == nil {
+		return nil, fmt.Errorf("invalid range %q: invalid start %q", start+"-"+end, start)
+	}
+
+	spec, err := parseRangeSpec(start+"-"+end, first, end)
+	if err != nil {
+		return nil, err
 	}
 
-	return &currentIP
+	return NewRangeHostsIterator(spec.First, spec.Last), nil
+}
+
+// Next returns the next host IP and the subnet it belongs to. It locks the iterator for thread-safety, so it is safe
+// to call from several goroutines at once; like SubnetHostsIterator.Next, it returns an independent copy of the IP.
+// If there are no more hosts in any of the subnets, it returns nil.
+func (it *MultiSubnetHostsIterator) Next() (*net.IP, *net.IPNet) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	for ; it.current < len(it.Iterators); it.current++ {
+		sub := it.Iterators[it.current]
+		if ip := sub.Next(); ip != nil {
+			return ip, sub.IPNet
+		}
+	}
+
+	return nil, nil
+}
+
+// cloneIP returns a pointer to a copy of ip, so the caller cannot alias the iterator's state.
+func cloneIP(ip net.IP) *net.IP {
+	clone := make(net.IP, len(ip))
+	copy(clone, ip)
+
+	return &clone
+}
+
+// ipAtOffset returns a new IP equal to ip advanced by offset addresses.
+func ipAtOffset(ip net.IP, offset int) net.IP {
+	result := make(net.IP, len(ip))
+	copy(result, ip)
+	addOffset(result, offset)
+
+	return result
+}
+
+// addOffset advances ip in place by offset addresses.
+func addOffset(ip net.IP, offset int) {
+	carry := offset
+	for i := len(ip) - 1; i >= 0 && carry > 0; i-- {
+		sum := int(ip[i]) + carry&0xff
+		ip[i] = byte(sum)
+		carry = carry>>8 + sum>>8
+	}
+}
+
+// NormalizeIP returns the canonical string form of the given IP address, as produced by net.IP.String.
+// IPv6 addresses are compressed and lower-cased, so "2001:0DB8:0000::0001" and "2001:db8::1" normalize
+// to the same value. It returns an error if the string is not a valid IP address.
+func NormalizeIP(ip string) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	return parsed.String(), nil
 }
 
 // GetFirstIPAddressFromIPNet returns the first host IP address within the given IP network.
@@ -129,24 +498,77 @@ func GetLastIPAddressFromIPNet(ipNet *net.IPNet) net.IP {
 	return lastIP
 }
 
+// NetworkAddress returns the network address of the given IP network, its lowest address,
+// in its 4-byte form for IPv4 networks.
+func NetworkAddress(ipNet *net.IPNet) net.IP {
+	ip := ipNet.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	return ip.Mask(ipNet.Mask)
+}
+
+// BroadcastAddress returns the broadcast address of the given IPv4 network, its highest address.
+// It returns an error for IPv6 networks, which have no broadcast address, and for /31 and /32 networks,
+// whose addresses are all hosts (see RFC 3021).
+func BroadcastAddress(ipNet *net.IPNet) (net.IP, error) {
+	networkIP := NetworkAddress(ipNet)
+	if len(networkIP) != net.IPv4len {
+		return nil, fmt.Errorf("the IPv6 subnet %s has no broadcast address", ipNet)
+	}
+
+	mask := ipNet.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[net.IPv6len-net.IPv4len:]
+	}
+
+	if ones, _ := mask.Size(); ones >= 31 {
+		return nil, fmt.Errorf("the subnet %s has no broadcast address", ipNet)
+	}
+
+	broadcast := make(net.IP, net.IPv4len)
+	for i := range broadcast {
+		broadcast[i] = networkIP[i] | ^mask[i]
+	}
+
+	return broadcast, nil
+}
+
+// ErrTooManyHosts is returned when the number of hosts of a subnet, e.g. an IPv6 /64, does not fit in an int.
+var ErrTooManyHosts = errors.New("too many hosts to count")
+
 // CalculateTotalHostsFromCIDRString calculates the total number of hosts based on the provided CIDR string.
+// When the number does not fit in an int, it returns math.MaxInt and an error wrapping ErrTooManyHosts.
 func CalculateTotalHostsFromCIDRString(cidr string) (int, error) {
 	_, parsedCIDR, err := net.ParseCIDR(cidr)
 	if err != nil {
 		return 0, err
 	}
 
+	if !HostCountFits(parsedCIDR) {
+		return math.MaxInt, fmt.Errorf("subnet %s: %w", parsedCIDR, ErrTooManyHosts)
+	}
+
 	return CalculateTotalHosts(parsedCIDR), nil
 }
 
 // CalculateTotalHosts calculates the total number of hosts based on the provided IP network.
+// The result is clamped at math.MaxInt for subnets whose number of hosts does not fit in an int, see HostCountFits.
 func CalculateTotalHosts(ipNet *net.IPNet) int {
-	// Calculate the number of host bits
+	if !HostCountFits(ipNet) {
+		return math.MaxInt
+	}
+
 	prefixLength, totalBits := ipNet.Mask.Size()
-	hostBits := totalBits - prefixLength
 
-	// Calculate the total hosts based on the number of host bits
-	totalHosts := int(math.Pow(2, float64(hostBits)))
+	return 1 << (totalBits - prefixLength)
+}
+
+// HostCountFits reports whether the number of hosts of the IP network fits in an int, which is not the case of
+// IPv6 subnets of 63 host bits or more on 64-bit platforms.
+func HostCountFits(ipNet *net.IPNet) bool {
+	prefixLength, totalBits := ipNet.Mask.Size()
 
-	return totalHosts
+	return totalBits-prefixLength < strconv.IntSize-1
 }
@@ -1,8 +1,13 @@
 package network_test
 
 import (
+	"bytes"
+	"errors"
 	"math"
 	"net"
+	"reflect"
+	"sort"
+	"sync"
 	"testing"
 
 	"github.com/fadhilyori/subping/pkg/network"
@@ -99,6 +104,81 @@ func BenchmarkHostsIterator(b *testing.B) {
 	}
 }
 
+func BenchmarkHostsIteratorNext(b *testing.B) {
+	const cidr = "2001:db8::/100"
+
+	b.Run("Next", func(b *testing.B) {
+		iterator, err := network.NewSubnetHostsIteratorFromCIDRString(cidr)
+		if err != nil {
+			b.Fatalf("NewSubnetHostsIteratorFromCIDRString() error => %v", err)
+		}
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if iterator.Next() == nil {
+				b.Fatal("Next() ran out of hosts")
+			}
+		}
+	})
+
+	b.Run("NextInto", func(b *testing.B) {
+		iterator, err := network.NewSubnetHostsIteratorFromCIDRString(cidr)
+		if err != nil {
+			b.Fatalf("NewSubnetHostsIteratorFromCIDRString() error => %v", err)
+		}
+
+		var ip net.IP
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if ip = iterator.NextInto(ip); ip == nil {
+				b.Fatal("NextInto() ran out of hosts")
+			}
+		}
+	})
+}
+
+func TestNextInto(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidr    string
+		shuffle bool
+	}{
+		{name: "IPv4 Subnet 28", cidr: "10.0.0.0/28"},
+		{name: "IPv6 Subnet 124", cidr: "2001:db8::/124"},
+		{name: "Shuffled IPv4 Subnet 28", cidr: "10.0.0.0/28", shuffle: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want, got []string
+
+			iterator, err := network.NewSubnetHostsIteratorFromCIDRString(tt.cidr)
+			if err != nil {
+				t.Fatalf("NewSubnetHostsIteratorFromCIDRString() error => %v", err)
+			}
+
+			if tt.shuffle {
+				iterator.Shuffle(42)
+			}
+
+			for ip := iterator.Next(); ip != nil; ip = iterator.Next() {
+				want = append(want, ip.String())
+			}
+
+			iterator.Reset()
+
+			var buf net.IP
+			for buf = iterator.NextInto(buf); buf != nil; buf = iterator.NextInto(buf) {
+				got = append(got, buf.String())
+			}
+
+			if len(got) != 16 || !reflect.DeepEqual(got, want) {
+				t.Errorf("NextInto() yielded %v, want %v", got, want)
+			}
+		})
+	}
+}
+
 func TestCalculateTotalHostsFromCIDRString(t *testing.T) {
 	type args struct {
 		cidr string
@@ -125,6 +205,30 @@ func TestCalculateTotalHostsFromCIDRString(t *testing.T) {
 			want:    256,
 			wantErr: false,
 		},
+		{
+			name: "IPv6 /100",
+			args: args{
+				cidr: "2001:db8::/100",
+			},
+			want:    1 << 28,
+			wantErr: false,
+		},
+		{
+			name: "IPv6 /64 is clamped",
+			args: args{
+				cidr: "2001:db8::/64",
+			},
+			want:    math.MaxInt,
+			wantErr: true,
+		},
+		{
+			name: "Whole IPv6 space is clamped",
+			args: args{
+				cidr: "::/0",
+			},
+			want:    math.MaxInt,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -133,9 +237,604 @@ func TestCalculateTotalHostsFromCIDRString(t *testing.T) {
 				t.Errorf("CalculateTotalHostsFromCIDRString() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if err != nil && !errors.Is(err, network.ErrTooManyHosts) {
+				t.Errorf("CalculateTotalHostsFromCIDRString() error = %v, want ErrTooManyHosts", err)
+			}
 			if got != tt.want {
 				t.Errorf("CalculateTotalHostsFromCIDRString() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestSubnetHostsIteratorShuffle(t *testing.T) {
+	tests := []struct {
+		name string
+		cidr string
+		seed int64
+	}{
+		{
+			name: "IPv4 Subnet 24",
+			cidr: "10.0.0.0/24",
+			seed: 42,
+		},
+		{
+			name: "IPv6 Subnet 120",
+			cidr: "2001:db8:1::/120",
+			seed: 7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collect := func() []string {
+				iterator, err := network.NewSubnetHostsIteratorFromCIDRString(tt.cidr)
+				if err != nil {
+					t.Fatalf("NewSubnetHostsIteratorFromCIDRString() error => %v", err)
+				}
+				iterator.Shuffle(tt.seed)
+
+				var hosts []string
+				for ip := iterator.Next(); ip != nil; ip = iterator.Next() {
+					hosts = append(hosts, ip.String())
+				}
+
+				return hosts
+			}
+
+			first, second := collect(), collect()
+
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("Shuffle(%d) produced different orders for the same seed", tt.seed)
+			}
+
+			_, ipNet, _ := net.ParseCIDR(tt.cidr)
+			seen := make(map[string]bool)
+			for _, host := range first {
+				if !ipNet.Contains(net.ParseIP(host)) {
+					t.Errorf("Shuffle() host %s is not in the subnet %s", host, ipNet.String())
+				}
+				seen[host] = true
+			}
+
+			if len(seen) != network.CalculateTotalHosts(ipNet) {
+				t.Errorf("Shuffle() yielded %d unique hosts, want %d", len(seen), network.CalculateTotalHosts(ipNet))
+			}
+		})
+	}
+}
+
+func TestCommonHosts(t *testing.T) {
+	tests := []struct {
+		name string
+		cidr string
+		want []string
+	}{
+		{
+			name: "IPv4 Subnet 24",
+			cidr: "192.168.1.0/24",
+			want: []string{
+				"192.168.1.1", "192.168.1.2", "192.168.1.3", "192.168.1.4", "192.168.1.5",
+				"192.168.1.10", "192.168.1.100", "192.168.1.200",
+				"192.168.1.250", "192.168.1.251", "192.168.1.252", "192.168.1.253", "192.168.1.254",
+			},
+		},
+		{
+			name: "IPv4 Subnet 27",
+			cidr: "10.0.0.32/27",
+			want: []string{
+				"10.0.0.33", "10.0.0.34", "10.0.0.35", "10.0.0.36", "10.0.0.37",
+				"10.0.0.42",
+				"10.0.0.58", "10.0.0.59", "10.0.0.60", "10.0.0.61", "10.0.0.62",
+			},
+		},
+		{
+			name: "Small subnet is returned in full",
+			cidr: "10.0.0.0/30",
+			want: []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iterator, err := network.NewSubnetHostsIteratorFromCIDRString(tt.cidr)
+			if err != nil {
+				t.Fatalf("NewSubnetHostsIteratorFromCIDRString() error => %v", err)
+			}
+
+			var got []string
+			for _, ip := range iterator.CommonHosts() {
+				got = append(got, ip.String())
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CommonHosts() = %v, want %v", got, tt.want)
+			}
+
+			iterator.RestrictToCommonHosts()
+
+			if yielded := collectHosts(iterator); !reflect.DeepEqual(yielded, tt.want) {
+				t.Errorf("Next() after RestrictToCommonHosts() yielded %v, want %v", yielded, tt.want)
+			}
+
+			// The common hosts are shuffled, whether the iterator is shuffled before or after being restricted.
+			iterator.Shuffle(42)
+			shuffled := collectHosts(iterator)
+
+			other, _ := network.NewSubnetHostsIteratorFromCIDRString(tt.cidr)
+			other.Shuffle(42)
+			other.RestrictToCommonHosts()
+
+			if got := collectHosts(other); !reflect.DeepEqual(got, shuffled) {
+				t.Errorf("Shuffle() before RestrictToCommonHosts() yielded %v, want %v", got, shuffled)
+			}
+
+			if reflect.DeepEqual(shuffled, tt.want) {
+				t.Errorf("Next() after Shuffle() yielded the common hosts in ascending order %v", shuffled)
+			}
+
+			sort.Slice(shuffled, func(i, j int) bool {
+				return bytes.Compare(net.ParseIP(shuffled[i]).To16(), net.ParseIP(shuffled[j]).To16()) < 0
+			})
+			if !reflect.DeepEqual(shuffled, tt.want) {
+				t.Errorf("Next() after Shuffle() yielded the hosts %v, want the common hosts %v", shuffled, tt.want)
+			}
+		})
+	}
+}
+
+// collectHosts returns the hosts yielded by it.
+func collectHosts(it *network.SubnetHostsIterator) []string {
+	var hosts []string
+	for ip := it.Next(); ip != nil; ip = it.Next() {
+		hosts = append(hosts, ip.String())
+	}
+
+	return hosts
+}
+
+func TestSubnetHostsIteratorUsable(t *testing.T) {
+	tests := []struct {
+		name       string
+		cidr       string
+		wantTotal  int
+		wantFirst  string
+		wantLast   string
+		wantCommon []string
+	}{
+		{
+			name:      "IPv4 Subnet 24",
+			cidr:      "192.168.1.0/24",
+			wantTotal: 254,
+			wantFirst: "192.168.1.1",
+			wantLast:  "192.168.1.254",
+			wantCommon: []string{
+				"192.168.1.1", "192.168.1.2", "192.168.1.3", "192.168.1.4", "192.168.1.5",
+				"192.168.1.10", "192.168.1.100", "192.168.1.200",
+				"192.168.1.250", "192.168.1.251", "192.168.1.252", "192.168.1.253", "192.168.1.254",
+			},
+		},
+		{
+			name:       "IPv4 Subnet 30",
+			cidr:       "10.0.0.4/30",
+			wantTotal:  2,
+			wantFirst:  "10.0.0.5",
+			wantLast:   "10.0.0.6",
+			wantCommon: []string{"10.0.0.5", "10.0.0.6"},
+		},
+		{
+			name:       "IPv4 Subnet 31 is left untouched",
+			cidr:       "10.0.0.4/31",
+			wantTotal:  2,
+			wantFirst:  "10.0.0.4",
+			wantLast:   "10.0.0.5",
+			wantCommon: []string{"10.0.0.4", "10.0.0.5"},
+		},
+		{
+			name:       "IPv4 Subnet 32 is left untouched",
+			cidr:       "10.0.0.4/32",
+			wantTotal:  1,
+			wantFirst:  "10.0.0.4",
+			wantLast:   "10.0.0.4",
+			wantCommon: []string{"10.0.0.4"},
+		},
+		{
+			name:       "IPv6 Subnet 126 is left untouched",
+			cidr:       "2001:db8::/126",
+			wantTotal:  4,
+			wantFirst:  "2001:db8::",
+			wantLast:   "2001:db8::3",
+			wantCommon: []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ipNet, err := net.ParseCIDR(tt.cidr)
+			if err != nil {
+				t.Fatalf("ParseCIDR() error => %v", err)
+			}
+
+			iterator := network.NewSubnetHostsIteratorUsable(ipNet)
+			if iterator.TotalHosts != tt.wantTotal {
+				t.Errorf("TotalHosts = %d, want %d", iterator.TotalHosts, tt.wantTotal)
+			}
+
+			var yielded []string
+			for ip := iterator.Next(); ip != nil; ip = iterator.Next() {
+				yielded = append(yielded, ip.String())
+			}
+
+			if len(yielded) != tt.wantTotal {
+				t.Fatalf("Next() yielded %d hosts, want %d", len(yielded), tt.wantTotal)
+			}
+
+			if yielded[0] != tt.wantFirst || yielded[len(yielded)-1] != tt.wantLast {
+				t.Errorf("Next() yielded %s to %s, want %s to %s",
+					yielded[0], yielded[len(yielded)-1], tt.wantFirst, tt.wantLast)
+			}
+
+			iterator.Shuffle(42)
+
+			shuffled := map[string]bool{}
+			for ip := iterator.Next(); ip != nil; ip = iterator.Next() {
+				shuffled[ip.String()] = true
+			}
+
+			for _, ip := range yielded {
+				if !shuffled[ip] {
+					t.Errorf("Next() after Shuffle() did not yield %s", ip)
+				}
+			}
+
+			if len(shuffled) != tt.wantTotal {
+				t.Errorf("Next() after Shuffle() yielded %d hosts, want %d", len(shuffled), tt.wantTotal)
+			}
+
+			var common []string
+			for _, ip := range iterator.CommonHosts() {
+				common = append(common, ip.String())
+			}
+
+			if !reflect.DeepEqual(common, tt.wantCommon) {
+				t.Errorf("CommonHosts() = %v, want %v", common, tt.wantCommon)
+			}
+		})
+	}
+}
+
+func TestSubnetHostsIteratorReset(t *testing.T) {
+	tests := []struct {
+		name    string
+		shuffle bool
+	}{
+		{name: "Sequential"},
+		{name: "Shuffled", shuffle: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iterator, err := network.NewSubnetHostsIteratorFromCIDRString("10.0.0.0/28")
+			if err != nil {
+				t.Fatalf("NewSubnetHostsIteratorFromCIDRString() error => %v", err)
+			}
+
+			if tt.shuffle {
+				iterator.Shuffle(42)
+			}
+
+			var first []string
+			for ip := iterator.Next(); ip != nil; ip = iterator.Next() {
+				first = append(first, ip.String())
+			}
+
+			iterator.Reset()
+
+			var second []string
+			for ip := iterator.Next(); ip != nil; ip = iterator.Next() {
+				second = append(second, ip.String())
+			}
+
+			if len(first) != 16 || !reflect.DeepEqual(first, second) {
+				t.Errorf("Next() after Reset() yielded %v, want %v", second, first)
+			}
+		})
+	}
+}
+
+func TestEnumerateHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidr    string
+		want    []string
+		wantLen int
+		wantErr error
+	}{
+		{
+			name: "IPv4 subnet",
+			cidr: "192.168.1.0/30",
+			want: []string{"192.168.1.0", "192.168.1.1", "192.168.1.2", "192.168.1.3"},
+		},
+		{
+			name: "IPv6 subnet",
+			cidr: "2001:db8::fe/127",
+			want: []string{"2001:db8::fe", "2001:db8::ff"},
+		},
+		{
+			name:    "Largest subnet",
+			cidr:    "10.0.0.0/16",
+			wantLen: network.MaxEnumeratedHosts,
+		},
+		{
+			name:    "Subnet too large",
+			cidr:    "10.0.0.0/15",
+			wantErr: network.ErrTooManyToEnumerate,
+		},
+		{
+			name:    "Uncountable subnet",
+			cidr:    "2001:db8::/64",
+			wantErr: network.ErrTooManyToEnumerate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := network.EnumerateHosts(tt.cidr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EnumerateHosts() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EnumerateHosts() = %v, want %v", got, tt.want)
+			}
+
+			if tt.wantLen != 0 && len(got) != tt.wantLen {
+				t.Errorf("EnumerateHosts() returned %d hosts, want %d", len(got), tt.wantLen)
+			}
+		})
+	}
+
+	if _, err := network.EnumerateHosts("10.0.0.0/33"); err == nil {
+		t.Error("EnumerateHosts() of an invalid subnet did not fail")
+	}
+}
+
+func TestSubnetHostsIteratorAll(t *testing.T) {
+	iterator, err := network.NewSubnetHostsIteratorFromCIDRString("10.0.0.0/28")
+	if err != nil {
+		t.Fatalf("NewSubnetHostsIteratorFromCIDRString() error => %v", err)
+	}
+
+	iterator.Shuffle(42)
+
+	// All does not consume the iteration.
+	first := iterator.Next()
+
+	all, err := iterator.All()
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+
+	want := []string{first.String()}
+	for ip := iterator.Next(); ip != nil; ip = iterator.Next() {
+		want = append(want, ip.String())
+	}
+
+	var got []string
+	for _, ip := range all {
+		got = append(got, ip.String())
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %v, want the shuffled order %v", got, want)
+	}
+}
+
+func TestMultiSubnetHostsIterator(t *testing.T) {
+	first, err := network.NewSubnetHostsIteratorFromCIDRString("10.0.0.0/31")
+	if err != nil {
+		t.Fatalf("NewSubnetHostsIteratorFromCIDRString() error => %v", err)
+	}
+
+	second, err := network.NewSubnetHostsIteratorFromCIDRString("192.168.1.4/30")
+	if err != nil {
+		t.Fatalf("NewSubnetHostsIteratorFromCIDRString() error => %v", err)
+	}
+
+	it := network.NewMultiSubnetHostsIterator(first, second)
+	if it.TotalHosts != 6 {
+		t.Errorf("TotalHosts = %d, want 6", it.TotalHosts)
+	}
+
+	want := [][2]string{
+		{"10.0.0.0", "10.0.0.0/31"},
+		{"10.0.0.1", "10.0.0.0/31"},
+		{"192.168.1.4", "192.168.1.4/30"},
+		{"192.168.1.5", "192.168.1.4/30"},
+		{"192.168.1.6", "192.168.1.4/30"},
+		{"192.168.1.7", "192.168.1.4/30"},
+	}
+
+	var got [][2]string
+	for ip, origin := it.Next(); ip != nil; ip, origin = it.Next() {
+		got = append(got, [2]string{ip.String(), origin.String()})
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Next() yielded %v, want %v", got, want)
+	}
+}
+
+func TestSubnetHostsIteratorConcurrentNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidr    string
+		shuffle bool
+	}{
+		{
+			name: "IPv4 sequential",
+			cidr: "10.0.0.0/22",
+		},
+		{
+			name:    "IPv4 shuffled",
+			cidr:    "10.0.0.0/22",
+			shuffle: true,
+		},
+		{
+			name: "IPv6 sequential",
+			cidr: "2001:db8:1::/118",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it, err := network.NewSubnetHostsIteratorFromCIDRString(tt.cidr)
+			if err != nil {
+				t.Fatalf("NewSubnetHostsIteratorFromCIDRString() error => %v", err)
+			}
+			if tt.shuffle {
+				it.Shuffle(1)
+			}
+
+			const producers = 8
+
+			// Every producer keeps the pointers it was handed and only reads them once all are done, so an IP
+			// modified by a later call to Next shows up as a duplicate or lost address.
+			yielded := make([][]*net.IP, producers)
+
+			var wg sync.WaitGroup
+			for p := 0; p < producers; p++ {
+				wg.Add(1)
+				go func(p int) {
+					defer wg.Done()
+					for ip := it.Next(); ip != nil; ip = it.Next() {
+						yielded[p] = append(yielded[p], ip)
+					}
+				}(p)
+			}
+			wg.Wait()
+
+			seen := make(map[string]bool, it.TotalHosts)
+			for _, ips := range yielded {
+				for _, ip := range ips {
+					if seen[ip.String()] {
+						t.Errorf("Next() yielded %s more than once", ip)
+					}
+					seen[ip.String()] = true
+				}
+			}
+
+			if len(seen) != it.TotalHosts {
+				t.Errorf("Next() yielded %d distinct hosts, want %d", len(seen), it.TotalHosts)
+			}
+		})
+	}
+}
+
+func TestNormalizeIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		ip      string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "IPv6 compressed",
+			ip:   "2001:db8::1",
+			want: "2001:db8::1",
+		},
+		{
+			name: "IPv6 expanded",
+			ip:   "2001:0db8:0000:0000:0000:0000:0000:0001",
+			want: "2001:db8::1",
+		},
+		{
+			name: "IPv6 partially compressed upper case",
+			ip:   "2001:0DB8:0000::0001",
+			want: "2001:db8::1",
+		},
+		{
+			name: "IPv4",
+			ip:   "192.168.0.1",
+			want: "192.168.0.1",
+		},
+		{
+			name:    "Invalid",
+			ip:      "2001:db8::g",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := network.NormalizeIP(tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NormalizeIP() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeIP() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkAndBroadcastAddress(t *testing.T) {
+	tests := []struct {
+		name          string
+		cidr          string
+		wantNetwork   string
+		wantBroadcast string
+		wantErr       bool
+	}{
+		{
+			name:          "IPv4 /24",
+			cidr:          "192.168.1.0/24",
+			wantNetwork:   "192.168.1.0",
+			wantBroadcast: "192.168.1.255",
+		},
+		{
+			name:          "IPv4 /30",
+			cidr:          "10.0.0.4/30",
+			wantNetwork:   "10.0.0.4",
+			wantBroadcast: "10.0.0.7",
+		},
+		{
+			name:        "IPv4 /31 has no broadcast address",
+			cidr:        "10.0.0.6/31",
+			wantNetwork: "10.0.0.6",
+			wantErr:     true,
+		},
+		{
+			name:        "IPv6 has no broadcast address",
+			cidr:        "2001:db8::/64",
+			wantNetwork: "2001:db8::",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ipNet, err := net.ParseCIDR(tt.cidr)
+			if err != nil {
+				t.Fatalf("ParseCIDR() error = %v", err)
+			}
+
+			if got := network.NetworkAddress(ipNet); got.String() != tt.wantNetwork {
+				t.Errorf("NetworkAddress() = %v, want %v", got, tt.wantNetwork)
+			}
+
+			got, err := network.BroadcastAddress(ipNet)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BroadcastAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.wantBroadcast {
+				t.Errorf("BroadcastAddress() = %v, want %v", got, tt.wantBroadcast)
+			}
+		})
+	}
+}
+
+func TestNetworkAndBroadcastAddressUnmaskedIPv4(t *testing.T) {
+	ipNet := &net.IPNet{IP: net.IPv4(192, 168, 1, 77), Mask: net.CIDRMask(24, 32)}
+
+	if got := network.NetworkAddress(ipNet); !got.Equal(net.IPv4(192, 168, 1, 0)) {
+		t.Errorf("NetworkAddress() = %v, want 192.168.1.0", got)
+	}
+
+	if got, err := network.BroadcastAddress(ipNet); err != nil || !got.Equal(net.IPv4(192, 168, 1, 255)) {
+		t.Errorf("BroadcastAddress() = %v, %v, want 192.168.1.255", got, err)
+	}
+}
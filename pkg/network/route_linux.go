@@ -0,0 +1,152 @@
+//go:build linux
+
+package network
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+const (
+	// routeTablePath is the IPv4 routing table exposed by the Linux kernel.
+	routeTablePath = "/proc/net/route"
+
+	// arpTablePath is the IPv4 ARP table exposed by the Linux kernel.
+	arpTablePath = "/proc/net/arp"
+
+	// rtfUp and rtfGateway are the RTF_UP and RTF_GATEWAY flags of a route: the route is usable, and its destination
+	// is reached through a gateway.
+	rtfUp      = 0x1
+	rtfGateway = 0x2
+)
+
+// nativeEndian is the byte order of the host, in which the kernel writes the addresses of the routing table.
+var nativeEndian = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+
+	return binary.BigEndian
+}()
+
+// ErrNoDefaultRoute is returned when the routing table has no default route.
+var ErrNoDefaultRoute = errors.New("no default route")
+
+// DefaultGateway returns the interface and the IP address of the IPv4 default gateway, read from the
+// kernel routing table, along with the MAC address of the gateway when it is in the ARP table.
+func DefaultGateway() (iface string, gateway net.IP, mac net.HardwareAddr, err error) {
+	routes, err := os.Open(routeTablePath)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read the routing table: %w", err)
+	}
+	defer routes.Close()
+
+	iface, gateway, err = ParseDefaultRoute(routes)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	arp, err := os.Open(arpTablePath)
+	if err != nil {
+		return iface, gateway, nil, fmt.Errorf("failed to read the ARP table: %w", err)
+	}
+	defer arp.Close()
+
+	neighbors, err := ParseARPTable(arp)
+	if err != nil {
+		return iface, gateway, nil, err
+	}
+
+	return iface, gateway, neighbors[gateway.String()], nil
+}
+
+// ParseDefaultRoute parses a routing table in the /proc/net/route format and returns
+// the interface and the gateway of the default route: the usable route to 0.0.0.0/0 through a gateway.
+// Routes such as 0.0.0.0/1, whose destination is also 0.0.0.0, and default routes without a gateway are skipped.
+func ParseDefaultRoute(r io.Reader) (iface string, gateway net.IP, err error) {
+	scanner := bufio.NewScanner(r)
+
+	// Skip the header line.
+	scanner.Scan()
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(fields[3], 16, 16)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid flags %q in the routing table", fields[3])
+		}
+
+		if flags&(rtfUp|rtfGateway) != rtfUp|rtfGateway {
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != net.IPv4len {
+			return "", nil, fmt.Errorf("invalid gateway %q in the routing table", fields[2])
+		}
+
+		// The kernel writes the address as an integer in the byte order of the host.
+		gateway = make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(gateway, nativeEndian.Uint32(raw))
+
+		return fields[0], gateway, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+
+	return "", nil, ErrNoDefaultRoute
+}
+
+// ParseARPTable parses an ARP table in the /proc/net/arp format and returns the MAC addresses by IP address.
+// Incomplete entries are skipped.
+func ParseARPTable(r io.Reader) (map[string]net.HardwareAddr, error) {
+	scanner := bufio.NewScanner(r)
+	neighbors := make(map[string]net.HardwareAddr)
+
+	// Skip the header line.
+	scanner.Scan()
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		mac, err := net.ParseMAC(fields[3])
+		if ip == nil || err != nil || isZeroMAC(mac) {
+			continue
+		}
+
+		neighbors[ip.String()] = mac
+	}
+
+	return neighbors, scanner.Err()
+}
+
+// isZeroMAC reports whether mac is the all-zero address of an incomplete ARP entry.
+func isZeroMAC(mac net.HardwareAddr) bool {
+	for _, b := range mac {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,61 @@
+//go:build linux
+
+package network_test
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fadhilyori/subping/pkg/network"
+)
+
+// stubRouteTable holds little-endian addresses, as written by the kernel of an amd64 or arm64 host. The tun0 route to
+// 0.0.0.0/1 and the ppp0 default route without a gateway precede the default route of wlan0.
+const stubRouteTable = `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	000200C0	00000000	0001	0	0	0	00FFFFFF	0	0	0
+tun0	00000000	0100080A	0003	0	0	0	00000080	0	0	0
+ppp0	00000000	00000000	0001	0	0	0	00000000	0	0	0
+wlan0	00000000	0101A8C0	0003	0	0	600	00000000	0	0	0
+`
+
+const stubARPTable = `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         a4:2b:b0:12:34:56     *        wlan0
+192.168.1.7      0x1         0x0         00:00:00:00:00:00     *        wlan0
+`
+
+func TestParseDefaultRoute(t *testing.T) {
+	iface, gateway, err := network.ParseDefaultRoute(strings.NewReader(stubRouteTable))
+	if err != nil {
+		t.Fatalf("ParseDefaultRoute() error = %v", err)
+	}
+
+	if iface != "wlan0" {
+		t.Errorf("ParseDefaultRoute() iface = %q, want %q", iface, "wlan0")
+	}
+
+	if !gateway.Equal(net.ParseIP("192.168.1.1")) {
+		t.Errorf("ParseDefaultRoute() gateway = %v, want 192.168.1.1", gateway)
+	}
+
+	noDefault := strings.Join(strings.Split(stubRouteTable, "\n")[:4], "\n")
+	if _, _, err := network.ParseDefaultRoute(strings.NewReader(noDefault)); !errors.Is(err, network.ErrNoDefaultRoute) {
+		t.Errorf("ParseDefaultRoute() without default route error = %v, want %v", err, network.ErrNoDefaultRoute)
+	}
+}
+
+func TestParseARPTable(t *testing.T) {
+	got, err := network.ParseARPTable(strings.NewReader(stubARPTable))
+	if err != nil {
+		t.Fatalf("ParseARPTable() error = %v", err)
+	}
+
+	mac, _ := net.ParseMAC("a4:2b:b0:12:34:56")
+	want := map[string]net.HardwareAddr{"192.168.1.1": mac}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseARPTable() = %v, want %v", got, want)
+	}
+}
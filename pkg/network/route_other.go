@@ -0,0 +1,14 @@
+//go:build !linux
+
+package network
+
+import (
+	"errors"
+	"net"
+)
+
+// DefaultGateway returns the interface and the IP address of the IPv4 default gateway, along with
+// its MAC address. It is only supported on Linux.
+func DefaultGateway() (iface string, gateway net.IP, mac net.HardwareAddr, err error) {
+	return "", nil, nil, errors.New("default gateway lookup is only supported on Linux")
+}
@@ -0,0 +1,318 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// TargetKind is the kind of a target spec.
+type TargetKind string
+
+const (
+	// TargetCIDR is a subnet in CIDR notation, e.g. "10.0.0.0/24".
+	TargetCIDR TargetKind = "cidr"
+
+	// TargetRange is an inclusive range of IP addresses, e.g. "10.0.0.1-10.0.0.20" or its IPv4 shorthand
+	// "10.0.0.1-20", or a number of consecutive addresses from a start address, e.g. "10.0.0.0+500".
+	TargetRange TargetKind = "range"
+
+	// TargetIP is a single IP address, e.g. "10.0.0.1" or "2001:db8::1".
+	TargetIP TargetKind = "ip"
+
+	// TargetHostname is a host name, e.g. "db.example.com".
+	TargetHostname TargetKind = "hostname"
+
+	// TargetWildcard is an IPv4 address whose trailing octets are wildcards, e.g. "10.0.*.*".
+	TargetWildcard TargetKind = "wildcard"
+)
+
+// TargetSpec describes a validated target spec.
+type TargetSpec struct {
+	// Kind is the kind of the target.
+	Kind TargetKind
+
+	// Value is the canonical form of the target: CIDRs are masked to their network address, IP addresses are
+	// written as by net.IP.String, ranges are written in full and host names are lower-cased without trailing dot.
+	Value string
+
+	// IPNet is the subnet holding exactly the addresses of the target, for CIDRs, IP addresses and wildcards.
+	// It is nil for ranges and host names.
+	IPNet *net.IPNet
+
+	// First is the first IP address of the target. It is nil for host names.
+	First net.IP
+
+	// Last is the last IP address of the target. It is nil for host names.
+	Last net.IP
+}
+
+// ParseTargetSpec validates a user-provided target and returns its kind and canonical form.
+// The supported forms are CIDRs, ranges, single IP addresses, host names and IPv4 wildcards,
+// see the TargetKind constants.
+func ParseTargetSpec(s string) (TargetSpec, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return TargetSpec{}, errors.New("empty target")
+	}
+
+	switch {
+	case strings.Contains(s, "/"):
+		return parseCIDRSpec(s)
+	case strings.Contains(s, "*"):
+		return parseWildcardSpec(s)
+	case strings.Contains(s, "+"):
+		return parseCountSpec(s)
+	}
+
+	if ip := net.ParseIP(s); ip != nil {
+		return ipSpec(ip), nil
+	}
+
+	if i := strings.Index(s, "-"); i > 0 {
+		if first := net.ParseIP(s[:i]); first != nil {
+			return parseRangeSpec(s, first, s[i+1:])
+		}
+	}
+
+	return parseHostnameSpec(s)
+}
+
+// parseCIDRSpec parses a target in CIDR notation.
+func parseCIDRSpec(s string) (TargetSpec, error) {
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return TargetSpec{}, fmt.Errorf("invalid CIDR %q", s)
+	}
+
+	return subnetSpec(TargetCIDR, ipNet.String(), ipNet), nil
+}
+
+// parseWildcardSpec parses an IPv4 address whose trailing octets are "*".
+func parseWildcardSpec(s string) (TargetSpec, error) {
+	octets := strings.Split(s, ".")
+	if len(octets) != net.IPv4len {
+		return TargetSpec{}, fmt.Errorf("invalid wildcard %q: it must have four octets", s)
+	}
+
+	ip := make(net.IP, net.IPv4len)
+	fixed := 0
+
+	for i, octet := range octets {
+		if octet == "*" {
+			continue
+		}
+
+		if fixed != i {
+			return TargetSpec{}, fmt.Errorf("invalid wildcard %q: only the trailing octets can be wildcards", s)
+		}
+
+		value, err := strconv.ParseUint(octet, 10, 8)
+		if err != nil {
+			return TargetSpec{}, fmt.Errorf("invalid wildcard %q: invalid octet %q", s, octet)
+		}
+
+		ip[i] = byte(value)
+		fixed++
+	}
+
+	value := make([]string, 0, net.IPv4len)
+	for i := range octets {
+		if i < fixed {
+			value = append(value, strconv.Itoa(int(ip[i])))
+		} else {
+			value = append(value, "*")
+		}
+	}
+
+	ipNet := &net.IPNet{IP: ip, Mask: net.CIDRMask(8*fixed, 8*net.IPv4len)}
+
+	return subnetSpec(TargetWildcard, strings.Join(value, "."), ipNet), nil
+}
+
+// parseRangeSpec parses the range starting at first and ending at last, either a full IP address or,
+// for IPv4, the last octet of the end of the range.
+func parseRangeSpec(s string, first net.IP, last string) (TargetSpec, error) {
+	first = canonicalIP(first)
+
+	end := net.ParseIP(last)
+	if end == nil && len(first) == net.IPv4len {
+		if octet, err := strconv.ParseUint(last, 10, 8); err == nil {
+			end = make(net.IP, net.IPv4len)
+			copy(end, first)
+			end[net.IPv4len-1] = byte(octet)
+		}
+	}
+
+	if end == nil {
+		return TargetSpec{}, fmt.Errorf("invalid range %q: invalid end %q", s, last)
+	}
+
+	end = canonicalIP(end)
+	if len(end) != len(first) {
+		return TargetSpec{}, fmt.Errorf("invalid range %q: mixed IPv4 and IPv6 addresses", s)
+	}
+
+	if bytes.Compare(first, end) > 0 {
+		return TargetSpec{}, fmt.Errorf("invalid range %q: the end precedes the start", s)
+	}
+
+	return TargetSpec{
+		Kind:  TargetRange,
+		Value: first.String() + "-" + end.String(),
+		First: first,
+		Last:  end,
+	}, nil
+}
+
+// parseCountSpec parses a range given as a start address followed by "+" and the number of addresses.
+func parseCountSpec(s string) (TargetSpec, error) {
+	start, count, _ := strings.Cut(s, "+")
+
+	first := net.ParseIP(start)
+	if first == nil {
+		return TargetSpec{}, fmt.Errorf("invalid range %q: invalid start %q", s, start)
+	}
+
+	n, ok := new(big.Int).SetString(count, 10)
+	if !ok || n.Sign() <= 0 || strings.Trim(count, "0123456789") != "" {
+		return TargetSpec{}, fmt.Errorf("invalid range %q: the number of addresses must be a positive integer", s)
+	}
+
+	first = canonicalIP(first)
+
+	last := new(big.Int).SetBytes(first)
+	last.Add(last, n).Sub(last, big.NewInt(1))
+	if last.BitLen() > 8*len(first) {
+		return TargetSpec{}, fmt.Errorf("invalid range %q: it overflows the address space", s)
+	}
+
+	end := net.IP(last.FillBytes(make([]byte, len(first))))
+
+	return TargetSpec{
+		Kind:  TargetRange,
+		Value: first.String() + "-" + end.String(),
+		First: first,
+		Last:  end,
+	}, nil
+}
+
+// IPNets returns the smallest list of subnets holding exactly the addresses of the target, see RangeToIPNets.
+// It returns nil for host names.
+func (t TargetSpec) IPNets() []*net.IPNet {
+	switch {
+	case t.IPNet != nil:
+		return []*net.IPNet{t.IPNet}
+	case t.First != nil:
+		return RangeToIPNets(t.First, t.Last)
+	default:
+		return nil
+	}
+}
+
+// RangeToIPNets returns the smallest list of subnets, in ascending order, holding exactly the addresses from first
+// to last inclusive, e.g. 10.0.0.0/24 and 10.0.1.0/25 for 10.0.0.0-10.0.1.127. Both addresses must be of the same
+// family. It returns nil when last precedes first.
+func RangeToIPNets(first, last net.IP) []*net.IPNet {
+	first, last = canonicalIP(first), canonicalIP(last)
+	bits := 8 * len(first)
+
+	start := new(big.Int).SetBytes(first)
+	end := new(big.Int).SetBytes(last)
+
+	var ipNets []*net.IPNet
+	for start.Cmp(end) <= 0 {
+		// The largest block aligned on start that does not go past end.
+		size := int(start.TrailingZeroBits())
+		if start.Sign() == 0 {
+			size = bits
+		}
+
+		for size > 0 {
+			blockEnd := new(big.Int).Lsh(big.NewInt(1), uint(size))
+			if blockEnd.Add(blockEnd, start).Sub(blockEnd, big.NewInt(1)).Cmp(end) <= 0 {
+				break
+			}
+			size--
+		}
+
+		ipNets = append(ipNets, &net.IPNet{
+			IP:   start.FillBytes(make([]byte, len(first))),
+			Mask: net.CIDRMask(bits-size, bits),
+		})
+
+		start.Add(start, new(big.Int).Lsh(big.NewInt(1), uint(size)))
+	}
+
+	return ipNets
+}
+
+// parseHostnameSpec parses a host name, following RFC 1123.
+func parseHostnameSpec(s string) (TargetSpec, error) {
+	name := strings.ToLower(strings.TrimSuffix(s, "."))
+	if len(name) == 0 || len(name) > 253 {
+		return TargetSpec{}, fmt.Errorf("invalid target %q", s)
+	}
+
+	labels := strings.Split(name, ".")
+	for _, label := range labels {
+		if !validHostnameLabel(label) {
+			return TargetSpec{}, fmt.Errorf("invalid target %q", s)
+		}
+	}
+
+	// A name made of digits only, such as "10.0.0.256", is a mistyped IP address rather than a host name.
+	if _, err := strconv.Atoi(labels[len(labels)-1]); err == nil {
+		return TargetSpec{}, fmt.Errorf("invalid IP address %q", s)
+	}
+
+	return TargetSpec{Kind: TargetHostname, Value: name}, nil
+}
+
+// validHostnameLabel reports whether label is a valid host name label: 1 to 63 letters, digits and hyphens,
+// neither starting nor ending with a hyphen.
+func validHostnameLabel(label string) bool {
+	if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+
+	for _, c := range label {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ipSpec returns the spec of the single IP address ip.
+func ipSpec(ip net.IP) TargetSpec {
+	ip = canonicalIP(ip)
+	ipNet := &net.IPNet{IP: ip, Mask: net.CIDRMask(8*len(ip), 8*len(ip))}
+
+	return subnetSpec(TargetIP, ip.String(), ipNet)
+}
+
+// subnetSpec returns the spec of the given kind and canonical value covering ipNet.
+func subnetSpec(kind TargetKind, value string, ipNet *net.IPNet) TargetSpec {
+	return TargetSpec{
+		Kind:  kind,
+		Value: value,
+		IPNet: ipNet,
+		First: GetFirstIPAddressFromIPNet(ipNet),
+		Last:  GetLastIPAddressFromIPNet(ipNet),
+	}
+}
+
+// canonicalIP returns ip in its 4-byte form when it is an IPv4 address.
+func canonicalIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+
+	return ip
+}
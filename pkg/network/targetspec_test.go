@@ -0,0 +1,409 @@
+package network_test
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/fadhilyori/subping/pkg/network"
+)
+
+func TestParseTargetSpec(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		wantKind  network.TargetKind
+		wantValue string
+		wantIPNet string
+		wantFirst string
+		wantLast  string
+		wantErr   bool
+	}{
+		{
+			name:      "IPv4 CIDR",
+			spec:      "10.0.0.0/24",
+			wantKind:  network.TargetCIDR,
+			wantValue: "10.0.0.0/24",
+			wantIPNet: "10.0.0.0/24",
+			wantFirst: "10.0.0.0",
+			wantLast:  "10.0.0.255",
+		},
+		{
+			name:      "CIDR with host bits is masked",
+			spec:      " 10.0.0.77/30 ",
+			wantKind:  network.TargetCIDR,
+			wantValue: "10.0.0.76/30",
+			wantIPNet: "10.0.0.76/30",
+			wantFirst: "10.0.0.76",
+			wantLast:  "10.0.0.79",
+		},
+		{
+			name:      "IPv6 CIDR",
+			spec:      "2001:DB8:0::/126",
+			wantKind:  network.TargetCIDR,
+			wantValue: "2001:db8::/126",
+			wantIPNet: "2001:db8::/126",
+			wantFirst: "2001:db8::",
+			wantLast:  "2001:db8::3",
+		},
+		{
+			name:    "Invalid CIDR",
+			spec:    "10.0.0.0/33",
+			wantErr: true,
+		},
+		{
+			name:      "IPv4 address",
+			spec:      "10.0.0.1",
+			wantKind:  network.TargetIP,
+			wantValue: "10.0.0.1",
+			wantIPNet: "10.0.0.1/32",
+			wantFirst: "10.0.0.1",
+			wantLast:  "10.0.0.1",
+		},
+		{
+			name:      "IPv6 address",
+			spec:      "2001:0DB8::0001",
+			wantKind:  network.TargetIP,
+			wantValue: "2001:db8::1",
+			wantIPNet: "2001:db8::1/128",
+			wantFirst: "2001:db8::1",
+			wantLast:  "2001:db8::1",
+		},
+		{
+			name:      "IPv4 range",
+			spec:      "10.0.0.1-10.0.1.5",
+			wantKind:  network.TargetRange,
+			wantValue: "10.0.0.1-10.0.1.5",
+			wantFirst: "10.0.0.1",
+			wantLast:  "10.0.1.5",
+		},
+		{
+			name:      "IPv4 range shorthand",
+			spec:      "10.0.0.1-20",
+			wantKind:  network.TargetRange,
+			wantValue: "10.0.0.1-10.0.0.20",
+			wantFirst: "10.0.0.1",
+			wantLast:  "10.0.0.20",
+		},
+		{
+			name:      "IPv6 range",
+			spec:      "2001:db8::1-2001:db8::ff",
+			wantKind:  network.TargetRange,
+			wantValue: "2001:db8::1-2001:db8::ff",
+			wantFirst: "2001:db8::1",
+			wantLast:  "2001:db8::ff",
+		},
+		{
+			name:    "Reversed range",
+			spec:    "10.0.0.20-10.0.0.1",
+			wantErr: true,
+		},
+		{
+			name:    "Range mixing IPv4 and IPv6",
+			spec:    "10.0.0.1-2001:db8::1",
+			wantErr: true,
+		},
+		{
+			name:    "Range with an invalid end",
+			spec:    "10.0.0.1-300",
+			wantErr: true,
+		},
+		{
+			name:      "Range given as a number of addresses",
+			spec:      "10.0.0.0+500",
+			wantKind:  network.TargetRange,
+			wantValue: "10.0.0.0-10.0.1.243",
+			wantFirst: "10.0.0.0",
+			wantLast:  "10.0.1.243",
+		},
+		{
+			name:      "Single address given as a number of addresses",
+			spec:      "2001:db8::1+1",
+			wantKind:  network.TargetRange,
+			wantValue: "2001:db8::1-2001:db8::1",
+			wantFirst: "2001:db8::1",
+			wantLast:  "2001:db8::1",
+		},
+		{
+			name:    "Zero addresses",
+			spec:    "10.0.0.0+0",
+			wantErr: true,
+		},
+		{
+			name:    "Negative number of addresses",
+			spec:    "10.0.0.0+-5",
+			wantErr: true,
+		},
+		{
+			name:    "Number of addresses overflowing the address space",
+			spec:    "255.255.255.0+257",
+			wantErr: true,
+		},
+		{
+			name:      "Number of addresses up to the end of the address space",
+			spec:      "255.255.255.0+256",
+			wantKind:  network.TargetRange,
+			wantValue: "255.255.255.0-255.255.255.255",
+			wantFirst: "255.255.255.0",
+			wantLast:  "255.255.255.255",
+		},
+		{
+			name:    "Invalid start address",
+			spec:    "10.0.0+5",
+			wantErr: true,
+		},
+		{
+			name:      "Wildcard",
+			spec:      "10.0.*.*",
+			wantKind:  network.TargetWildcard,
+			wantValue: "10.0.*.*",
+			wantIPNet: "10.0.0.0/16",
+			wantFirst: "10.0.0.0",
+			wantLast:  "10.0.255.255",
+		},
+		{
+			name:      "Wildcard of every address",
+			spec:      "*.*.*.*",
+			wantKind:  network.TargetWildcard,
+			wantValue: "*.*.*.*",
+			wantIPNet: "0.0.0.0/0",
+			wantFirst: "0.0.0.0",
+			wantLast:  "255.255.255.255",
+		},
+		{
+			name:    "Wildcard in a leading octet",
+			spec:    "10.*.0.*",
+			wantErr: true,
+		},
+		{
+			name:    "Wildcard with three octets",
+			spec:    "10.0.*",
+			wantErr: true,
+		},
+		{
+			name:      "Hostname",
+			spec:      "DB-1.Example.com.",
+			wantKind:  network.TargetHostname,
+			wantValue: "db-1.example.com",
+		},
+		{
+			name:    "Hostname with an invalid label",
+			spec:    "db_1.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "Mistyped IP address",
+			spec:    "10.0.0.256",
+			wantErr: true,
+		},
+		{
+			name:    "Empty target",
+			spec:    "  ",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := network.ParseTargetSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTargetSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got.Kind != tt.wantKind || got.Value != tt.wantValue {
+				t.Errorf("ParseTargetSpec() = %s %q, want %s %q", got.Kind, got.Value, tt.wantKind, tt.wantValue)
+			}
+
+			if gotIPNet := ipNetString(got.IPNet); gotIPNet != tt.wantIPNet {
+				t.Errorf("ParseTargetSpec().IPNet = %q, want %q", gotIPNet, tt.wantIPNet)
+			}
+
+			if gotFirst := ipString(got.First); gotFirst != tt.wantFirst {
+				t.Errorf("ParseTargetSpec().First = %q, want %q", gotFirst, tt.wantFirst)
+			}
+
+			if gotLast := ipString(got.Last); gotLast != tt.wantLast {
+				t.Errorf("ParseTargetSpec().Last = %q, want %q", gotLast, tt.wantLast)
+			}
+		})
+	}
+}
+
+// ipNetString returns ipNet in CIDR notation, or an empty string when it is nil.
+func ipNetString(ipNet *net.IPNet) string {
+	if ipNet == nil {
+		return ""
+	}
+
+	return ipNet.String()
+}
+
+// ipString returns ip as a string, or an empty string when it is nil.
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+
+	return ip.String()
+}
+
+func TestRangeToIPNets(t *testing.T) {
+	tests := []struct {
+		name  string
+		first string
+		last  string
+		want  []string
+	}{
+		{
+			name:  "Aligned subnet",
+			first: "10.0.0.0",
+			last:  "10.0.0.255",
+			want:  []string{"10.0.0.0/24"},
+		},
+		{
+			name:  "Unaligned range",
+			first: "10.0.0.0",
+			last:  "10.0.1.243",
+			want:  []string{"10.0.0.0/24", "10.0.1.0/25", "10.0.1.128/26", "10.0.1.192/27", "10.0.1.224/28", "10.0.1.240/30"},
+		},
+		{
+			name:  "Range starting mid-block",
+			first: "10.0.0.3",
+			last:  "10.0.0.8",
+			want:  []string{"10.0.0.3/32", "10.0.0.4/30", "10.0.0.8/32"},
+		},
+		{
+			name:  "Every IPv4 address",
+			first: "0.0.0.0",
+			last:  "255.255.255.255",
+			want:  []string{"0.0.0.0/0"},
+		},
+		{
+			name:  "IPv6 range",
+			first: "2001:db8::1",
+			last:  "2001:db8::4",
+			want:  []string{"2001:db8::1/128", "2001:db8::2/127", "2001:db8::4/128"},
+		},
+		{
+			name:  "Reversed range",
+			first: "10.0.0.8",
+			last:  "10.0.0.3",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, ipNet := range network.RangeToIPNets(net.ParseIP(tt.first), net.ParseIP(tt.last)) {
+				got = append(got, ipNet.String())
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RangeToIPNets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRangeHostsIterator(t *testing.T) {
+	spec, err := network.ParseTargetSpec("10.0.0.0+500")
+	if err != nil {
+		t.Fatalf("ParseTargetSpec() error = %v", err)
+	}
+
+	it := network.NewRangeHostsIterator(spec.First, spec.Last)
+	if it.TotalHosts != 500 {
+		t.Errorf("TotalHosts = %d, want 500", it.TotalHosts)
+	}
+
+	var count int
+	var last *net.IP
+	for ip, _ := it.Next(); ip != nil; ip, _ = it.Next() {
+		count++
+		last = ip
+	}
+
+	if count != 500 {
+		t.Errorf("Next() yielded %d hosts, want 500", count)
+	}
+
+	if last == nil || last.String() != "10.0.1.243" {
+		t.Errorf("Next() yielded %v last, want 10.0.1.243", last)
+	}
+}
+
+func TestNewSubnetHostsIteratorFromRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		wantTotal int
+		wantFirst string
+		wantLast  string
+		wantErr   bool
+	}{
+		{
+			name:      "IPv4 range",
+			start:     "192.168.1.10",
+			end:       "192.168.1.50",
+			wantTotal: 41,
+			wantFirst: "192.168.1.10",
+			wantLast:  "192.168.1.50",
+		},
+		{
+			name:      "IPv4 range across subnets",
+			start:     "10.0.0.250",
+			end:       "10.0.1.5",
+			wantTotal: 12,
+			wantFirst: "10.0.0.250",
+			wantLast:  "10.0.1.5",
+		},
+		{
+			name:      "Single address",
+			start:     "10.0.0.1",
+			end:       "10.0.0.1",
+			wantTotal: 1,
+			wantFirst: "10.0.0.1",
+			wantLast:  "10.0.0.1",
+		},
+		{
+			name:      "IPv6 range",
+			start:     "2001:db8::fe",
+			end:       "2001:db8::101",
+			wantTotal: 4,
+			wantFirst: "2001:db8::fe",
+			wantLast:  "2001:db8::101",
+		},
+		{name: "End precedes start", start: "192.168.1.50", end: "192.168.1.10", wantErr: true},
+		{name: "Mixed IP families", start: "192.168.1.10", end: "2001:db8::1", wantErr: true},
+		{name: "Invalid start", start: "192.168.1", end: "192.168.1.10", wantErr: true},
+		{name: "Invalid end", start: "192.168.1.10", end: "192.168.1.x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it, err := network.NewSubnetHostsIteratorFromRange(tt.start, tt.end)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSubnetHostsIteratorFromRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if it.TotalHosts != tt.wantTotal {
+				t.Errorf("TotalHosts = %d, want %d", it.TotalHosts, tt.wantTotal)
+			}
+
+			var got []string
+			for ip, _ := it.Next(); ip != nil; ip, _ = it.Next() {
+				got = append(got, ip.String())
+			}
+
+			if len(got) != tt.wantTotal || got[0] != tt.wantFirst || got[len(got)-1] != tt.wantLast {
+				t.Errorf("Next() yielded %v, want %d hosts from %s to %s", got, tt.wantTotal, tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrMockFailure is returned by MockPinger for the failing pings of the hosts with a FailFirst.
+var ErrMockFailure = errors.New("simulated transient failure")
+
+// MockHostConfig describes how a simulated host answers the mock pinger.
+type MockHostConfig struct {
+	// Online reports whether the host answers the ping requests.
+	Online bool
+
+	// Latency is the round-trip time of every answered ping request, or its mean when Jitter is set.
+	Latency time.Duration
+
+	// Jitter spreads the round-trip time of each answered ping request uniformly within Latency ± Jitter,
+	// never below zero, so that MinRtt, MaxRtt and StdDevRtt differ from AvgRtt.
+	Jitter time.Duration
+
+	// PacketLoss is the percentage, from 0 to 100, of the ping requests left unanswered by an online host.
+	PacketLoss float64
+
+	// Duplicates is the number of duplicate replies reported by the host when it answers at least one request,
+	// as misconfigured multihomed hosts do.
+	Duplicates int
+
+	// RespondFrom is the address the replies come from. When empty, the target itself replies.
+	RespondFrom string
+
+	// FailFirst is the number of the first pings of the host failing with ErrMockFailure, as on an ephemeral
+	// socket error, before the host answers as configured.
+	FailFirst int
+}
+
+// MockPinger is a Pinger that simulates hosts instead of sending packets, for tests and demonstrations.
+// It is safe for concurrent use as long as its fields are not modified while pinging.
+type MockPinger struct {
+	// Hosts maps the IP addresses of the simulated hosts to their configuration.
+	Hosts map[string]MockHostConfig
+
+	// Default is the configuration of the hosts missing from Hosts. The zero value is an offline host.
+	Default MockHostConfig
+
+	// Seed seeds the random round-trip times of the hosts with a Jitter. Together with the target, it determines
+	// them entirely, so the same seed always produces the same results whatever the order of the pings.
+	Seed int64
+
+	// mu guards calls.
+	mu sync.Mutex
+
+	// calls counts the pings of each target, to fail the first FailFirst of them.
+	calls map[string]int
+}
+
+// NewMockPinger returns a MockPinger simulating the given hosts. Every other host is offline.
+func NewMockPinger(hosts map[string]MockHostConfig) *MockPinger {
+	return &MockPinger{Hosts: hosts}
+}
+
+// Ping returns the simulated statistics of the target. It only fails for the first FailFirst pings of a host.
+// The TTL of the replies is opts.TTL, as if every host were next to the pinger, so that results stay deterministic.
+func (p *MockPinger) Ping(target string, opts Options) (Result, error) {
+	host, ok := p.Hosts[target]
+	if !ok {
+		host = p.Default
+	}
+
+	if host.FailFirst > 0 && p.countCall(target) <= host.FailFirst {
+		return Result{}, fmt.Errorf("failed to ping %s: %w", target, ErrMockFailure)
+	}
+
+	// Only the hosts with a Jitter draw random round-trip times.
+	var rng *rand.Rand
+	if host.Jitter > 0 {
+		rng = rand.New(rand.NewSource(p.Seed ^ targetSeed(target)))
+	}
+
+	result := calculateResult(host, opts, rng)
+	if result.PacketsRecv > 0 && host.RespondFrom != "" && host.RespondFrom != target {
+		result.RespondedFrom = host.RespondFrom
+	}
+
+	if result.PacketsRecv > 0 {
+		result.TTL = opts.TTL
+	}
+
+	return result, nil
+}
+
+// countCall records a ping of target and returns the number of its pings so far, this one included.
+func (p *MockPinger) countCall(target string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.calls == nil {
+		p.calls = make(map[string]int)
+	}
+	p.calls[target]++
+
+	return p.calls[target]
+}
+
+// targetSeed derives a seed from target, so that each simulated host draws its own round-trip times.
+func targetSeed(target string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(target))
+
+	return int64(h.Sum64())
+}
+
+// calculateResult computes the statistics of sending opts.Count ping requests to the given host, drawing
+// the round-trip times from rng when the host has a Jitter, rng being nil otherwise. The lost requests are the first
+// ones, so with opts.StopOnFirstReply the host is probed until its first answered request.
+func calculateResult(host MockHostConfig, opts Options, rng *rand.Rand) Result {
+	result := Result{
+		PacketsSent: opts.Count,
+		PacketLoss:  100,
+	}
+
+	if !host.Online || opts.Count < 1 {
+		return result
+	}
+
+	lost := int(math.Round(float64(opts.Count) * host.PacketLoss / 100))
+	if lost > opts.Count {
+		lost = opts.Count
+	}
+
+	if opts.StopOnFirstReply && lost < opts.Count {
+		result.PacketsSent = lost + 1
+	}
+
+	result.PacketsRecv = result.PacketsSent - lost
+	result.PacketLoss = float64(lost) / float64(result.PacketsSent) * 100
+
+	if result.PacketsRecv > 0 {
+		setRttStats(&result, host.rtts(result.PacketsRecv, rng))
+		result.PacketsRecvDuplicates = host.Duplicates
+	}
+
+	return result
+}
+
+// rtts returns the round-trip times of n answered ping requests to the host.
+func (host MockHostConfig) rtts(n int, rng *rand.Rand) []time.Duration {
+	rtts := make([]time.Duration, n)
+	for i := range rtts {
+		rtts[i] = host.Latency
+		if host.Jitter > 0 {
+			rtts[i] += time.Duration(rng.Int63n(int64(2*host.Jitter)+1)) - host.Jitter
+		}
+
+		if rtts[i] < 0 {
+			rtts[i] = 0
+		}
+	}
+
+	return rtts
+}
+
+// setRttStats fills the round-trip time statistics of result from the given round-trip times, computing the
+// standard deviation over the whole population as pro-bing does.
+func setRttStats(result *Result, rtts []time.Duration) {
+	var sum time.Duration
+
+	result.MinRtt, result.MaxRtt = rtts[0], rtts[0]
+	for _, rtt := range rtts {
+		sum += rtt
+
+		if rtt < result.MinRtt {
+			result.MinRtt = rtt
+		}
+
+		if rtt > result.MaxRtt {
+			result.MaxRtt = rtt
+		}
+	}
+
+	result.AvgRtt = sum / time.Duration(len(rtts))
+
+	var squares float64
+	for _, rtt := range rtts {
+		deviation := float64(rtt - result.AvgRtt)
+		squares += deviation * deviation
+	}
+
+	result.StdDevRtt = time.Duration(math.Sqrt(squares / float64(len(rtts))))
+}
@@ -0,0 +1,226 @@
+package ping_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestMockPinger(t *testing.T) {
+	p := ping.NewMockPinger(map[string]ping.MockHostConfig{
+		"10.0.0.1": {Online: true, Latency: 5 * time.Millisecond},
+		"10.0.0.2": {Online: true, Latency: 20 * time.Millisecond, PacketLoss: 50},
+		"10.0.0.3": {Online: true, Latency: 20 * time.Millisecond, PacketLoss: 100},
+		"10.0.0.5": {Online: true, Latency: time.Millisecond, RespondFrom: "10.0.0.254"},
+		"10.0.0.6": {Online: true, Latency: time.Millisecond, Duplicates: 3},
+		"10.0.0.7": {Online: true, Latency: time.Millisecond, PacketLoss: 100, Duplicates: 3},
+	})
+
+	tests := []struct {
+		name   string
+		target string
+		want   ping.Result
+	}{
+		{
+			name:   "Online host",
+			target: "10.0.0.1",
+			want: ping.Result{AvgRtt: 5 * time.Millisecond, MinRtt: 5 * time.Millisecond, MaxRtt: 5 * time.Millisecond,
+				PacketsSent: 4, PacketsRecv: 4},
+		},
+		{
+			name:   "Lossy host",
+			target: "10.0.0.2",
+			want: ping.Result{AvgRtt: 20 * time.Millisecond, MinRtt: 20 * time.Millisecond, MaxRtt: 20 * time.Millisecond,
+				PacketLoss: 50, PacketsSent: 4, PacketsRecv: 2},
+		},
+		{
+			name:   "Host losing every packet",
+			target: "10.0.0.3",
+			want:   ping.Result{PacketLoss: 100, PacketsSent: 4},
+		},
+		{
+			name:   "Host answered by another address",
+			target: "10.0.0.5",
+			want: ping.Result{AvgRtt: time.Millisecond, MinRtt: time.Millisecond, MaxRtt: time.Millisecond,
+				PacketsSent: 4, PacketsRecv: 4, RespondedFrom: "10.0.0.254"},
+		},
+		{
+			name:   "Host answering with duplicates",
+			target: "10.0.0.6",
+			want: ping.Result{AvgRtt: time.Millisecond, MinRtt: time.Millisecond, MaxRtt: time.Millisecond,
+				PacketsSent: 4, PacketsRecv: 4, PacketsRecvDuplicates: 3},
+		},
+		{
+			name:   "Host losing every packet has no duplicates",
+			target: "10.0.0.7",
+			want:   ping.Result{PacketLoss: 100, PacketsSent: 4},
+		},
+		{
+			name:   "Unknown host is offline",
+			target: "10.0.0.4",
+			want:   ping.Result{PacketLoss: 100, PacketsSent: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Ping(tt.target, ping.Options{Count: 4})
+			if err != nil {
+				t.Fatalf("Ping() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ping() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockPingerStopOnFirstReply(t *testing.T) {
+	p := ping.NewMockPinger(map[string]ping.MockHostConfig{
+		"10.0.0.1": {Online: true, Latency: 5 * time.Millisecond},
+		"10.0.0.2": {Online: true, Latency: 20 * time.Millisecond, PacketLoss: 25},
+	})
+
+	tests := []struct {
+		name   string
+		target string
+		want   ping.Result
+	}{
+		{
+			name:   "Online host replies to the first request",
+			target: "10.0.0.1",
+			want: ping.Result{AvgRtt: 5 * time.Millisecond, MinRtt: 5 * time.Millisecond, MaxRtt: 5 * time.Millisecond,
+				PacketsSent: 1, PacketsRecv: 1},
+		},
+		{
+			name:   "Lossy host replies after its lost requests",
+			target: "10.0.0.2",
+			want: ping.Result{AvgRtt: 20 * time.Millisecond, MinRtt: 20 * time.Millisecond, MaxRtt: 20 * time.Millisecond,
+				PacketLoss: 50, PacketsSent: 2, PacketsRecv: 1},
+		},
+		{
+			name:   "Offline host gets every request",
+			target: "10.0.0.3",
+			want:   ping.Result{PacketLoss: 100, PacketsSent: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Ping(tt.target, ping.Options{Count: 4, StopOnFirstReply: true})
+			if err != nil {
+				t.Fatalf("Ping() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ping() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockPingerTTL(t *testing.T) {
+	p := ping.NewMockPinger(map[string]ping.MockHostConfig{
+		"10.0.0.1": {Online: true, Latency: time.Millisecond},
+	})
+
+	tests := []struct {
+		name   string
+		target string
+		ttl    int
+		want   int
+	}{
+		{name: "Online host echoes the TTL", target: "10.0.0.1", ttl: 32, want: 32},
+		{name: "Online host with the default TTL", target: "10.0.0.1", ttl: 0, want: 0},
+		{name: "Offline host has no TTL", target: "10.0.0.2", ttl: 32, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Ping(tt.target, ping.Options{Count: 1, TTL: tt.ttl})
+			if err != nil {
+				t.Fatalf("Ping() error = %v", err)
+			}
+
+			if got.TTL != tt.want {
+				t.Errorf("Ping() TTL = %d, want %d", got.TTL, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockPingerFailFirst(t *testing.T) {
+	p := ping.NewMockPinger(map[string]ping.MockHostConfig{
+		"10.0.0.1": {Online: true, Latency: time.Millisecond, FailFirst: 2},
+	})
+
+	for call := 1; call <= 3; call++ {
+		got, err := p.Ping("10.0.0.1", ping.Options{Count: 1})
+		if call <= 2 {
+			if !errors.Is(err, ping.ErrMockFailure) {
+				t.Errorf("Ping() call %d error = %v, want %v", call, err, ping.ErrMockFailure)
+			}
+
+			continue
+		}
+
+		if err != nil || got.PacketsRecv != 1 {
+			t.Errorf("Ping() call %d = %+v, %v, want a reply", call, got, err)
+		}
+	}
+}
+
+func TestMockPingerJitter(t *testing.T) {
+	const (
+		latency = 20 * time.Millisecond
+		jitter  = 5 * time.Millisecond
+	)
+
+	newPinger := func(seed int64) *ping.MockPinger {
+		p := ping.NewMockPinger(map[string]ping.MockHostConfig{
+			"10.0.0.1": {Online: true, Latency: latency, Jitter: jitter},
+			"10.0.0.2": {Online: true, Latency: time.Millisecond, Jitter: jitter},
+		})
+		p.Seed = seed
+
+		return p
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		minRtt time.Duration
+		maxRtt time.Duration
+	}{
+		{name: "RTTs within the jitter", target: "10.0.0.1", minRtt: latency - jitter, maxRtt: latency + jitter},
+		{name: "RTTs never below zero", target: "10.0.0.2", minRtt: 0, maxRtt: time.Millisecond + jitter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newPinger(1).Ping(tt.target, ping.Options{Count: 50})
+			if err != nil {
+				t.Fatalf("Ping() error = %v", err)
+			}
+
+			if got.MinRtt < tt.minRtt || got.MaxRtt > tt.maxRtt || got.MinRtt >= got.MaxRtt {
+				t.Errorf("Ping() RTTs range from %s to %s, want distinct RTTs within %s to %s",
+					got.MinRtt, got.MaxRtt, tt.minRtt, tt.maxRtt)
+			}
+
+			if got.AvgRtt < got.MinRtt || got.AvgRtt > got.MaxRtt || got.StdDevRtt <= 0 {
+				t.Errorf("Ping() = %+v, want an average within the range and a positive standard deviation", got)
+			}
+
+			again, _ := newPinger(1).Ping(tt.target, ping.Options{Count: 50})
+			if !reflect.DeepEqual(got, again) {
+				t.Errorf("Ping() with the same seed = %+v, want %+v", again, got)
+			}
+
+			other, _ := newPinger(2).Ping(tt.target, ping.Options{Count: 50})
+			if reflect.DeepEqual(got, other) {
+				t.Errorf("Ping() with another seed = %+v, want different RTTs", other)
+			}
+		})
+	}
+}
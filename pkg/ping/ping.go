@@ -0,0 +1,277 @@
+// Package ping provides the Pinger abstraction used by subping to probe a single target.
+//
+// The package defines the Result type holding the statistics of a ping operation, the Pinger interface
+// implemented by every probing strategy, and the default ICMP implementation backed by pro-bing.
+//
+// Example:
+//
+//	p := ping.NewPinger()
+//
+//	result, err := p.Ping("192.168.0.1", ping.Options{
+//		Count:    3,
+//		Interval: 300 * time.Millisecond,
+//		Timeout:  time.Second,
+//	})
+//	if err != nil {
+//		fmt.Println("Error:", err)
+//		return
+//	}
+//	fmt.Println("Average RTT:", result.AvgRtt)
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+)
+
+const (
+	// DefaultPayloadSize is the size in bytes of the payload of the echo requests sent by the ICMP pinger when
+	// Options.Size is zero. It is also the smallest payload, holding the timestamp and the tracking UUID of the
+	// request.
+	DefaultPayloadSize = 24
+
+	// MaxPayloadSize is the largest payload in bytes of an echo request, which fills an IPv4 packet of 65535 bytes.
+	MaxPayloadSize = 65507
+
+	// MaxTTL is the largest time to live, or hop limit, of an echo request.
+	MaxTTL = 255
+
+	// MaxTOS is the largest type of service, or traffic class over IPv6, of an echo request. Its 6 high bits are
+	// the DSCP of the request.
+	MaxTOS = 255
+)
+
+// ErrTOSUnsupported is returned by the pingers unable to honour Options.TOS: the ICMP pinger, since pro-bing has no
+// setting for the type of service of the echo requests, and the TCP pinger on the platforms where its connections
+// cannot be marked.
+var ErrTOSUnsupported = errors.New("setting the type of service of the requests is unsupported on this platform")
+
+// Result contains the statistics and metrics for a single ping operation.
+type Result struct {
+	// AvgRtt is the average round-trip time of the ping requests.
+	AvgRtt time.Duration
+
+	// MinRtt is the shortest round-trip time of the ping requests.
+	MinRtt time.Duration
+
+	// MaxRtt is the longest round-trip time of the ping requests.
+	MaxRtt time.Duration
+
+	// StdDevRtt is the standard deviation of the round-trip times of the ping requests.
+	StdDevRtt time.Duration
+
+	// PacketLoss is the percentage of packets lost during the ping operation.
+	PacketLoss float64
+
+	// PacketsSent is the number of packets sent for the ping operation.
+	PacketsSent int
+
+	// PacketsRecv is the number of packets received for the ping operation.
+	PacketsRecv int
+
+	// PacketsRecvDuplicates is the number of duplicate packets received.
+	PacketsRecvDuplicates int
+
+	// Score is the reachability score of the target, from 0 (unreachable) to 100 (no loss, no latency).
+	// Pingers leave it empty; it is computed by subping from the other fields.
+	Score float64
+
+	// Ports reports, for pingers probing TCP ports, whether each port accepted a connection.
+	// It is nil for ICMP pings.
+	Ports map[int]bool
+
+	// TTL is the time to live, or hop limit over IPv6, of the first reply received. Subtracted from the initial
+	// TTL of the responder, commonly 64, 128 or 255, it estimates its distance in hops. It is zero when no reply
+	// was received or when the pinger does not report it.
+	TTL int
+
+	// RespondedFrom is the address the replies came from when it differs from the target,
+	// which reveals NAT, proxy ARP or misrouting. It is empty when the target itself replied.
+	RespondedFrom string
+
+	// Hostname is the name of the target found by reverse DNS.
+	// Pingers leave it empty; it is filled by the ResolveHostnames processor of subping.
+	Hostname string
+
+	// Count is the number of ping requests the result was obtained with, e.g. to tell a quick check from a
+	// confirmation. Pingers leave it empty; it is filled by subping.
+	Count int
+}
+
+// Options holds the parameters of a single ping operation.
+type Options struct {
+	// Count is the number of ping requests to send to the target.
+	Count int
+
+	// Interval is the time duration between each ping request.
+	Interval time.Duration
+
+	// Timeout specifies the timeout duration before giving up on the target.
+	Timeout time.Duration
+
+	// Privileged makes the ICMP pinger use raw sockets, which requires elevated privileges,
+	// instead of unprivileged datagram sockets. It is always enabled on Windows.
+	Privileged bool
+
+	// Source is the source IP address of the ping requests. When empty, the system picks it.
+	Source string
+
+	// StopOnFirstReply stops probing the target as soon as it replies once instead of sending all Count requests,
+	// saving packets and time on hosts that are clearly up.
+	StopOnFirstReply bool
+
+	// Size is the size in bytes of the payload of each echo request, from DefaultPayloadSize to MaxPayloadSize.
+	// Larger payloads reveal MTU and fragmentation problems that small requests miss. When zero,
+	// DefaultPayloadSize is used. It is ignored by the pingers not sending ICMP echo requests.
+	Size int
+
+	// TTL is the time to live, or hop limit over IPv6, of each echo request, up to MaxTTL. When zero, the default
+	// of the pinger is used, 64 for the ICMP pinger.
+	TTL int
+
+	// TOS is the type of service, or traffic class over IPv6, of each echo request, up to MaxTOS, e.g. 184 to mark
+	// the requests with the EF DSCP. When zero, the requests are not marked. The TCP pinger marks the packets of its
+	// connections. The pingers unable to mark their requests, such as the ICMP pinger, fail with ErrTOSUnsupported
+	// rather than ignoring it.
+	TOS int
+}
+
+// Pinger probes a single target and reports the collected statistics.
+//
+// Implementations must be safe for concurrent use, since subping calls Ping from multiple workers.
+type Pinger interface {
+	// Ping probes the target using the given options. An error is returned when the probe
+	// could not be performed at all; an unreachable target is reported through the Result.
+	Ping(target string, opts Options) (Result, error)
+}
+
+// The kinds of Pinger accepted by NewPingerWithOptions.
+const (
+	// KindICMP is the default Pinger, sending ICMP echo requests, see NewPinger.
+	KindICMP = "icmp"
+
+	// KindTCP is the Pinger measuring the latency of TCP handshakes, see NewTCPPinger.
+	KindTCP = "tcp"
+)
+
+// NewPinger returns the default Pinger, which sends ICMP echo requests using pro-bing.
+func NewPinger() Pinger {
+	return &realPinger{}
+}
+
+// NewPingerWithOptions returns the Pinger of the given kind, KindICMP or KindTCP. The TCP pinger connects to each of
+// ports in turn, which must hold at least one port between 1 and 65535; the ICMP pinger takes no port.
+func NewPingerWithOptions(kind string, ports []int) (Pinger, error) {
+	switch kind {
+	case KindICMP:
+		if len(ports) > 0 {
+			return nil, fmt.Errorf("the %s pinger takes no port, got %v", kind, ports)
+		}
+
+		return NewPinger(), nil
+	case KindTCP:
+		if len(ports) == 0 {
+			return nil, fmt.Errorf("the %s pinger requires at least one port", kind)
+		}
+
+		for _, port := range ports {
+			if port < 1 || port > 65535 {
+				return nil, fmt.Errorf("port %d is out of range (1-65535)", port)
+			}
+		}
+
+		return NewTCPPinger(ports, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown pinger kind %q, expected %s or %s", kind, KindICMP, KindTCP)
+	}
+}
+
+// realPinger is the ICMP Pinger backed by pro-bing.
+type realPinger struct{}
+
+// Ping sends ICMP echo requests to the target and returns the collected statistics.
+func (p *realPinger) Ping(target string, opts Options) (Result, error) {
+	if opts.TOS != 0 {
+		return Result{}, fmt.Errorf("failed to ping %s: %w", target, ErrTOSUnsupported)
+	}
+
+	pinger, err := probing.NewPinger(target)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create pinger for %s: %w", target, err)
+	}
+
+	pinger.Count = opts.Count
+	pinger.Interval = opts.Interval
+
+	if opts.Timeout > 0 {
+		pinger.Timeout = opts.Timeout
+	}
+
+	if opts.Source != "" {
+		pinger.Source = opts.Source
+	}
+
+	if opts.Size > 0 {
+		pinger.Size = opts.Size
+	}
+
+	if opts.TTL > 0 {
+		pinger.TTL = opts.TTL
+	}
+
+	if opts.Privileged || runtime.GOOS == "windows" {
+		pinger.SetPrivileged(true)
+	}
+
+	// OnRecv is called from the goroutine of Run, so the responder and the TTL can be read once Run returns.
+	var (
+		responder net.IP
+		ttl       int
+	)
+	pinger.OnRecv = func(pkt *probing.Packet) {
+		if responder == nil && pkt.IPAddr != nil {
+			responder = pkt.IPAddr.IP
+		}
+
+		if ttl == 0 {
+			ttl = pkt.TTL
+		}
+
+		if opts.StopOnFirstReply {
+			pinger.Stop()
+		}
+	}
+
+	if err := pinger.Run(); err != nil {
+		return Result{}, fmt.Errorf("failed to ping %s: %w", target, err)
+	}
+
+	stats := pinger.Statistics()
+
+	return Result{
+		AvgRtt:                stats.AvgRtt,
+		MinRtt:                stats.MinRtt,
+		MaxRtt:                stats.MaxRtt,
+		StdDevRtt:             stats.StdDevRtt,
+		PacketLoss:            stats.PacketLoss,
+		PacketsSent:           stats.PacketsSent,
+		PacketsRecv:           stats.PacketsRecv,
+		PacketsRecvDuplicates: stats.PacketsRecvDuplicates,
+		TTL:                   ttl,
+		RespondedFrom:         respondedFrom(responder, pinger.IPAddr()),
+	}, nil
+}
+
+// respondedFrom returns the address of the responder when it differs from the target, or an empty string.
+func respondedFrom(responder net.IP, target *net.IPAddr) string {
+	if responder == nil || target == nil || responder.Equal(target.IP) {
+		return ""
+	}
+
+	return responder.String()
+}
@@ -0,0 +1,60 @@
+package ping_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestRealPinger(t *testing.T) {
+	tests := []struct {
+		name            string
+		target          string
+		wantErr         bool
+		wantPacketsRecv int
+	}{
+		{
+			name:            "Localhost replies",
+			target:          "127.0.0.1",
+			wantErr:         false,
+			wantPacketsRecv: 2,
+		},
+		{
+			name:            "Invalid target",
+			target:          "invalid..host",
+			wantErr:         true,
+			wantPacketsRecv: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ping.NewPinger().Ping(tt.target, ping.Options{
+				Count:    2,
+				Interval: 100 * time.Millisecond,
+				Timeout:  time.Second,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got.PacketsRecv != tt.wantPacketsRecv {
+				t.Errorf("Ping() PacketsRecv = %v, want %v", got.PacketsRecv, tt.wantPacketsRecv)
+			}
+		})
+	}
+}
+
+func TestRealPingerTOS(t *testing.T) {
+	_, err := ping.NewPinger().Ping("127.0.0.1", ping.Options{
+		Count:    1,
+		Interval: 100 * time.Millisecond,
+		Timeout:  time.Second,
+		TOS:      184,
+	})
+	if !errors.Is(err, ping.ErrTOSUnsupported) {
+		t.Errorf("Ping() error = %v, want %v", err, ping.ErrTOSUnsupported)
+	}
+}
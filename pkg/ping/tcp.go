@@ -0,0 +1,161 @@
+package ping
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// defaultTCPTimeout is the connection timeout used by the TCP pinger when none is given.
+const defaultTCPTimeout = time.Second
+
+// Dialer opens network connections. *net.Dialer satisfies this interface.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// ConfigurableDialer is a Dialer able to apply the options of a probe to its connections. The TCP pinger uses it to
+// open the connections from Options.Source and mark them with Options.TOS. A *net.Dialer needs not implement it: the
+// pinger configures a copy of it.
+type ConfigurableDialer interface {
+	Dialer
+
+	// Configure returns a Dialer whose connections leave from opts.Source when it is set and are marked with opts.TOS
+	// when it is not zero.
+	Configure(opts Options) (Dialer, error)
+}
+
+// tcpPinger is a Pinger that measures the latency of TCP handshakes instead of sending ICMP echo requests.
+// Every connection attempt counts as a sent packet and every completed handshake as a received packet.
+type tcpPinger struct {
+	ports  []int
+	dialer Dialer
+}
+
+// NewTCPPinger returns a Pinger that probes each target by connecting to every given port in turn.
+// The ports of a target are probed sequentially, so the number of concurrent connections never exceeds
+// the number of concurrent Ping calls. When dialer is nil, a *net.Dialer is used. Options.Source and Options.TOS
+// are honoured by a *net.Dialer and by the dialers implementing ConfigurableDialer; the others fail rather than
+// ignoring them, with ErrTOSUnsupported for TOS.
+func NewTCPPinger(ports []int, dialer Dialer) Pinger {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	return &tcpPinger{ports: ports, dialer: dialer}
+}
+
+// Ping connects Count times to every port of the target and reports, in Result.Ports,
+// which ports accepted at least one connection. With StopOnFirstReply, it stops at the first accepted connection.
+func (p *tcpPinger) Ping(target string, opts Options) (Result, error) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTCPTimeout
+	}
+
+	dialer, err := p.dialerFor(opts)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to ping %s: %w", target, err)
+	}
+
+	result := Result{Ports: make(map[int]bool, len(p.ports))}
+	for _, port := range p.ports {
+		result.Ports[port] = false
+	}
+
+	var totalRtt time.Duration
+
+	for i := 0; i < opts.Count && !(opts.StopOnFirstReply && result.PacketsRecv > 0); i++ {
+		if i > 0 {
+			time.Sleep(opts.Interval)
+		}
+
+		for _, port := range p.ports {
+			result.PacketsSent++
+
+			rtt, err := connect(dialer, target, port, timeout)
+			if err != nil {
+				continue
+			}
+
+			result.PacketsRecv++
+			result.Ports[port] = true
+			totalRtt += rtt
+
+			if opts.StopOnFirstReply {
+				break
+			}
+		}
+	}
+
+	if result.PacketsRecv > 0 {
+		result.AvgRtt = totalRtt / time.Duration(result.PacketsRecv)
+	}
+
+	if result.PacketsSent > 0 {
+		result.PacketLoss = float64(result.PacketsSent-result.PacketsRecv) / float64(result.PacketsSent) * 100
+	}
+
+	return result, nil
+}
+
+// dialerFor returns the dialer of the pinger configured with the options of a probe.
+func (p *tcpPinger) dialerFor(opts Options) (Dialer, error) {
+	if opts.Source == "" && opts.TOS == 0 {
+		return p.dialer, nil
+	}
+
+	switch d := p.dialer.(type) {
+	case ConfigurableDialer:
+		return d.Configure(opts)
+	case *net.Dialer:
+		configured := *d
+
+		if opts.Source != "" {
+			ip := net.ParseIP(opts.Source)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid source address %q", opts.Source)
+			}
+
+			configured.LocalAddr = &net.TCPAddr{IP: ip}
+		}
+
+		if opts.TOS != 0 {
+			control, err := tosControl(opts.TOS)
+			if err != nil {
+				return nil, err
+			}
+
+			configured.Control = control
+		}
+
+		return &configured, nil
+	default:
+		if opts.TOS != 0 {
+			return nil, fmt.Errorf("%w: the dialer %T cannot mark its connections", ErrTOSUnsupported, p.dialer)
+		}
+
+		return nil, fmt.Errorf("the dialer %T cannot open its connections from the source address %s",
+			p.dialer, opts.Source)
+	}
+}
+
+// connect opens a TCP connection to the port of the target and returns the time the handshake took.
+func connect(dialer Dialer, target string, port int, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(target, strconv.Itoa(port)))
+	if err != nil {
+		return 0, err
+	}
+
+	rtt := time.Since(start)
+	_ = conn.Close()
+
+	return rtt, nil
+}
@@ -0,0 +1,265 @@
+package ping_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// stubDialer is a ping.Dialer accepting connections only to the configured addresses.
+type stubDialer struct {
+	mu       sync.Mutex
+	open     map[string]bool
+	attempts []string
+}
+
+func (d *stubDialer) DialContext(_ context.Context, _, address string) (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.attempts = append(d.attempts, address)
+
+	if !d.open[address] {
+		return nil, errors.New("connection refused")
+	}
+
+	client, server := net.Pipe()
+	_ = server.Close()
+
+	return client, nil
+}
+
+func TestTCPPingerPorts(t *testing.T) {
+	tests := []struct {
+		name            string
+		target          string
+		count           int
+		want            map[int]bool
+		wantPacketsSent int
+		wantPacketsRecv int
+	}{
+		{
+			name:            "Some ports open",
+			target:          "10.0.0.1",
+			count:           1,
+			want:            map[int]bool{22: true, 80: false, 443: true},
+			wantPacketsSent: 3,
+			wantPacketsRecv: 2,
+		},
+		{
+			name:            "All ports closed",
+			target:          "10.0.0.2",
+			count:           2,
+			want:            map[int]bool{22: false, 80: false, 443: false},
+			wantPacketsSent: 6,
+			wantPacketsRecv: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer := &stubDialer{open: map[string]bool{"10.0.0.1:22": true, "10.0.0.1:443": true}}
+
+			got, err := ping.NewTCPPinger([]int{22, 80, 443}, dialer).Ping(tt.target, ping.Options{
+				Count:    tt.count,
+				Interval: time.Millisecond,
+				Timeout:  time.Second,
+			})
+			if err != nil {
+				t.Fatalf("Ping() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got.Ports, tt.want) {
+				t.Errorf("Ping() Ports = %v, want %v", got.Ports, tt.want)
+			}
+
+			if got.PacketsSent != tt.wantPacketsSent || got.PacketsRecv != tt.wantPacketsRecv {
+				t.Errorf("Ping() PacketsSent/PacketsRecv = %d/%d, want %d/%d",
+					got.PacketsSent, got.PacketsRecv, tt.wantPacketsSent, tt.wantPacketsRecv)
+			}
+
+			if len(dialer.attempts) != tt.wantPacketsSent {
+				t.Errorf("Ping() made %d connection attempts, want %d", len(dialer.attempts), tt.wantPacketsSent)
+			}
+		})
+	}
+}
+
+func TestNewPingerWithOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		ports   []int
+		wantErr bool
+	}{
+		{name: "ICMP", kind: ping.KindICMP},
+		{name: "TCP", kind: ping.KindTCP, ports: []int{22, 443}},
+		{name: "ICMP with a port", kind: ping.KindICMP, ports: []int{443}, wantErr: true},
+		{name: "TCP without port", kind: ping.KindTCP, wantErr: true},
+		{name: "TCP port out of range", kind: ping.KindTCP, ports: []int{65536}, wantErr: true},
+		{name: "Unknown kind", kind: "udp", ports: []int{53}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ping.NewPingerWithOptions(tt.kind, tt.ports)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPingerWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && p == nil {
+				t.Error("NewPingerWithOptions() returned a nil Pinger")
+			}
+		})
+	}
+
+	// The TCP pinger probes the given ports, here the port of a local listener.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer l.Close()
+
+	port := l.Addr().(*net.TCPAddr).Port
+
+	p, err := ping.NewPingerWithOptions(ping.KindTCP, []int{port})
+	if err != nil {
+		t.Fatalf("NewPingerWithOptions() error = %v", err)
+	}
+
+	result, err := p.Ping("127.0.0.1", ping.Options{Count: 1, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	if want := map[int]bool{port: true}; !reflect.DeepEqual(result.Ports, want) {
+		t.Errorf("Ping() Ports = %v, want %v", result.Ports, want)
+	}
+}
+
+// configurableDialer is a stubDialer implementing ping.ConfigurableDialer, recording the options it is configured with.
+type configurableDialer struct {
+	stubDialer
+	configured []ping.Options
+}
+
+func (d *configurableDialer) Configure(opts ping.Options) (ping.Dialer, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.configured = append(d.configured, opts)
+
+	return &d.stubDialer, nil
+}
+
+func TestTCPPingerSource(t *testing.T) {
+	opts := ping.Options{Count: 1, Timeout: time.Second, Source: "10.0.1.5"}
+
+	dialer := &configurableDialer{stubDialer: stubDialer{open: map[string]bool{"10.0.0.1:443": true}}}
+
+	got, err := ping.NewTCPPinger([]int{443}, dialer).Ping("10.0.0.1", opts)
+	if err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	if got.PacketsRecv != 1 {
+		t.Errorf("Ping() PacketsRecv = %d, want 1", got.PacketsRecv)
+	}
+
+	if len(dialer.configured) != 1 || dialer.configured[0].Source != opts.Source {
+		t.Errorf("Ping() configured the dialer with %+v, want the source %s", dialer.configured, opts.Source)
+	}
+
+	// A dialer unable to pick the source address is not silently used.
+	if _, err := ping.NewTCPPinger([]int{443}, &stubDialer{}).Ping("10.0.0.1", opts); err == nil {
+		t.Error("Ping() with a plain Dialer and a source did not fail")
+	}
+
+	// The default dialer opens the connections from the source, here 127.0.0.2 to a local listener.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Addr, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+
+		accepted <- conn.RemoteAddr()
+		conn.Close()
+	}()
+
+	port := l.Addr().(*net.TCPAddr).Port
+
+	got, err = ping.NewTCPPinger([]int{port}, nil).Ping("127.0.0.1", ping.Options{
+		Count:   1,
+		Timeout: time.Second,
+		Source:  "127.0.0.2",
+	})
+	if err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	if !got.Ports[port] {
+		t.Skipf("Ping() from 127.0.0.2 failed on this platform, Ports = %v", got.Ports)
+	}
+
+	if addr, ok := (<-accepted).(*net.TCPAddr); !ok || !addr.IP.Equal(net.ParseIP("127.0.0.2")) {
+		t.Errorf("Ping() connected from %v, want 127.0.0.2", addr)
+	}
+}
+
+func TestTCPPingerTOS(t *testing.T) {
+	opts := ping.Options{Count: 1, Timeout: time.Second, TOS: 184}
+
+	dialer := &configurableDialer{stubDialer: stubDialer{open: map[string]bool{"10.0.0.1:443": true}}}
+
+	got, err := ping.NewTCPPinger([]int{443}, dialer).Ping("10.0.0.1", opts)
+	if err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	if got.PacketsRecv != 1 {
+		t.Errorf("Ping() PacketsRecv = %d, want 1", got.PacketsRecv)
+	}
+
+	if len(dialer.configured) != 1 || dialer.configured[0].TOS != opts.TOS {
+		t.Errorf("Ping() configured the dialer with %+v, want the TOS %d", dialer.configured, opts.TOS)
+	}
+
+	// A dialer unable to mark its connections is not silently used.
+	_, err = ping.NewTCPPinger([]int{443}, &stubDialer{}).Ping("10.0.0.1", opts)
+	if !errors.Is(err, ping.ErrTOSUnsupported) {
+		t.Errorf("Ping() with a plain Dialer error = %v, want %v", err, ping.ErrTOSUnsupported)
+	}
+
+	// The default dialer marks the connections, here to a local listener.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer l.Close()
+
+	port := l.Addr().(*net.TCPAddr).Port
+
+	got, err = ping.NewTCPPinger([]int{port}, nil).Ping("127.0.0.1", opts)
+	if errors.Is(err, ping.ErrTOSUnsupported) {
+		t.Skipf("Ping() error = %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	if !got.Ports[port] {
+		t.Errorf("Ping() Ports = %v, want the port %d of the listener open", got.Ports, port)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package ping
+
+import "syscall"
+
+// tosControl fails with ErrTOSUnsupported: the connections cannot be marked on this platform.
+func tosControl(int) (func(network, address string, c syscall.RawConn) error, error) {
+	return nil, ErrTOSUnsupported
+}
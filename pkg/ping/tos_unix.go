@@ -0,0 +1,25 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package ping
+
+import "syscall"
+
+// tosControl returns the net.Dialer.Control hook marking the connections with the type of service tos, through
+// IP_TOS over IPv4 and IPV6_TCLASS over IPv6.
+func tosControl(tos int) (func(network, address string, c syscall.RawConn) error, error) {
+	return func(network, _ string, c syscall.RawConn) error {
+		level, opt := syscall.IPPROTO_IP, syscall.IP_TOS
+		if network == "tcp6" {
+			level, opt = syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS
+		}
+
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = syscall.SetsockoptInt(int(fd), level, opt, tos)
+		}); err != nil {
+			return err
+		}
+
+		return sockErr
+	}, nil
+}
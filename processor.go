@@ -0,0 +1,138 @@
+package subping
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResultProcessor enriches or filters the results of a scan, see Options.Processors.
+type ResultProcessor interface {
+	// Process returns the processed results, keyed by IP address. It may modify and return results.
+	Process(results map[string]Result) map[string]Result
+}
+
+// ResultProcessorFunc adapts an ordinary function to the ResultProcessor interface.
+type ResultProcessorFunc func(results map[string]Result) map[string]Result
+
+// Process calls f(results).
+func (f ResultProcessorFunc) Process(results map[string]Result) map[string]Result {
+	return f(results)
+}
+
+// FilterOffline is a ResultProcessor dropping the hosts that did not reply.
+type FilterOffline struct{}
+
+// Process returns the results of the hosts that replied.
+func (FilterOffline) Process(results map[string]Result) map[string]Result {
+	online := make(map[string]Result, len(results))
+	for ip, result := range results {
+		if result.PacketsRecv > 0 {
+			online[ip] = result
+		}
+	}
+
+	return online
+}
+
+// DefaultResolveTimeout is the time ResolveHostnames waits for the reverse DNS lookup of a host when its Timeout is
+// not set.
+const DefaultResolveTimeout = 2 * time.Second
+
+// ContextResolver returns the names pointing to an IP address, i.e. its PTR records, giving up once ctx is done.
+// net.DefaultResolver.LookupAddr is a ContextResolver.
+type ContextResolver func(ctx context.Context, addr string) ([]string, error)
+
+// ResolveHostnames is a ResultProcessor filling the Hostname of every host with the first name returned by a reverse
+// DNS lookup, without the trailing dot. The hosts whose lookup fails or times out are left without a hostname.
+type ResolveHostnames struct {
+	// Resolve looks up the names of an IP address. When nil, net.DefaultResolver.LookupAddr is used.
+	Resolve ContextResolver
+
+	// Workers is the maximum number of concurrent lookups, each holding its slot until Resolve returns. When zero or
+	// negative, the hosts are resolved one after the other.
+	Workers int
+
+	// Timeout is the time waited for the lookup of a single host, after which the context of Resolve is done. When
+	// zero or negative, DefaultResolveTimeout is used.
+	Timeout time.Duration
+
+	// OnlineOnly skips the lookups of the hosts that did not reply, which avoids most lookups of large subnets.
+	OnlineOnly bool
+}
+
+// Process fills the Hostname of the results whose IP address resolves to a name.
+func (p ResolveHostnames) Process(results map[string]Result) map[string]Result {
+	resolve := p.Resolve
+	if resolve == nil {
+		resolve = net.DefaultResolver.LookupAddr
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultResolveTimeout
+	}
+
+	workers := p.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, workers)
+		hostnames = make(map[string]string)
+	)
+
+	for ip, result := range results {
+		if p.OnlineOnly && result.PacketsRecv == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(ip string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			hostname, ok := lookupHostname(resolve, ip, timeout)
+			if !ok {
+				return
+			}
+
+			mu.Lock()
+			hostnames[ip] = hostname
+			mu.Unlock()
+		}(ip)
+	}
+
+	wg.Wait()
+
+	for ip, hostname := range hostnames {
+		result := results[ip]
+		result.Hostname = hostname
+		results[ip] = result
+	}
+
+	return results
+}
+
+// lookupHostname returns the first name of ip found by resolve, without the trailing dot. It reports false when the
+// lookup fails or finds no name. The lookup is given up once timeout elapses.
+func lookupHostname(resolve ContextResolver, ip string, timeout time.Duration) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	names, err := resolve(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return "", false
+	}
+
+	return strings.TrimSuffix(names[0], "."), true
+}
@@ -0,0 +1,163 @@
+package subping_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestProcessors(t *testing.T) {
+	resolve := func(_ context.Context, addr string) ([]string, error) {
+		if addr == "10.0.0.1" {
+			return []string{"gw.example.com."}, nil
+		}
+
+		return nil, errors.New("no PTR record")
+	}
+
+	var resolved []string
+	countingResolve := func(ctx context.Context, addr string) ([]string, error) {
+		resolved = append(resolved, addr)
+
+		return resolve(ctx, addr)
+	}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger: ping.NewMockPinger(map[string]ping.MockHostConfig{
+			"10.0.0.1": {Online: true, Latency: time.Millisecond},
+			"10.0.0.2": {Online: true, Latency: 2 * time.Millisecond},
+		}),
+		Processors: []subping.ResultProcessor{
+			subping.FilterOffline{},
+			subping.ResolveHostnames{Resolve: countingResolve},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	want := map[string]subping.Result{
+		"10.0.0.1": {AvgRtt: time.Millisecond, MinRtt: time.Millisecond, MaxRtt: time.Millisecond,
+			PacketsSent: 1, PacketsRecv: 1, Score: sp.Results["10.0.0.1"].Score, Hostname: "gw.example.com", Count: 1},
+		"10.0.0.2": {AvgRtt: 2 * time.Millisecond, MinRtt: 2 * time.Millisecond, MaxRtt: 2 * time.Millisecond,
+			PacketsSent: 1, PacketsRecv: 1, Score: sp.Results["10.0.0.2"].Score, Count: 1},
+	}
+	if !reflect.DeepEqual(sp.Results, want) {
+		t.Errorf("Results = %+v, want %+v", sp.Results, want)
+	}
+
+	if sp.TotalResults != 2 {
+		t.Errorf("TotalResults = %d, want 2", sp.TotalResults)
+	}
+
+	// FilterOffline runs first, so the offline hosts are never resolved.
+	if len(resolved) != 2 {
+		t.Errorf("ResolveHostnames resolved %v, want only the 2 online hosts", resolved)
+	}
+}
+
+func TestResolveHostnames(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		active   int
+		maxSeen  int
+		resolved []string
+	)
+
+	resolve := func(ctx context.Context, addr string) ([]string, error) {
+		mu.Lock()
+		active++
+		if active > maxSeen {
+			maxSeen = active
+		}
+		resolved = append(resolved, addr)
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+
+		switch addr {
+		case "10.0.0.1":
+			time.Sleep(10 * time.Millisecond)
+			return []string{"gw.example.com."}, nil
+		case "10.0.0.2":
+			time.Sleep(10 * time.Millisecond)
+			return nil, errors.New("no PTR record")
+		default:
+			// The lookup never completes, so it is given up once it times out.
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+	}
+
+	results := map[string]subping.Result{
+		"10.0.0.1": {PacketsSent: 1, PacketsRecv: 1},
+		"10.0.0.2": {PacketsSent: 1, PacketsRecv: 1},
+		"10.0.0.3": {PacketsSent: 1, PacketsRecv: 1},
+		"10.0.0.4": {PacketsSent: 1},
+	}
+
+	p := subping.ResolveHostnames{Resolve: resolve, Workers: 2, Timeout: 100 * time.Millisecond, OnlineOnly: true}
+	got := p.Process(results)
+
+	want := map[string]string{"10.0.0.1": "gw.example.com", "10.0.0.2": "", "10.0.0.3": "", "10.0.0.4": ""}
+	for ip, hostname := range want {
+		if got[ip].Hostname != hostname {
+			t.Errorf("Hostname of %s = %q, want %q", ip, got[ip].Hostname, hostname)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(resolved) != 3 {
+		t.Errorf("ResolveHostnames resolved %v, want only the 3 online hosts", resolved)
+	}
+
+	if maxSeen > 2 {
+		t.Errorf("ResolveHostnames ran %d lookups at once, want at most 2", maxSeen)
+	}
+}
+
+func TestResultProcessorFunc(t *testing.T) {
+	var order []string
+
+	step := func(name string) subping.ResultProcessor {
+		return subping.ResultProcessorFunc(func(results map[string]subping.Result) map[string]subping.Result {
+			order = append(order, name)
+
+			return results
+		})
+	}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/31",
+		Count:      1,
+		MaxWorkers: 1,
+		Pinger:     ping.NewMockPinger(nil),
+		Processors: []subping.ResultProcessor{step("first"), step("second")},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	if want := []string{"first", "second"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("processors ran in order %v, want %v", order, want)
+	}
+}
@@ -0,0 +1,42 @@
+package subping
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// newLimiter returns the limiter pacing the packets to limit per second, or nil when limit is zero. Its burst is large
+// enough for the biggest ping of a scan, of count or confirmCount packets, so that no ping is ever refused.
+func newLimiter(limit, count, confirmCount int) *rate.Limiter {
+	if limit == 0 {
+		return nil
+	}
+
+	burst := limit
+	for _, n := range []int{count, confirmCount} {
+		if n > burst {
+			burst = n
+		}
+	}
+
+	return rate.NewLimiter(rate.Limit(limit), burst)
+}
+
+// waitForRate blocks the calling worker until packets more packets can be sent within RateLimit. Once ctx is done,
+// it gives the packets back to the limiter and returns ctx.Err().
+func (s *Subping) waitForRate(ctx context.Context, packets int) error {
+	if s.limiter == nil {
+		return nil
+	}
+
+	now := s.now()
+	reservation := s.limiter.ReserveN(now, packets)
+	if delay := reservation.DelayFrom(now); !s.sleep(ctx, delay) {
+		reservation.CancelAt(s.now())
+
+		return ctx.Err()
+	}
+
+	return nil
+}
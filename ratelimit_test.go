@@ -0,0 +1,108 @@
+package subping_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		rateLimit int
+		count     int
+		want      time.Duration
+	}{
+		{name: "Unlimited", rateLimit: 0, count: 1, want: 0},
+		// The first 5 packets use the burst, the 15 others are paced at 5 per second.
+		{name: "One packet per host", rateLimit: 5, count: 1, want: 3 * time.Second},
+		{name: "Several packets per host", rateLimit: 10, count: 2, want: 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Unix(0, 0)
+			clock := &recordingClock{now: start}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     "10.0.0.0/28",
+				Subnets:    []string{"10.0.1.0/30"},
+				Count:      tt.count,
+				MaxWorkers: 1,
+				RateLimit:  tt.rateLimit,
+				Clock:      clock,
+				Pinger:     ping.NewMockPinger(nil),
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			if len(sp.Results) != 20 {
+				t.Errorf("Run() produced %d results, want 20", len(sp.Results))
+			}
+
+			if got := clock.Now().Sub(start); got != tt.want {
+				t.Errorf("Run() took %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pinged atomic.Int64
+
+	// The real clock is used: the workers waiting for the rate limit are interrupted.
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/24",
+		Count:      1,
+		MaxWorkers: 4,
+		RateLimit:  1,
+		Pinger:     ping.NewMockPinger(nil),
+		OnResult: func(string, subping.Result) {
+			if pinged.Add(1) == 1 {
+				cancel()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- sp.RunContext(ctx) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("RunContext() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("RunContext() did not return once interrupted")
+	}
+
+	// Only the first target fits in the burst; the targets given up while waiting have no result.
+	if len(sp.Results) != 1 {
+		t.Errorf("RunContext() produced %d results, want 1", len(sp.Results))
+	}
+}
+
+func TestRateLimitValidation(t *testing.T) {
+	_, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 1,
+		RateLimit:  -1,
+	})
+	if err == nil {
+		t.Error("NewSubping() with a negative rate limit did not fail")
+	}
+}
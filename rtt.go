@@ -0,0 +1,63 @@
+package subping
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RTTUnit is the unit used to render round-trip times.
+type RTTUnit string
+
+const (
+	// RTTUnitAuto renders round-trip times with time.Duration.String, which picks the unit automatically.
+	RTTUnitAuto RTTUnit = "auto"
+
+	// RTTUnitNanoseconds renders round-trip times as a number of nanoseconds.
+	RTTUnitNanoseconds RTTUnit = "ns"
+
+	// RTTUnitMicroseconds renders round-trip times as a number of microseconds.
+	RTTUnitMicroseconds RTTUnit = "us"
+
+	// RTTUnitMilliseconds renders round-trip times as a number of milliseconds.
+	RTTUnitMilliseconds RTTUnit = "ms"
+
+	// RTTUnitSeconds renders round-trip times as a number of seconds.
+	RTTUnitSeconds RTTUnit = "s"
+)
+
+// ParseRTTUnit parses an RTT unit name. An empty string is parsed as RTTUnitAuto.
+func ParseRTTUnit(s string) (RTTUnit, error) {
+	switch u := RTTUnit(s); u {
+	case "":
+		return RTTUnitAuto, nil
+	case RTTUnitAuto, RTTUnitNanoseconds, RTTUnitMicroseconds, RTTUnitMilliseconds, RTTUnitSeconds:
+		return u, nil
+	default:
+		return "", fmt.Errorf("invalid RTT unit %q, expected one of auto, ns, us, ms, s", s)
+	}
+}
+
+// Format renders d in the unit u. Fixed units render a plain number with three decimals and no suffix,
+// e.g. "1.500" for 1.5ms in RTTUnitMilliseconds, while RTTUnitAuto renders d.String().
+func (u RTTUnit) Format(d time.Duration) string {
+	if u == RTTUnitAuto || u == "" {
+		return d.String()
+	}
+
+	return strconv.FormatFloat(float64(d)/float64(u.duration()), 'f', 3, 64)
+}
+
+// duration returns the length of one u. RTTUnitAuto and unknown units default to milliseconds.
+func (u RTTUnit) duration() time.Duration {
+	switch u {
+	case RTTUnitNanoseconds:
+		return time.Nanosecond
+	case RTTUnitMicroseconds:
+		return time.Microsecond
+	case RTTUnitSeconds:
+		return time.Second
+	default:
+		return time.Millisecond
+	}
+}
@@ -0,0 +1,95 @@
+package subping_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+)
+
+func TestRTTUnitFormat(t *testing.T) {
+	d := 1500 * time.Microsecond
+
+	tests := []struct {
+		name    string
+		unit    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Default is auto",
+			unit: "",
+			want: "1.5ms",
+		},
+		{
+			name: "Auto",
+			unit: "auto",
+			want: "1.5ms",
+		},
+		{
+			name: "Nanoseconds",
+			unit: "ns",
+			want: "1500000.000",
+		},
+		{
+			name: "Microseconds",
+			unit: "us",
+			want: "1500.000",
+		},
+		{
+			name: "Milliseconds",
+			unit: "ms",
+			want: "1.500",
+		},
+		{
+			name: "Seconds",
+			unit: "s",
+			want: "0.002",
+		},
+		{
+			name:    "Invalid unit",
+			unit:    "min",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit, err := subping.ParseRTTUnit(tt.unit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRTTUnit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := unit.Format(d); got != tt.want {
+				t.Errorf("Format() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRTTUnitValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		unit    subping.RTTUnit
+		wantErr bool
+	}{
+		{name: "Default", unit: ""},
+		{name: "Milliseconds", unit: subping.RTTUnitMilliseconds},
+		{name: "Unknown", unit: "minutes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := subping.NewSubping(&subping.Options{
+				Subnet:     "10.0.0.1/32",
+				Count:      1,
+				MaxWorkers: 1,
+				RTTUnit:    tt.unit,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
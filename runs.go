@@ -0,0 +1,44 @@
+package subping
+
+import (
+	"net"
+)
+
+// OnlineRuns returns the maximal ranges of contiguous online IP addresses of the last run, in IP order,
+// as start/end pairs. A single online host between two offline ones forms a run whose start and end are equal.
+// It summarizes densely-allocated subnets, e.g. ".10-.25 are all up".
+func (s *Subping) OnlineRuns() [][2]net.IP {
+	online, _ := s.GetOnlineHosts()
+
+	var runs [][2]net.IP
+	for _, addr := range sortIPs(online) {
+		ip := net.ParseIP(addr)
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+
+		if last := len(runs) - 1; last >= 0 && nextIP(runs[last][1]).Equal(ip) {
+			runs[last][1] = ip
+			continue
+		}
+
+		runs = append(runs, [2]net.IP{ip, ip})
+	}
+
+	return runs
+}
+
+// nextIP returns the IP address following ip. The address following the last address wraps around.
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+
+	return next
+}
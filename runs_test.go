@@ -0,0 +1,46 @@
+package subping_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestOnlineRuns(t *testing.T) {
+	hosts := make(map[string]ping.MockHostConfig)
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.7", "10.0.0.10", "10.0.0.11"} {
+		hosts[ip] = ping.MockHostConfig{Online: true, Latency: time.Millisecond}
+	}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/28",
+		Count:      1,
+		MaxWorkers: 4,
+		Pinger:     ping.NewMockPinger(hosts),
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	want := [][2]string{
+		{"10.0.0.1", "10.0.0.3"},
+		{"10.0.0.7", "10.0.0.7"},
+		{"10.0.0.10", "10.0.0.11"},
+	}
+
+	got := sp.OnlineRuns()
+	if len(got) != len(want) {
+		t.Fatalf("OnlineRuns() = %v, want %v", got, want)
+	}
+
+	for i, run := range got {
+		if !run[0].Equal(net.ParseIP(want[i][0])) || !run[1].Equal(net.ParseIP(want[i][1])) {
+			t.Errorf("OnlineRuns()[%d] = %v-%v, want %s-%s", i, run[0], run[1], want[i][0], want[i][1])
+		}
+	}
+}
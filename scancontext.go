@@ -0,0 +1,99 @@
+package subping
+
+import (
+	"net"
+	"time"
+
+	"github.com/fadhilyori/subping/pkg/network"
+)
+
+// ScanContext describes the network environment a scan ran in, for network audits.
+// The fields that could not be determined are left empty.
+type ScanContext struct {
+	// Interface is the name of the interface used to reach the scanned subnet.
+	Interface string `json:"interface"`
+
+	// InterfaceIP is the IP address of Interface used to reach the scanned subnet.
+	InterfaceIP string `json:"interface_ip"`
+
+	// InterfaceMAC is the MAC address of Interface.
+	InterfaceMAC string `json:"interface_mac"`
+
+	// GatewayIP is the IP address of the IPv4 default gateway.
+	GatewayIP string `json:"gateway_ip"`
+
+	// GatewayMAC is the MAC address of the default gateway, read from the ARP table.
+	GatewayMAC string `json:"gateway_mac"`
+
+	// Timestamp is the time the last run started, or the current time when no run was made.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ScanContext returns the interface used to reach the scanned subnet with its IP and MAC addresses,
+// the default gateway with its MAC address, and the time the last run started.
+// The default gateway is only looked up on Linux, from the kernel routing and ARP tables.
+func (s *Subping) ScanContext() ScanContext {
+	ctx := ScanContext{Timestamp: s.startedAt}
+	if ctx.Timestamp.IsZero() {
+		ctx.Timestamp = time.Now()
+	}
+
+	if local, err := localAddrTo(s.TargetsIterator.FirstIP); err != nil {
+		s.logger.Debug("Failed to find the local address used to reach the subnet.", "error", err)
+	} else {
+		ctx.InterfaceIP = local.String()
+
+		if iface := interfaceWithIP(local); iface != nil {
+			ctx.Interface = iface.Name
+			ctx.InterfaceMAC = iface.HardwareAddr.String()
+		}
+	}
+
+	_, gateway, mac, err := network.DefaultGateway()
+	if err != nil {
+		s.logger.Debug("Failed to find the default gateway.", "error", err)
+	}
+
+	if gateway != nil {
+		ctx.GatewayIP = gateway.String()
+	}
+
+	ctx.GatewayMAC = mac.String()
+
+	return ctx
+}
+
+// localAddrTo returns the local IP address the system would use to send packets to ip.
+// No packet is sent: connecting a UDP socket only selects the route.
+func localAddrTo(ip net.IP) (net.IP, error) {
+	conn, err := net.Dial("udp", net.JoinHostPort(ip.String(), "9"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+// interfaceWithIP returns the network interface holding ip, or nil if there is none.
+func interfaceWithIP(ip net.IP) *net.Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	for i := range ifaces {
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return &ifaces[i]
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package subping_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+)
+
+func TestScanContext(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "127.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 1,
+		Pinger:     &stubPinger{},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	before := time.Now()
+	sp.Run()
+
+	ctx := sp.ScanContext()
+
+	if ctx.InterfaceIP != "127.0.0.1" {
+		t.Errorf("ScanContext() InterfaceIP = %q, want the loopback address", ctx.InterfaceIP)
+	}
+
+	if ctx.Interface == "" {
+		t.Errorf("ScanContext() Interface is empty, want the loopback interface")
+	}
+
+	if ctx.Timestamp.Before(before) || ctx.Timestamp.After(time.Now()) {
+		t.Errorf("ScanContext() Timestamp = %v, want the start of the run", ctx.Timestamp)
+	}
+
+	var buf bytes.Buffer
+	f := &subping.JSONFormatter{Context: &ctx}
+	if err := f.Format(&buf, sp.SortedResults(), sp.Summary()); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	var doc struct {
+		Context *subping.ScanContext `json:"context"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Format() wrote invalid JSON: %v\n%s", err, buf.String())
+	}
+
+	if doc.Context == nil || doc.Context.InterfaceIP != ctx.InterfaceIP || !doc.Context.Timestamp.Equal(ctx.Timestamp) {
+		t.Errorf("Format() context = %+v, want %+v", doc.Context, ctx)
+	}
+}
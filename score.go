@@ -0,0 +1,89 @@
+package subping
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// defaultScoreLatencyReference is the RTT at which the latency factor of the score halves.
+	defaultScoreLatencyReference = 100 * time.Millisecond
+)
+
+// ScoreWeights configures how the reachability score of a host is computed.
+//
+// The score is 100 * delivery^LossWeight * latencyFactor^LatencyWeight, where delivery is the
+// fraction of packets that were answered and latencyFactor is LatencyReference / (LatencyReference + AvgRtt).
+type ScoreWeights struct {
+	// LossWeight is the exponent applied to the delivery ratio. Zero defaults to 1.
+	LossWeight float64 `json:"loss_weight"`
+
+	// LatencyWeight is the exponent applied to the latency factor. Zero defaults to 1.
+	LatencyWeight float64 `json:"latency_weight"`
+
+	// LatencyReference is the RTT at which the latency factor equals 0.5. Zero defaults to 100ms.
+	LatencyReference time.Duration `json:"latency_reference"`
+}
+
+// validate returns an error when a weight or the latency reference is negative.
+func (w ScoreWeights) validate() error {
+	if w.LossWeight < 0 || w.LatencyWeight < 0 {
+		return errors.New("score weights cannot be negative")
+	}
+
+	if w.LatencyReference < 0 {
+		return errors.New("score latency reference cannot be negative")
+	}
+
+	return nil
+}
+
+// withDefaults returns a copy of w with the zero fields replaced by their default values.
+func (w ScoreWeights) withDefaults() ScoreWeights {
+	if w.LossWeight == 0 {
+		w.LossWeight = 1
+	}
+
+	if w.LatencyWeight == 0 {
+		w.LatencyWeight = 1
+	}
+
+	if w.LatencyReference <= 0 {
+		w.LatencyReference = defaultScoreLatencyReference
+	}
+
+	return w
+}
+
+// Score computes the reachability score of a ping result using the given weights.
+// A host that did not reply scores 0, and a host with no loss and no latency scores 100.
+func Score(r Result, w ScoreWeights) float64 {
+	if r.PacketsRecv == 0 {
+		return 0
+	}
+
+	w = w.withDefaults()
+
+	delivery := 1 - r.PacketLoss/100
+	if delivery < 0 {
+		delivery = 0
+	}
+
+	latencyFactor := float64(w.LatencyReference) / float64(w.LatencyReference+r.AvgRtt)
+
+	return 100 * math.Pow(delivery, w.LossWeight) * math.Pow(latencyFactor, w.LatencyWeight)
+}
+
+// ByScore returns the IP addresses of results sorted by descending score.
+// Hosts with the same score are ordered by IP address.
+func ByScore(results map[string]Result) []string {
+	ips := sortIPs(results)
+
+	sort.SliceStable(ips, func(i, j int) bool {
+		return results[ips[i]].Score > results[ips[j]].Score
+	})
+
+	return ips
+}
@@ -0,0 +1,101 @@
+package subping_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  subping.Result
+		weights subping.ScoreWeights
+		want    float64
+	}{
+		{
+			name:   "0% loss without latency",
+			result: subping.Result{PacketsSent: 4, PacketsRecv: 4},
+			want:   100,
+		},
+		{
+			name:   "0% loss at the latency reference",
+			result: subping.Result{AvgRtt: 100 * time.Millisecond, PacketsSent: 4, PacketsRecv: 4},
+			want:   50,
+		},
+		{
+			name:   "100% loss",
+			result: subping.Result{PacketLoss: 100, PacketsSent: 4},
+			want:   0,
+		},
+		{
+			name:   "50% loss without latency",
+			result: subping.Result{PacketLoss: 50, PacketsSent: 4, PacketsRecv: 2},
+			want:   50,
+		},
+		{
+			name:    "50% loss with double loss weight",
+			result:  subping.Result{PacketLoss: 50, PacketsSent: 4, PacketsRecv: 2},
+			weights: subping.ScoreWeights{LossWeight: 2},
+			want:    25,
+		},
+		{
+			name:    "0% loss with custom latency reference",
+			result:  subping.Result{AvgRtt: 10 * time.Millisecond, PacketsSent: 4, PacketsRecv: 4},
+			weights: subping.ScoreWeights{LatencyReference: 10 * time.Millisecond},
+			want:    50,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subping.Score(tt.result, tt.weights); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByScore(t *testing.T) {
+	results := map[string]subping.Result{
+		"10.0.0.3": {Score: 10},
+		"10.0.0.1": {Score: 90},
+		"10.0.0.2": {Score: 10},
+		"10.0.0.4": {Score: 0},
+	}
+
+	want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+
+	if got := subping.ByScore(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("ByScore() = %v, want %v", got, want)
+	}
+}
+
+func TestScoreWeightsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights subping.ScoreWeights
+		wantErr bool
+	}{
+		{name: "Default", weights: subping.ScoreWeights{}},
+		{name: "Custom", weights: subping.ScoreWeights{LossWeight: 2, LatencyWeight: 0.5, LatencyReference: time.Second}},
+		{name: "Negative loss weight", weights: subping.ScoreWeights{LossWeight: -1}, wantErr: true},
+		{name: "Negative latency weight", weights: subping.ScoreWeights{LatencyWeight: -1}, wantErr: true},
+		{name: "Negative latency reference", weights: subping.ScoreWeights{LatencyReference: -time.Second}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := subping.NewSubping(&subping.Options{
+				Subnet:       "10.0.0.1/32",
+				Count:        1,
+				MaxWorkers:   1,
+				ScoreWeights: tt.weights,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package subping
+
+import "time"
+
+// SmoothedRTT returns the exponential moving average of the RTT of each host that replied at least once across the
+// rounds of the current or last Watch, keyed by normalized IP address: a more stable latency signal for dashboards
+// than the RTT of any single round. Each round that a host replies in moves its average towards the RTT of the
+// round by RTTSmoothingFactor; the rounds it does not reply in leave its average unchanged.
+// It is safe to call while Watch is running.
+func (s *Subping) SmoothedRTT() map[string]time.Duration {
+	s.smoothedRTTMu.Lock()
+	defer s.smoothedRTTMu.Unlock()
+
+	smoothed := make(map[string]time.Duration, len(s.smoothedRTT))
+	for ip, rtt := range s.smoothedRTT {
+		smoothed[ip] = rtt
+	}
+
+	return smoothed
+}
+
+// resetSmoothedRTT forgets the moving averages of a previous Watch.
+func (s *Subping) resetSmoothedRTT() {
+	s.smoothedRTTMu.Lock()
+	defer s.smoothedRTTMu.Unlock()
+
+	s.smoothedRTT = make(map[string]time.Duration)
+}
+
+// updateSmoothedRTT moves the moving average of each host that replied in results towards its RTT.
+// The first RTT of a host initializes its average.
+func (s *Subping) updateSmoothedRTT(results map[string]Result) {
+	s.smoothedRTTMu.Lock()
+	defer s.smoothedRTTMu.Unlock()
+
+	for ip, r := range results {
+		if r.PacketsRecv == 0 {
+			continue
+		}
+
+		previous, ok := s.smoothedRTT[ip]
+		if !ok {
+			s.smoothedRTT[ip] = r.AvgRtt
+			continue
+		}
+
+		s.smoothedRTT[ip] = previous + time.Duration(s.RTTSmoothingFactor*float64(r.AvgRtt-previous))
+	}
+}
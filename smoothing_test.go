@@ -0,0 +1,111 @@
+package subping_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// latencyPinger is a ping.Pinger replying with a per-target sequence of RTTs, one entry per call.
+// Zero entries, targets without a sequence and calls past its end are offline.
+type latencyPinger struct {
+	mu    sync.Mutex
+	rtts  map[string][]time.Duration
+	calls map[string]int
+}
+
+func (p *latencyPinger) Ping(target string, opts ping.Options) (ping.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	call := p.calls[target]
+	p.calls[target]++
+
+	if seq := p.rtts[target]; call < len(seq) && seq[call] > 0 {
+		return ping.Result{AvgRtt: seq[call], PacketsSent: opts.Count, PacketsRecv: opts.Count}, nil
+	}
+
+	return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+}
+
+func TestSmoothedRTT(t *testing.T) {
+	const rounds = 12
+
+	// 10.0.0.1 jumps from 100ms to a steady 20ms, 10.0.0.2 misses a round, and 10.0.0.3 never replies.
+	steady := make([]time.Duration, rounds)
+	for i := range steady {
+		steady[i] = 20 * time.Millisecond
+	}
+	steady[0] = 100 * time.Millisecond
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:             "10.0.0.0/30",
+		Count:              1,
+		MaxWorkers:         2,
+		RTTSmoothingFactor: 0.5,
+		Pinger: &latencyPinger{
+			rtts: map[string][]time.Duration{
+				"10.0.0.1": steady,
+				"10.0.0.2": {10 * time.Millisecond, 0, 30 * time.Millisecond},
+			},
+			calls: make(map[string]int),
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	for range sp.Watch(context.Background(), time.Millisecond, rounds) {
+	}
+
+	smoothed := sp.SmoothedRTT()
+
+	// After 11 rounds at 20ms, 0.5^11 of the initial 80ms gap is left.
+	if got := smoothed["10.0.0.1"]; got < 20*time.Millisecond || got > 20*time.Millisecond+50*time.Microsecond {
+		t.Errorf("SmoothedRTT()[10.0.0.1] = %v, want it converged to 20ms", got)
+	}
+
+	// The missed round leaves the average unchanged: 10ms, then halfway to 30ms.
+	if got := smoothed["10.0.0.2"]; got != 20*time.Millisecond {
+		t.Errorf("SmoothedRTT()[10.0.0.2] = %v, want 20ms", got)
+	}
+
+	if got, ok := smoothed["10.0.0.3"]; ok {
+		t.Errorf("SmoothedRTT()[10.0.0.3] = %v, want no average for a host that never replied", got)
+	}
+}
+
+func TestRTTSmoothingFactorValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		factor  float64
+		wantErr bool
+	}{
+		{name: "Default", factor: 0},
+		{name: "Latest round only", factor: 1},
+		{name: "Negative", factor: -0.1, wantErr: true},
+		{name: "Greater than 1", factor: 1.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:             "10.0.0.0/30",
+				Count:              1,
+				MaxWorkers:         1,
+				RTTSmoothingFactor: tt.factor,
+				Pinger:             ping.NewMockPinger(nil),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && tt.factor == 0 && sp.RTTSmoothingFactor != subping.DefaultRTTSmoothingFactor {
+				t.Errorf("RTTSmoothingFactor = %v, want the default %v", sp.RTTSmoothingFactor, subping.DefaultRTTSmoothingFactor)
+			}
+		})
+	}
+}
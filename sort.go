@@ -0,0 +1,73 @@
+package subping
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+)
+
+// SortOrder is the order in which SortHostResults sorts the results.
+type SortOrder string
+
+const (
+	// SortByIP sorts the results by ascending IP address.
+	SortByIP SortOrder = "ip"
+
+	// SortByLatency sorts the results by ascending average RTT, the offline hosts last.
+	SortByLatency SortOrder = "latency"
+
+	// SortByLoss sorts the results by ascending packet loss, so the hosts losing the most packets come last.
+	SortByLoss SortOrder = "loss"
+)
+
+// ParseSortOrder returns the SortOrder named s, or an error if there is none.
+func ParseSortOrder(s string) (SortOrder, error) {
+	switch order := SortOrder(s); order {
+	case SortByIP, SortByLatency, SortByLoss:
+		return order, nil
+	default:
+		return "", fmt.Errorf("invalid sort order %q, must be one of %s, %s or %s", s, SortByIP, SortByLatency, SortByLoss)
+	}
+}
+
+// SortHostResults sorts results in the given order. The results that tie are sorted by IP address, so the order
+// does not depend on the order of results.
+func SortHostResults(results []HostResult, order SortOrder) {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i].Result, results[j].Result
+
+		switch order {
+		case SortByLatency:
+			aOnline, bOnline := a.PacketsRecv > 0, b.PacketsRecv > 0
+			if aOnline != bOnline {
+				return aOnline
+			}
+
+			if aOnline && a.AvgRtt != b.AvgRtt {
+				return a.AvgRtt < b.AvgRtt
+			}
+		case SortByLoss:
+			if a.PacketLoss != b.PacketLoss {
+				return a.PacketLoss < b.PacketLoss
+			}
+		}
+
+		return ipLess(results[i].IP, results[j].IP)
+	})
+}
+
+// ipLess reports whether the IP address a sorts before b. The hosts that are not IP addresses, e.g. the unresolved
+// host names of SortedResults, come last and are compared as strings.
+func ipLess(a, b string) bool {
+	aIP, bIP := net.ParseIP(a).To16(), net.ParseIP(b).To16()
+	if (aIP == nil) != (bIP == nil) {
+		return aIP != nil
+	}
+
+	if c := bytes.Compare(aIP, bIP); c != 0 {
+		return c < 0
+	}
+
+	return a < b
+}
@@ -0,0 +1,90 @@
+package subping_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+)
+
+func TestSortHostResults(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/29",
+		Count:      4,
+		MaxWorkers: 2,
+		Pinger: &stubPinger{online: map[string]time.Duration{
+			"10.0.0.1": 30 * time.Millisecond,
+			"10.0.0.3": 10 * time.Millisecond,
+			"10.0.0.5": 20 * time.Millisecond,
+			"10.0.0.6": 10 * time.Millisecond,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	// 10.0.0.5 loses half of its packets.
+	lossy := sp.Results["10.0.0.5"]
+	lossy.PacketLoss = 50
+	sp.Results["10.0.0.5"] = lossy
+
+	tests := []struct {
+		order subping.SortOrder
+		want  []string
+	}{
+		{
+			order: subping.SortByIP,
+			want:  []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7"},
+		},
+		{
+			order: subping.SortByLatency,
+			want:  []string{"10.0.0.3", "10.0.0.6", "10.0.0.5", "10.0.0.1", "10.0.0.0", "10.0.0.2", "10.0.0.4", "10.0.0.7"},
+		},
+		{
+			order: subping.SortByLoss,
+			want:  []string{"10.0.0.1", "10.0.0.3", "10.0.0.6", "10.0.0.5", "10.0.0.0", "10.0.0.2", "10.0.0.4", "10.0.0.7"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			// The results are reversed first, so the ties cannot keep the IP address order of SortedResults.
+			results := sp.SortedResults()
+			for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+				results[i], results[j] = results[j], results[i]
+			}
+
+			subping.SortHostResults(results, tt.order)
+
+			var got []string
+			for _, host := range results {
+				got = append(got, host.IP)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortHostResults() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    subping.SortOrder
+		wantErr bool
+	}{
+		{s: "ip", want: subping.SortByIP},
+		{s: "latency", want: subping.SortByLatency},
+		{s: "loss", want: subping.SortByLoss},
+		{s: "rtt", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := subping.ParseSortOrder(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSortOrder(%q) = %q, %v, want %q, wantErr %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
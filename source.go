@@ -0,0 +1,96 @@
+package subping
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// source is a local source address together with the subnet it is attached to.
+type source struct {
+	ip     net.IP
+	subnet *net.IPNet
+}
+
+// parseSources parses source addresses written in CIDR notation, e.g. "192.168.10.1/24", where the prefix
+// is the subnet the address is attached to. A bare IP address is attached to itself only.
+func parseSources(addrs []string) ([]source, error) {
+	sources := make([]source, 0, len(addrs))
+
+	for _, addr := range addrs {
+		if !strings.Contains(addr, "/") {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid source address %q", addr)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+
+			addr = fmt.Sprintf("%s/%d", addr, bits)
+		}
+
+		ip, subnet, err := net.ParseCIDR(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source address %q: %w", addr, err)
+		}
+
+		sources = append(sources, source{ip: ip, subnet: subnet})
+	}
+
+	return sources, nil
+}
+
+// selectSource returns the source address to ping target from: the source whose subnet contains target,
+// the most specific one if several do, or else the source sharing the longest prefix with target.
+// Sources of the other IP family are never selected. It returns an empty string when no source fits.
+func selectSource(target net.IP, sources []source) string {
+	var (
+		best      string
+		bestScore = -1
+	)
+
+	for _, src := range sources {
+		if (src.ip.To4() != nil) != (target.To4() != nil) {
+			continue
+		}
+
+		// A containing subnet always beats a mere common prefix, whose length is at most 128.
+		score := commonPrefixLen(src.ip, target)
+		if src.subnet.Contains(target) {
+			ones, _ := src.subnet.Mask.Size()
+			score = 256 + ones
+		}
+
+		if score > bestScore {
+			best, bestScore = src.ip.String(), score
+		}
+	}
+
+	return best
+}
+
+// commonPrefixLen returns the number of leading bits a and b have in common.
+func commonPrefixLen(a, b net.IP) int {
+	a, b = a.To16(), b.To16()
+
+	n := 0
+	for i := range a {
+		x := a[i] ^ b[i]
+		if x == 0 {
+			n += 8
+			continue
+		}
+
+		for x&0x80 == 0 {
+			n++
+			x <<= 1
+		}
+
+		break
+	}
+
+	return n
+}
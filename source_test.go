@@ -0,0 +1,107 @@
+package subping_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// sourcePinger is a ping.Pinger that records the source address each target is pinged from.
+type sourcePinger struct {
+	mu      sync.Mutex
+	sources map[string]string
+}
+
+func (p *sourcePinger) Ping(target string, opts ping.Options) (ping.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sources[target] = opts.Source
+
+	return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+}
+
+func TestSourceSelection(t *testing.T) {
+	// routes is the stub route table of a router with an interface in two VLANs and a more specific subnet.
+	routes := []string{"10.0.0.1/16", "10.0.1.1/24", "192.168.7.1/24", "2001:db8::1/64"}
+
+	tests := []struct {
+		name   string
+		subnet string
+		want   map[string]string
+	}{
+		{
+			name:   "Most specific containing subnet",
+			subnet: "10.0.1.8/30",
+			want: map[string]string{
+				"10.0.1.8":  "10.0.1.1",
+				"10.0.1.9":  "10.0.1.1",
+				"10.0.1.10": "10.0.1.1",
+				"10.0.1.11": "10.0.1.1",
+			},
+		},
+		{
+			name:   "Containing subnet",
+			subnet: "10.0.2.0/31",
+			want: map[string]string{
+				"10.0.2.0": "10.0.0.1",
+				"10.0.2.1": "10.0.0.1",
+			},
+		},
+		{
+			name:   "Closest source when no subnet contains the target",
+			subnet: "192.168.8.0/31",
+			want: map[string]string{
+				"192.168.8.0": "192.168.7.1",
+				"192.168.8.1": "192.168.7.1",
+			},
+		},
+		{
+			name:   "Source of the same IP family",
+			subnet: "2001:db8:1::/127",
+			want: map[string]string{
+				"2001:db8:1::":  "2001:db8::1",
+				"2001:db8:1::1": "2001:db8::1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := &sourcePinger{sources: make(map[string]string)}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     tt.subnet,
+				Count:      1,
+				MaxWorkers: 2,
+				Pinger:     pinger,
+				Sources:    routes,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			for target, want := range tt.want {
+				if got := pinger.sources[target]; got != want {
+					t.Errorf("source of %s = %q, want %q", target, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidSource(t *testing.T) {
+	_, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 1,
+		Pinger:     &stubPinger{},
+		Sources:    []string{"not-an-ip"},
+	})
+	if err == nil {
+		t.Error("NewSubping() error = nil, want an error for an invalid source")
+	}
+}
@@ -0,0 +1,78 @@
+package subping
+
+import "sync"
+
+// ResultStore holds the results of a scan, keyed by IP address, see Options.ResultStore. Implementations must be safe
+// for concurrent use, since the workers store their results concurrently.
+type ResultStore interface {
+	// Store records the result of ip, replacing its previous result.
+	Store(ip string, result Result)
+
+	// Range calls fn for each stored result, in no particular order, until fn returns false.
+	Range(fn func(ip string, result Result) bool)
+}
+
+// memoryStore is the ResultStore holding the results in memory, used when Options.ResultStore is nil.
+type memoryStore struct {
+	m sync.Map
+}
+
+// Store records the result of ip.
+func (s *memoryStore) Store(ip string, result Result) {
+	s.m.Store(ip, result)
+}
+
+// Range calls fn for each stored result until fn returns false.
+func (s *memoryStore) Range(fn func(ip string, result Result) bool) {
+	s.m.Range(func(key, value any) bool {
+		return fn(key.(string), value.(Result))
+	})
+}
+
+// setResults replaces Results with results, which must not be modified afterwards, and clears the store of the scan
+// that produced them.
+func (s *Subping) setResults(results map[string]Result) {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+
+	s.Results = results
+	s.live = nil
+}
+
+// setLive makes store the store of the scan in progress, read by snapshot.
+func (s *Subping) setLive(store ResultStore) {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+
+	s.live = store
+}
+
+// snapshot returns a copy of Results merged, like a scan retry, with the results stored so far by the scan in
+// progress. It is consistent with the writes of Run and Watch, so it can be called while they are in progress.
+func (s *Subping) snapshot() map[string]Result {
+	s.resultsMu.RLock()
+	defer s.resultsMu.RUnlock()
+
+	results := copyResults(s.Results)
+	if s.live != nil {
+		live := make(map[string]Result)
+		s.live.Range(func(ip string, result Result) bool {
+			live[ip] = result
+			return true
+		})
+
+		results = mergeBestResults(results, live)
+	}
+
+	return results
+}
+
+// copyResults returns a copy of results, so it can be modified without affecting the readers of results.
+func copyResults(results map[string]Result) map[string]Result {
+	c := make(map[string]Result, len(results))
+	for ip, result := range results {
+		c[ip] = result
+	}
+
+	return c
+}
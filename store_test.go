@@ -0,0 +1,177 @@
+package subping_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// countingStore is a subping.ResultStore keeping only the number of online and offline hosts, as a store writing the
+// results to disk would keep none of them in memory.
+type countingStore struct {
+	mu      sync.Mutex
+	online  int
+	offline int
+	ranged  int
+}
+
+func (s *countingStore) Store(_ string, result subping.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if result.PacketsRecv > 0 {
+		s.online++
+	} else {
+		s.offline++
+	}
+}
+
+func (s *countingStore) Range(func(string, subping.Result) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ranged++
+}
+
+func TestResultStore(t *testing.T) {
+	store := &countingStore{}
+	processed := false
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:      "10.0.0.0/28",
+		Count:       1,
+		MaxWorkers:  4,
+		ResultStore: store,
+		Pinger: ping.NewMockPinger(map[string]ping.MockHostConfig{
+			"10.0.0.1": {Online: true, Latency: time.Millisecond},
+			"10.0.0.9": {Online: true, Latency: time.Millisecond},
+		}),
+		Processors: []subping.ResultProcessor{
+			subping.ResultProcessorFunc(func(results map[string]subping.Result) map[string]subping.Result {
+				processed = true
+
+				return results
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	if store.online != 2 || store.offline != 14 {
+		t.Errorf("ResultStore got %d online and %d offline hosts, want 2 and 14", store.online, store.offline)
+	}
+
+	if len(sp.Results) != 0 {
+		t.Errorf("Results holds %d results, want none since they are written to the ResultStore", len(sp.Results))
+	}
+
+	if sp.TotalResults != 16 {
+		t.Errorf("TotalResults = %d, want 16", sp.TotalResults)
+	}
+
+	if summary := sp.Summary(); summary.OnlineHosts != 2 || summary.OfflineHosts != 14 {
+		t.Errorf("Summary() = %d online and %d offline hosts, want 2 and 14", summary.OnlineHosts, summary.OfflineHosts)
+	}
+
+	if processed {
+		t.Error("Processors were applied to the results of a ResultStore")
+	}
+}
+
+// gatedPinger is a ping.Pinger on which every target replies, except that the ping of gate blocks until release is
+// closed. It closes reached once the ping of gate starts.
+type gatedPinger struct {
+	gate    string
+	reached chan struct{}
+	release chan struct{}
+}
+
+func (p *gatedPinger) Ping(target string, opts ping.Options) (ping.Result, error) {
+	if target == p.gate {
+		close(p.reached)
+		<-p.release
+	}
+
+	return ping.Result{AvgRtt: time.Millisecond, PacketsSent: opts.Count, PacketsRecv: opts.Count}, nil
+}
+
+func TestGetOnlineHostsDuringRun(t *testing.T) {
+	pinger := &gatedPinger{gate: "10.0.0.3", reached: make(chan struct{}), release: make(chan struct{})}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 1,
+		Pinger:     pinger,
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		sp.Run()
+	}()
+
+	<-pinger.reached
+
+	// The targets are pinged in order by a single worker, so the first three are done.
+	if _, online := sp.GetOnlineHosts(); online != 3 {
+		t.Errorf("GetOnlineHosts() during Run = %d online hosts, want 3", online)
+	}
+
+	if got := sp.FilterResults(func(subping.Result) bool { return true }); len(got) != 3 {
+		t.Errorf("FilterResults() during Run = %d results, want 3", len(got))
+	}
+
+	close(pinger.release)
+	<-done
+
+	if _, online := sp.GetOnlineHosts(); online != 4 {
+		t.Errorf("GetOnlineHosts() after Run = %d online hosts, want 4", online)
+	}
+}
+
+func TestResultStoreNotReadDuringRun(t *testing.T) {
+	store := &countingStore{}
+	pinger := &gatedPinger{gate: "10.0.0.3", reached: make(chan struct{}), release: make(chan struct{})}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:      "10.0.0.0/30",
+		Count:       1,
+		MaxWorkers:  1,
+		ResultStore: store,
+		Pinger:      pinger,
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		sp.Run()
+	}()
+
+	<-pinger.reached
+
+	if _, online := sp.GetOnlineHosts(); online != 0 {
+		t.Errorf("GetOnlineHosts() during Run = %d online hosts, want none read back from the ResultStore", online)
+	}
+
+	close(pinger.release)
+	<-done
+
+	if store.ranged != 0 {
+		t.Errorf("ResultStore was ranged over %d times, want never", store.ranged)
+	}
+}
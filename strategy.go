@@ -0,0 +1,37 @@
+package subping
+
+import "fmt"
+
+const (
+	// largeScanHosts is the number of hosts from which RecommendStrategy suggests a quick scan.
+	largeScanHosts = 1 << 12
+
+	// hugeScanHosts is the number of hosts from which RecommendStrategy also suggests sampling random hosts.
+	hugeScanHosts = 1 << 16
+
+	// sampleHosts is the number of random hosts RecommendStrategy suggests to sample in huge scans.
+	sampleHosts = 1000
+)
+
+// RecommendStrategy returns human advice on how to scan the configured subnets faster when they hold many hosts,
+// e.g. "10.0.0.0/16 has 65536 hosts; consider --quick to ping only the common hosts, or --shuffle
+// --max-total-packets 1000 to sample about 1000 random hosts". Scans of fewer than 4096 hosts, and scans that are
+// already quick or sampled, need no advice and get an empty string.
+func (s *Subping) RecommendStrategy() string {
+	total := s.TotalHosts()
+	if s.Quick || (s.Shuffle && s.MaxTotalPackets > 0) || total < largeScanHosts {
+		return ""
+	}
+
+	scope := fmt.Sprintf("%s has %d hosts", s.Subnets[0], total)
+	if len(s.Subnets) > 1 {
+		scope = fmt.Sprintf("these %d subnets have %d hosts", len(s.Subnets), total)
+	}
+
+	if total < hugeScanHosts {
+		return fmt.Sprintf("%s; consider --quick to ping only the common hosts", scope)
+	}
+
+	return fmt.Sprintf("%s; consider --quick to ping only the common hosts, or --shuffle --max-total-packets %d "+
+		"to sample about %d random hosts", scope, sampleHosts*s.Count, sampleHosts)
+}
@@ -0,0 +1,76 @@
+package subping_test
+
+import (
+	"testing"
+
+	"github.com/fadhilyori/subping"
+)
+
+func TestRecommendStrategy(t *testing.T) {
+	tests := []struct {
+		name string
+		opts subping.Options
+		want string
+	}{
+		{
+			name: "small subnet",
+			opts: subping.Options{Subnet: "10.0.0.0/24"},
+			want: "",
+		},
+		{
+			name: "just below the large threshold",
+			opts: subping.Options{Subnet: "10.0.0.0/21"},
+			want: "",
+		},
+		{
+			name: "large subnet",
+			opts: subping.Options{Subnet: "10.0.0.0/20"},
+			want: "10.0.0.0/20 has 4096 hosts; consider --quick to ping only the common hosts",
+		},
+		{
+			name: "huge subnet",
+			opts: subping.Options{Subnet: "10.0.0.0/16"},
+			want: "10.0.0.0/16 has 65536 hosts; consider --quick to ping only the common hosts, " +
+				"or --shuffle --max-total-packets 1000 to sample about 1000 random hosts",
+		},
+		{
+			name: "huge subnet with several packets per host",
+			opts: subping.Options{Subnet: "10.0.0.0/16", Count: 3},
+			want: "10.0.0.0/16 has 65536 hosts; consider --quick to ping only the common hosts, " +
+				"or --shuffle --max-total-packets 3000 to sample about 1000 random hosts",
+		},
+		{
+			name: "several subnets adding up",
+			opts: subping.Options{Subnet: "10.0.0.0/21", Subnets: []string{"10.1.0.0/21"}},
+			want: "these 2 subnets have 4096 hosts; consider --quick to ping only the common hosts",
+		},
+		{
+			name: "quick scan",
+			opts: subping.Options{Subnet: "10.0.0.0/16", Quick: true},
+			want: "",
+		},
+		{
+			name: "sampled scan",
+			opts: subping.Options{Subnet: "10.0.0.0/16", Shuffle: true, MaxTotalPackets: 1000},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			if opts.Count == 0 {
+				opts.Count = 1
+			}
+			opts.MaxWorkers = 1
+
+			sp, err := subping.NewSubping(&opts)
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			if got := sp.RecommendStrategy(); got != tt.want {
+				t.Errorf("RecommendStrategy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package subping
+
+import "context"
+
+// Stream is like StreamContext with a context that is never done.
+func (s *Subping) Stream() <-chan HostResult {
+	return s.StreamContext(context.Background())
+}
+
+// StreamContext scans the targets like RunContext, but sends the result of each target on the returned channel as
+// soon as it completes instead of collecting them in Results, so large subnets can be processed, e.g. displayed live,
+// without holding every result in memory. The channel is closed once every target is pinged, or once ctx is done or
+// MaxDuration is exceeded, the targets not pinged yet being skipped.
+//
+// The caller must drain the channel or cancel ctx, since the workers block until their results are received. Results
+// is left untouched, and neither the scan retries nor Processors are applied.
+// A scan aborted by a permission error closes the channel early; Err then reports the error.
+func (s *Subping) StreamContext(ctx context.Context) <-chan HostResult {
+	results := make(chan HostResult, s.MaxWorkers)
+
+	ctx, cancel := s.beginRun(ctx)
+	s.stream = results
+
+	go func() {
+		defer close(results)
+		defer cancel()
+		defer func() { s.stream = nil }()
+
+		s.scan(ctx, s.targets(s.NewIterator()))
+	}()
+
+	return results
+}
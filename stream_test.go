@@ -0,0 +1,88 @@
+package subping_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestStream(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/29",
+		Count:      1,
+		MaxWorkers: 3,
+		Labels:     map[string]map[string]string{"10.0.0.1": {"role": "gateway"}},
+		Pinger: ping.NewMockPinger(map[string]ping.MockHostConfig{
+			"10.0.0.1": {Online: true, Latency: time.Millisecond},
+			"10.0.0.5": {Online: true, Latency: 2 * time.Millisecond},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	got := make(map[string]subping.HostResult)
+	for host := range sp.Stream() {
+		if _, ok := got[host.IP]; ok {
+			t.Errorf("Stream() sent %s twice", host.IP)
+		}
+
+		got[host.IP] = host
+	}
+
+	if len(got) != 8 {
+		t.Errorf("Stream() sent %d results, want 8", len(got))
+	}
+
+	if h := got["10.0.0.1"]; h.Result.PacketsRecv != 1 || !reflect.DeepEqual(h.Labels, map[string]string{"role": "gateway"}) {
+		t.Errorf("Stream() sent %+v for 10.0.0.1, want it online with its labels", h)
+	}
+
+	if h := got["10.0.0.2"]; h.Result.PacketsRecv != 0 {
+		t.Errorf("Stream() sent %+v for 10.0.0.2, want it offline", h)
+	}
+
+	if len(sp.Results) != 0 {
+		t.Errorf("Results holds %d results, want the streamed results not to be collected", len(sp.Results))
+	}
+}
+
+func TestStreamContextCancelled(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/24",
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger:     &stubPinger{},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+	fullRun, _ := sp.TrafficStats()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := sp.StreamContext(ctx)
+
+	<-results
+	cancel()
+
+	// The workers no longer block on the undrained channel, which is closed shortly.
+	timeout := time.After(5 * time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-results:
+			closed = !ok
+		case <-timeout:
+			t.Fatal("StreamContext() did not close the channel once ctx was cancelled")
+		}
+	}
+
+	if sent, _ := sp.TrafficStats(); sent >= fullRun {
+		t.Errorf("TrafficStats() = %d bytes sent, want less than the %d bytes of the full run before", sent, fullRun)
+	}
+}
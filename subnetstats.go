@@ -0,0 +1,43 @@
+package subping
+
+// SubnetStats holds the host counts of one of the scanned subnets.
+type SubnetStats struct {
+	// Total is the number of hosts of the subnet that were pinged.
+	Total int
+
+	// Online is the number of hosts of the subnet that replied.
+	Online int
+
+	// Offline is the number of hosts of the subnet that did not reply.
+	Offline int
+}
+
+// PerSubnetStats returns the host counts of the last run broken down by scanned subnet, keyed by the canonical CIDR
+// of each subnet in Subnets. It shows which subnets of a multi-subnet scan are densely or sparsely populated.
+// Subnets without any pinged host, e.g. because every host is excluded, have zero counts.
+func (s *Subping) PerSubnetStats() map[string]SubnetStats {
+	stats := make(map[string]SubnetStats, len(s.Subnets))
+	for _, subnet := range s.Subnets {
+		stats[subnet] = SubnetStats{}
+	}
+
+	for ip, result := range s.Results {
+		subnet, ok := s.origins[ip]
+		if !ok {
+			continue
+		}
+
+		st := stats[subnet]
+		st.Total++
+
+		if result.PacketsRecv > 0 {
+			st.Online++
+		} else {
+			st.Offline++
+		}
+
+		stats[subnet] = st
+	}
+
+	return stats
+}
@@ -0,0 +1,83 @@
+package subping_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestPerSubnetStats(t *testing.T) {
+	hosts := make(map[string]ping.MockHostConfig)
+	for _, ip := range []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.5", "10.0.0.6", "192.168.1.9"} {
+		hosts[ip] = ping.MockHostConfig{Online: true, Latency: time.Millisecond}
+	}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/29",
+		Subnets:    []string{"192.168.1.0/28", "172.16.0.0/30"},
+		Count:      1,
+		MaxWorkers: 4,
+		Exclude:    []string{"172.16.0.0", "172.16.0.1", "172.16.0.2", "172.16.0.3"},
+		Pinger:     ping.NewMockPinger(hosts),
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	if sp.TotalResults != 24 {
+		t.Errorf("TotalResults = %d, want 24", sp.TotalResults)
+	}
+
+	want := map[string]subping.SubnetStats{
+		"10.0.0.0/29":    {Total: 8, Online: 6, Offline: 2},
+		"192.168.1.0/28": {Total: 16, Online: 1, Offline: 15},
+		"172.16.0.0/30":  {},
+	}
+
+	if got := sp.PerSubnetStats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PerSubnetStats() = %v, want %v", got, want)
+	}
+}
+
+func TestOverlappingSubnets(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnets []string
+	}{
+		{
+			name:    "Subnet inside the first subnet",
+			subnets: []string{"10.0.0.4/30"},
+		},
+		{
+			name:    "Subnet containing the first subnet",
+			subnets: []string{"10.0.0.0/16"},
+		},
+		{
+			name:    "Overlapping additional subnets",
+			subnets: []string{"192.168.0.0/24", "192.168.0.128/25"},
+		},
+		{
+			name:    "Invalid subnet",
+			subnets: []string{"192.168.0.0/33"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := subping.NewSubping(&subping.Options{
+				Subnet:     "10.0.0.0/29",
+				Subnets:    tt.subnets,
+				Count:      1,
+				MaxWorkers: 4,
+				Pinger:     ping.NewMockPinger(nil),
+			})
+			if err == nil {
+				t.Errorf("NewSubping() error = nil, want an error")
+			}
+		})
+	}
+}
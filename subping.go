@@ -28,19 +28,33 @@
 //	onlineHosts, total := sp.GetOnlineHosts()
 //	fmt.Printf("Online Hosts: %v\n", onlineHosts)
 //	fmt.Printf("Total Online Hosts: %d\n", total)
+//
+// Scan runs a whole scan in a single call, e.g. to run several scans concurrently:
+//
+//	results, err := subping.Scan(ctx, opts)
 package subping
 
 import (
+	"context"
 	"errors"
-	"log"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"net"
+	"os"
 	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 
 	"github.com/fadhilyori/subping/pkg/network"
-	ping "github.com/prometheus-community/pro-bing"
+	"github.com/fadhilyori/subping/pkg/ping"
+	probing "github.com/prometheus-community/pro-bing"
 )
 
 // Subping is a utility for concurrently pinging multiple IP addresses and collecting the results.
@@ -51,69 +65,514 @@ type Subping struct {
 	// Count is the number of ping requests to send for each target.
 	Count int
 
+	// ConfirmCount is the number of ping requests sent to confirm the targets that lost packets, or zero.
+	ConfirmCount int
+
 	// Interval is the time duration between each ping request.
 	Interval time.Duration
 
 	// Timeout specifies the timeout duration before exiting each target.
 	Timeout time.Duration
 
-	// BatchSize is the number of concurrent ping jobs to execute.
-	BatchSize int64
+	// Timeouts holds the per-attempt timeouts used when retrying a target.
+	// When empty, Timeout is used for every attempt.
+	Timeouts []time.Duration
+
+	// Retries is the number of extra attempts made for a target that did not reply.
+	Retries int
 
-	// Results stores the ping results for each target IP address.
+	// RetryBackoff is the pause before retrying a failed ping of a target, doubled before each following one.
+	RetryBackoff time.Duration
+
+	// JobBufferSize is the capacity of the channel handing the targets to the workers, i.e. the number of targets
+	// queued ahead of the workers. It does not limit the number of concurrent pings, see MaxWorkers.
+	JobBufferSize int
+
+	// Results stores the ping results for each target IP address. It is replaced, not updated in place, by Run and
+	// Watch; use GetOnlineHosts or FilterResults to read the results of a run in progress.
 	Results map[string]Result
 
 	// TotalResults represents the total number of ping results collected.
 	TotalResults int
 
+	// Elapsed is the time the last run took.
+	Elapsed time.Duration
+
 	// MaxWorkers specifies the maximum number of concurrent workers to use.
 	MaxWorkers int
 
-	logger *logrus.Logger
+	// Shuffle reports whether the targets are pinged in a pseudo-random order.
+	Shuffle bool
+
+	// Seed is the seed of the shuffle permutation, which can be reused to reproduce the scan order.
+	Seed int64
+
+	// ScoreWeights configures how the Score of each result is computed.
+	ScoreWeights ScoreWeights
+
+	// OfflineReminderEvery is the number of consecutive offline rounds between two
+	// EventStillOffline events emitted by Watch. Zero disables the reminders.
+	OfflineReminderEvery int
+
+	// RTTUnit is the unit used to render round-trip times in the exported results.
+	RTTUnit RTTUnit
+
+	// Ports lists the TCP ports probed on each target in TCP mode. It is empty in ICMP mode.
+	Ports []int
+
+	// Privileged reports whether ICMP echo requests are sent using raw sockets.
+	Privileged bool
+
+	// RetryOnAllOffline reports whether a run in which no host replied is retried once.
+	RetryOnAllOffline bool
+
+	// RetryPrivileged reports whether the retry of RetryOnAllOffline switches to privileged mode.
+	RetryPrivileged bool
+
+	// IntervalJitter randomizes the pause between two targets of a worker to Interval ± IntervalJitter.
+	IntervalJitter time.Duration
+
+	// InterSubnetDelay is the pause between finishing a subnet and starting the next one of a multi-subnet scan.
+	// The subnets covering a single range, and the addresses of Options.Hosts, are scanned without pausing.
+	InterSubnetDelay time.Duration
+
+	// FallbackToMock reports whether the scan switches to the mock pinger when ICMP is not permitted.
+	FallbackToMock bool
+
+	// Simulated reports whether the results come from the mock pinger, after falling back to it.
+	Simulated bool
+
+	// MaxTotalPackets caps the number of packets sent in a run. Zero means no cap.
+	MaxTotalPackets int
+
+	// RateLimit caps the number of packets sent per second across all workers. Zero means no limit.
+	RateLimit int
+
+	// Adaptive reports whether the number of workers pinging at once is scaled from the response rate.
+	Adaptive bool
+
+	// Labels holds the labels attached to target IP addresses, keyed by normalized IP address.
+	Labels map[string]map[string]string
+
+	// OnResult is called with the result of each target as soon as it completes. It may be nil.
+	OnResult func(ip string, result Result)
+
+	// OnDispatch is called with each target just before it is pinged. It may be nil.
+	OnDispatch func(ip string)
+
+	// Processors are applied in order to the results at the end of each run.
+	Processors []ResultProcessor
+
+	// ResultStore receives the results of Run instead of Results when set.
+	ResultStore ResultStore
+
+	// Sources lists the source addresses, in CIDR notation, from which each target is pinged.
+	Sources []string
+
+	// ScanRetries is the number of times the whole scan is re-run when most targets could not be pinged at all.
+	ScanRetries int
+
+	// Quick reports whether only the common hosts of each subnet are pinged.
+	Quick bool
+
+	// StopOnFirstReply reports whether a target stops being pinged as soon as it replies once.
+	StopOnFirstReply bool
+
+	// PayloadSize is the size in bytes of the payload of each echo request.
+	PayloadSize int
+
+	// TTL is the time to live of each echo request, or zero for the default of the pinger.
+	TTL int
+
+	// TOS is the type of service of each echo request, or zero to leave them unmarked.
+	TOS int
+
+	// CollectWorkerStats reports whether per-worker statistics are collected, see WorkerStats.
+	CollectWorkerStats bool
+
+	// RTTSmoothingFactor is the weight of the latest round in the moving average of SmoothedRTT.
+	RTTSmoothingFactor float64
+
+	// ScanRetryBackoff is the pause before the first re-run of the scan, doubled before each following one.
+	ScanRetryBackoff time.Duration
+
+	// MaxDuration caps the time each run takes, or is zero for no cap.
+	MaxDuration time.Duration
+
+	// Subnets lists the scanned subnets in canonical CIDR notation: the subnet of TargetsIterator followed by
+	// the additional subnets of Options.Subnets and the addresses of Options.Hosts.
+	Subnets []string
+
+	// UnresolvedHosts holds the errors of the Options.Hosts that could not be resolved, keyed by host. These hosts
+	// are not pinged, so they are missing from Results, but SortedResults and Summary report them.
+	UnresolvedHosts map[string]error
+
+	// config holds the options as resolved by NewSubping.
+	config Options
+
+	// jobBufferDefaulted reports whether JobBufferSize is derived from MaxWorkers rather than given, see
+	// SetMaxWorkers.
+	jobBufferDefaulted bool
+
+	// sources holds the parsed Sources.
+	sources []source
+
+	// locator geolocates the hosts, see Options.Locator. It is nil when geolocation is disabled.
+	locator Locator
+
+	// extraSubnets holds the additional subnets scanned after the subnet of TargetsIterator.
+	extraSubnets []*net.IPNet
+
+	// targetOf holds the given subnet or range each scanned subnet is taken from, keyed by canonical CIDR, so that
+	// InterSubnetDelay only pauses between them. The subnets of the addresses of Options.Hosts are missing from it.
+	targetOf map[string]string
+
+	// origins holds the canonical CIDR of the subnet each target of the last run, or Watch round, was taken from,
+	// keyed by normalized IP address.
+	origins map[string]string
+
+	// excluded holds the normalized excluded and known IP addresses that are skipped during a scan.
+	excluded map[string]struct{}
+
+	// err is the error that aborted the last run, if any.
+	err error
+
+	// startedAt is the time the last run, or Watch round, started.
+	startedAt time.Time
+
+	// sentPackets counts the packets reserved in the current run against MaxTotalPackets.
+	sentPackets atomic.Int64
+
+	// packetCapReached records whether a target was denied packets because of MaxTotalPackets.
+	packetCapReached atomic.Bool
+
+	// limiter paces the packets of all workers to RateLimit. It is nil when RateLimit is zero.
+	limiter *rate.Limiter
+
+	// failedTargets counts the targets of the current run that could not be pinged at all because of an error.
+	failedTargets atomic.Int64
+
+	// doneTargets counts the targets of the current scan whose result was stored, see Progress.
+	doneTargets atomic.Int64
+
+	// onlineTargets counts the targets of the current scan whose result was stored with a reply, see Summary.
+	onlineTargets atomic.Int64
+
+	// workerStats holds the statistics of each worker in the last scan when CollectWorkerStats is set.
+	// Each worker only updates its own entry.
+	workerStats []WorkerStat
+
+	// sentBytes and recvBytes estimate the traffic of the last run, or Watch round, see TrafficStats.
+	sentBytes atomic.Int64
+	recvBytes atomic.Int64
+
+	// stream receives the result of each target instead of the results map during Stream.
+	stream chan<- HostResult
+
+	// store receives the result of each target instead of the results map during Run, see Options.ResultStore.
+	store ResultStore
+
+	// scaler limits the workers pinging at once during a scan in the adaptive mode. It is nil otherwise.
+	scaler *workerScaler
+
+	// live is the store of the scan in progress, read by snapshot. It is nil once its results are in Results.
+	live ResultStore
+
+	// resultsMu guards the writes of Results and live during a run against snapshot.
+	resultsMu sync.RWMutex
+
+	// smoothedRTT holds the moving average of the RTT of each host across the rounds of Watch, see SmoothedRTT.
+	smoothedRTT   map[string]time.Duration
+	smoothedRTTMu sync.Mutex
+
+	pinger ping.Pinger
+	clock  Clock
+	logger Logger
 }
 
 // Options holds the configuration options for creating a new Subping instance.
 type Options struct {
-	// LogLevel sets the log levels for the Subping instance.
-	LogLevel string
+	// LogLevel sets the log levels for the Subping instance. It only applies to the default logrus logger, not to
+	// Logger.
+	LogLevel string `json:"log_level"`
+
+	// Logger receives the messages of the Subping instance, e.g. a *slog.Logger, so they integrate with the logs of
+	// the application. Its trace messages, such as each target handed to a worker, are logged at the debug level
+	// unless it has a Trace method. When nil, the messages of LogLevel and above are written to os.Stderr by logrus.
+	Logger Logger `json:"-"`
 
-	// Subnet is the subnet to scan for IP addresses to ping.
-	Subnet string
+	// Subnet is the subnet to scan for IP addresses to ping. It may also be a comma separated list of subnets,
+	// e.g. "10.0.0.0/24,192.168.1.0/24", whose first subnet is scanned first and the others as if listed in Subnets.
+	// Ranges, IPv4 wildcards and single IP addresses, e.g. "10.0.0.1-10.0.0.20", "10.0.*.*" or "10.0.0.5", are
+	// accepted too and scanned as the subnets covering them, see network.ParseTargetSpec.
+	Subnet string `json:"subnet"`
 
 	// Count is the number of ping requests to send for each target.
-	Count int
+	Count int `json:"count"`
+
+	// ConfirmCount enables a two-tier probe: each target is first pinged with Count requests, typically a quick
+	// single ping, and the targets that lost packets are pinged again with ConfirmCount requests, whose result
+	// is kept. Result.Count reports which tier each result came from. Zero disables the confirmation;
+	// otherwise it must be greater than Count.
+	ConfirmCount int `json:"confirm_count"`
 
 	// Interval is the time duration between each ping request.
-	Interval time.Duration
+	Interval time.Duration `json:"interval"`
 
 	// Timeout specifies the timeout duration before exiting each target.
-	Timeout time.Duration
+	Timeout time.Duration `json:"timeout"`
 
 	// MaxWorkers specifies the maximum number of concurrent workers to use.
-	MaxWorkers int
-}
+	// It is lowered to the number of hosts to ping when it exceeds it, see StrictWorkers. The lowering is logged as a
+	// warning when MaxWorkers is at least twice the number of hosts, and as a debug message otherwise.
+	MaxWorkers int `json:"max_workers"`
 
-// Result contains the statistics and metrics for a single ping operation.
-type Result struct {
-	// AvgRtt is the average round-trip time of the ping requests.
-	AvgRtt time.Duration
+	// StrictWorkers makes NewSubping fail instead of lowering MaxWorkers when it exceeds the number of hosts to
+	// ping, e.g. 256 workers for the 4 hosts of a /30.
+	StrictWorkers bool `json:"strict_workers"`
+
+	// JobBufferSize is the number of targets queued ahead of the workers. When zero, it is the number of hosts
+	// divided by MaxWorkers, rounded up.
+	JobBufferSize int `json:"job_buffer_size"`
+
+	// Timeouts holds the per-attempt timeouts used when retrying a target, e.g. 100ms, 500ms, 2s.
+	// Attempts beyond the end of the list reuse the last timeout. When empty, Timeout is used.
+	Timeouts []time.Duration `json:"timeouts"`
+
+	// Retries is the number of extra attempts made for a target that did not reply, or whose ping failed, e.g.
+	// because of an ephemeral socket error. The target is recorded as offline only once every attempt is made.
+	Retries int `json:"retries"`
+
+	// RetryBackoff is the pause before retrying a failed ping of a target, doubled before each following one,
+	// which gives a transient failure time to clear. Targets that did not reply are retried without a pause.
+	// Zero defaults to DefaultRetryBackoff.
+	RetryBackoff time.Duration `json:"retry_backoff"`
+
+	// Pinger overrides the pinger used to probe each target.
+	// When nil, the default ICMP pinger is used. A panic of the pinger is logged and the target is reported
+	// as offline, like a ping that failed, so a buggy pinger cannot crash the scan.
+	Pinger ping.Pinger `json:"-"`
+
+	// Shuffle enables pinging the targets in a pseudo-random order instead of sequentially.
+	Shuffle bool `json:"shuffle"`
+
+	// Seed seeds the shuffle permutation so the same seed yields the same scan order.
+	// When zero, a time-based seed is used.
+	Seed int64 `json:"seed"`
+
+	// ScoreWeights configures how the Score of each result is computed.
+	// The zero value uses the default weights.
+	ScoreWeights ScoreWeights `json:"score_weights"`
+
+	// OfflineReminderEvery makes Watch emit an EventStillOffline event every N consecutive
+	// rounds a host stays offline. Zero disables the reminders.
+	OfflineReminderEvery int `json:"offline_reminder_every"`
+
+	// Exclude lists IP addresses within the subnet that are not pinged.
+	// IPv6 addresses may be written in any notation.
+	Exclude []string `json:"exclude"`
+
+	// KnownHosts lists already-known IP addresses, e.g. loaded from an inventory, that are not pinged.
+	// It allows re-scanning a subnet to discover new devices only: every online host in the
+	// results is then a newly-responding host.
+	KnownHosts []string `json:"known_hosts"`
+
+	// RTTUnit is the unit used to render round-trip times in the exported results.
+	// The zero value renders durations automatically, and as milliseconds in numeric formats such as CSV.
+	RTTUnit RTTUnit `json:"rtt_unit"`
+
+	// Ports switches to TCP mode when Pinger is nil: instead of sending ICMP echo requests, each target
+	// is probed by connecting to every port in turn, and Result.Ports reports which ports are open.
+	Ports []int `json:"ports"`
+
+	// Privileged sends ICMP echo requests using raw sockets, which requires elevated privileges,
+	// instead of unprivileged datagram sockets.
+	Privileged bool `json:"privileged"`
+
+	// RetryOnAllOffline retries a run once when no host replied at all, which usually indicates a
+	// permission or routing problem rather than every host being down. A warning is logged before retrying.
+	RetryOnAllOffline bool `json:"retry_on_all_offline"`
+
+	// RetryPrivileged makes the retry of RetryOnAllOffline, and the following runs, use privileged mode.
+	RetryPrivileged bool `json:"retry_privileged"`
+
+	// IntervalJitter randomizes the pause between two targets of a worker to Interval ± rand(IntervalJitter),
+	// so the probes do not synchronize with periodic network timers. Zero disables the jitter.
+	IntervalJitter time.Duration `json:"interval_jitter"`
+
+	// InterSubnetDelay pauses a multi-subnet scan between subnets: every target of a subnet is pinged before
+	// the pause, and the next subnet is only started after it, so subnets behind different links are not loaded
+	// at the same time. Zero starts the next subnet as soon as the targets of the previous one are dispatched.
+	// The pause is keyed on the given subnets and ranges: the subnets covering a range are scanned as one, and so
+	// are the addresses of Hosts.
+	InterSubnetDelay time.Duration `json:"inter_subnet_delay"`
+
+	// Clock overrides the clock used to pause between targets. When nil, the real clock is used.
+	Clock Clock `json:"-"`
+
+	// FallbackToMock makes a scan whose first target fails with a permission error, e.g. because ICMP
+	// sockets are not permitted, log a prominent warning and continue with the simulated results of the
+	// mock pinger. When unset, such a scan is aborted and Err reports ErrPermissionDenied.
+	FallbackToMock bool `json:"fallback_to_mock"`
+
+	// MaxTotalPackets is a hard cap on the number of packets sent in a run, or in a Watch round, regardless of
+	// the number of targets and Count, for metered or quota-limited links. Once the cap is reached, the remaining
+	// targets are not pinged and are missing from the results, and the scan is not retried: the retries of
+	// ScanRetries and RetryOnAllOffline share the budget of the run. Zero means no cap.
+	MaxTotalPackets int `json:"max_total_packets"`
+
+	// RateLimit is the maximum number of packets sent per second across all workers, retries and confirmations
+	// included, to protect low-bandwidth links and stay below IDS thresholds. The packets of a ping are accounted
+	// when it starts, so a single ping of more than RateLimit packets is let through at once. Zero means no limit.
+	RateLimit int `json:"rate_limit"`
+
+	// Adaptive scales the number of workers pinging at once from the outcomes of the latest 32 pings instead of
+	// running MaxWorkers of them throughout. A scan starts with a quarter of MaxWorkers, adds half as many workers
+	// again, up to MaxWorkers, while at most a quarter of the pings time out, as on a dense subnet, and halves them,
+	// down to the quarter of MaxWorkers, while at least three quarters do, as on a sparse subnet where the workers
+	// mostly wait for timeouts. Each change is logged at the debug level with the number of workers.
+	Adaptive bool `json:"adaptive"`
 
-	// PacketLoss is the percentage of packets lost during the ping operation.
-	PacketLoss float64
+	// Labels attaches key/value labels, e.g. "role": "db", to target IP addresses for inventory integration.
+	// The labels are carried into HostResult and the exported formats. IPv6 addresses may be written in any notation.
+	Labels map[string]map[string]string `json:"labels"`
 
-	// PacketsSent is the number of packets sent for the ping operation.
-	PacketsSent int
+	// MaxHosts is the safety threshold on the number of hosts in Subnet: larger ranges, such as 0.0.0.0/0,
+	// are refused unless AllowLargeRanges is set. Zero defaults to DefaultMaxHosts.
+	MaxHosts int `json:"max_hosts"`
 
-	// PacketsRecv is the number of packets received for the ping operation.
-	PacketsRecv int
+	// AllowLargeRanges disables the MaxHosts safety threshold.
+	AllowLargeRanges bool `json:"allow_large_ranges"`
 
-	// PacketsRecvDuplicates is the number of duplicate packets received.
-	PacketsRecvDuplicates int
+	// OnResult is called with the normalized IP address and the result of each target as soon as its ping
+	// completes, before the result is stored. It is called from multiple workers concurrently, so it must be
+	// safe for concurrent use, and it should return quickly since it blocks the calling worker.
+	OnResult func(ip string, result ping.Result) `json:"-"`
+
+	// OnDispatch is called with the normalized IP address of each target just before it is pinged, whether or not
+	// it replies, e.g. to keep an audit trail of the probed addresses in order. Like OnResult, it is called from
+	// multiple workers concurrently and blocks the calling worker.
+	OnDispatch func(ip string) `json:"-"`
+
+	// Processors enrich or filter the results at the end of each Run, in order, each receiving the results returned
+	// by the previous one, e.g. FilterOffline followed by ResolveHostnames. They are not applied by Watch.
+	Processors []ResultProcessor `json:"-"`
+
+	// ResultStore receives the result of each target of Run instead of Results, to scan large subnets with a bounded
+	// memory use. The scan retries, RetryOnAllOffline and Processors are then skipped, and only Summary counts the
+	// online hosts.
+	ResultStore ResultStore `json:"-"`
+
+	// Sources lists the local source addresses to ping from, in CIDR notation where the prefix is the subnet the
+	// address is attached to, e.g. "192.168.10.1/24" and "10.0.20.1/24" on a router with an interface in each VLAN.
+	// Each target is pinged from the source whose subnet contains it, or else from the source sharing the longest
+	// prefix with it, whether by ICMP or through the TCP connections of Ports. When empty, the system picks the
+	// source address.
+	Sources []string `json:"sources"`
+
+	// Locator annotates the IP addresses in HostResult with their country and city, e.g. a *geo.DB of the pkg/geo
+	// package reading an offline MaxMind GeoLite2 database, which does not annotate private addresses.
+	// When nil, geolocation is disabled.
+	Locator Locator `json:"-"`
+
+	// Subnets lists additional subnets, in CIDR notation, scanned after Subnet in the same run. Like Subnet, it
+	// also accepts ranges, IPv4 wildcards and single IP addresses. The subnets cannot overlap each other or Subnet.
+	Subnets []string `json:"subnets"`
+
+	// Hosts lists host names, e.g. "db.example.com", resolved by NewSubping to their IPv4 and IPv6 addresses, which
+	// are all scanned after Subnet and Subnets, except those the subnets already hold. Subnet may then be empty.
+	// The hosts that cannot be resolved are reported in Subping.UnresolvedHosts.
+	Hosts []string `json:"hosts"`
+
+	// IPFamily restricts the addresses the Hosts are pinged at to IPv4 or IPv6, e.g. for the hosts having both A
+	// and AAAA records. The hosts without an address of the family are reported in Subping.UnresolvedHosts.
+	// Empty defaults to IPFamilyAny.
+	IPFamily IPFamily `json:"ip_family"`
+
+	// LookupIP resolves the Hosts to their IP addresses. When nil, net.LookupIP is used.
+	LookupIP func(host string) ([]net.IP, error) `json:"-"`
+
+	// ScanRetries re-runs the entire scan, up to ScanRetries times, when most targets could not be pinged at all
+	// because of an error, e.g. "network is unreachable" while a Wi-Fi link drops, as opposed to hosts not replying.
+	// A few targets failing on their own do not re-run the scan. The best result of each target across the attempts
+	// is kept. Unlike Retries, it retries the whole scan after
+	// a pause, which gives a flapping link time to recover. A permission error is not retried.
+	ScanRetries int `json:"scan_retries"`
+
+	// ScanRetryBackoff is the pause before the first re-run of the scan, doubled before each following one.
+	// Zero defaults to DefaultScanRetryBackoff.
+	ScanRetryBackoff time.Duration `json:"scan_retry_backoff"`
+
+	// MaxDuration caps the time a run takes, retries included, unlike Timeout which caps the ping of a single
+	// target. Once exceeded, the run stops as RunContext does when its context is done and keeps the results
+	// collected so far, e.g. to bound periodic scans. It is measured on the wall clock, even when Clock is set.
+	// Zero means no cap.
+	MaxDuration time.Duration `json:"max_duration"`
+
+	// Quick restricts the scan to the common hosts of each subnet, see network.SubnetHostsIterator.CommonHosts:
+	// a near-instant reconnaissance pass checking whether anyone is home before committing to a full sweep.
+	Quick bool `json:"quick"`
+
+	// StopOnFirstReply stops pinging a target as soon as it replies once instead of sending all Count packets,
+	// which saves packets and time on hosts that are clearly up. The packets left unsent are returned to the
+	// MaxTotalPackets budget.
+	StopOnFirstReply bool `json:"stop_on_first_reply"`
+
+	// PayloadSize is the size in bytes of the payload of each ICMP echo request, from ping.DefaultPayloadSize to
+	// ping.MaxPayloadSize, e.g. 1472 to fill an IPv4 packet of a 1500 bytes MTU and reveal fragmentation problems.
+	// Zero defaults to ping.DefaultPayloadSize.
+	PayloadSize int `json:"payload_size"`
+
+	// TTL is the time to live, or hop limit over IPv6, of each echo request, up to ping.MaxTTL. A low TTL checks
+	// which hosts are reachable within that many hops. Zero uses the default of the pinger.
+	TTL int `json:"ttl"`
+
+	// TOS is the type of service, or traffic class over IPv6, of each echo request, up to ping.MaxTOS, e.g. 184 to
+	// mark the requests with the EF DSCP for QoS testing. Zero leaves them unmarked. Only the TCP pinger of Ports
+	// can mark the requests among the built-in pingers. A scan with a pinger unable to mark the requests is aborted
+	// and Err reports ping.ErrTOSUnsupported.
+	TOS int `json:"tos"`
+
+	// CollectWorkerStats collects the number of targets handled by each worker and the time it spent pinging them,
+	// reported by WorkerStats, e.g. to diagnose a worker stuck on slow timeouts.
+	CollectWorkerStats bool `json:"collect_worker_stats"`
+
+	// RTTSmoothingFactor is the weight, from 0 excluded to 1, of the latest round in the exponential moving average
+	// of the RTT of each host kept across the rounds of Watch, see SmoothedRTT. Lower values give a smoother but
+	// slower to react signal, and 1 keeps the latest RTT only. Zero defaults to DefaultRTTSmoothingFactor.
+	RTTSmoothingFactor float64 `json:"rtt_smoothing_factor"`
 }
 
-// NewSubping creates a new Subping instance with the provided options.
+// DefaultRTTSmoothingFactor is the default weight of the latest round in SmoothedRTT, see Options.RTTSmoothingFactor.
+const DefaultRTTSmoothingFactor = 0.3
+
+// DefaultScanRetryBackoff is the default pause before the first re-run of a scan, see Options.ScanRetries.
+const DefaultScanRetryBackoff = time.Second
+
+// DefaultRetryBackoff is the default pause before retrying a failed ping of a target, see Options.RetryBackoff.
+const DefaultRetryBackoff = 50 * time.Millisecond
+
+// DefaultMaxHosts is the default safety threshold on the number of hosts in a subnet, the size of a /16 IPv4 network.
+const DefaultMaxHosts = 1 << 16
+
+// errPacketCapReached is returned by pingHost when MaxTotalPackets leaves no packet for the target.
+var errPacketCapReached = errors.New("the packet cap is reached")
+
+// ErrPermissionDenied is reported by Err when a scan is aborted because the pinger is not permitted to send packets.
+var ErrPermissionDenied = errors.New("permission denied to send ICMP echo requests: run subping as root, " +
+	"grant it the CAP_NET_RAW capability or allow unprivileged ICMP sockets with the net.ipv4.ping_group_range sysctl")
+
+// Result contains the statistics and metrics for a single ping operation.
+type Result = ping.Result
+
+// NewSubping creates a new Subping instance with the provided options. The options are not modified, so they can be
+// reused for another Subping.
 func NewSubping(opts *Options) (*Subping, error) {
-	if opts.Subnet == "" {
+	// The defaults are applied to a copy, not written back into the options of the caller.
+	o := *opts
+	opts = &o
+
+	if opts.Subnet == "" && len(opts.Hosts) == 0 {
 		return nil, errors.New("subnet should be in CIDR notation and cannot empty")
 	}
 
@@ -121,166 +580,1298 @@ func NewSubping(opts *Options) (*Subping, error) {
 		return nil, errors.New("count should be more than zero (0)")
 	}
 
+	if opts.ConfirmCount < 0 {
+		return nil, errors.New("confirm count cannot be negative")
+	}
+
+	if opts.ConfirmCount > 0 && opts.ConfirmCount <= opts.Count {
+		return nil, errors.New("confirm count should be more than count")
+	}
+
 	if opts.MaxWorkers < 1 {
 		return nil, errors.New("max workers should be more than zero (0)")
 	}
 
-	ips, err := network.NewSubnetHostsIteratorFromCIDRString(opts.Subnet)
-	if err != nil {
-		log.Fatal(err.Error())
+	if opts.Retries < 0 {
+		return nil, errors.New("retries cannot be negative")
 	}
 
-	batchLimit, err := calculateMaxPartitionSize(ips.TotalHosts, opts.MaxWorkers)
-	if err != nil {
-		return nil, err
+	if opts.OfflineReminderEvery < 0 {
+		return nil, errors.New("offline reminder interval cannot be negative")
 	}
 
-	if opts.LogLevel == "" {
-		opts.LogLevel = "error"
+	if opts.MaxHosts < 0 {
+		return nil, errors.New("max hosts cannot be negative")
 	}
 
-	logLevel, err := logrus.ParseLevel(opts.LogLevel)
-	if err != nil {
-		return nil, errors.New("max workers should be more than zero (0)")
+	if opts.MaxTotalPackets < 0 {
+		return nil, errors.New("max total packets cannot be negative")
 	}
 
-	instance := &Subping{
-		TargetsIterator: ips,
-		Count:           opts.Count,
-		Interval:        opts.Interval,
-		Timeout:         opts.Timeout,
-		BatchSize:       int64(batchLimit),
-		MaxWorkers:      opts.MaxWorkers,
-		logger:          logrus.New(),
+	if opts.RateLimit < 0 {
+		return nil, errors.New("rate limit cannot be negative")
 	}
 
-	instance.logger.SetLevel(logLevel)
+	if opts.PayloadSize == 0 {
+		opts.PayloadSize = ping.DefaultPayloadSize
+	}
 
-	return instance, nil
-}
+	if opts.PayloadSize < ping.DefaultPayloadSize || opts.PayloadSize > ping.MaxPayloadSize {
+		return nil, fmt.Errorf("payload size must be between %d and %d bytes, got %d",
+			ping.DefaultPayloadSize, ping.MaxPayloadSize, opts.PayloadSize)
+	}
 
-// Run starts the Subping process, concurrently pinging the target IP addresses.
-// It spawns worker goroutines, assigns tasks to them, waits for them to finish,
-// and collects the results.
-func (s *Subping) Run() {
-	var (
-		// syncMap to store the results from workers.
-		syncMap sync.Map
+	if opts.TTL < 0 || opts.TTL > ping.MaxTTL {
+		return nil, fmt.Errorf("TTL must be between 0 and %d, got %d", ping.MaxTTL, opts.TTL)
+	}
 
-		// wg WaitGroup to synchronize the workers.
-		wg sync.WaitGroup
+	if opts.TOS < 0 || opts.TOS > ping.MaxTOS {
+		return nil, fmt.Errorf("TOS must be between 0 and %d, got %d", ping.MaxTOS, opts.TOS)
+	}
 
-		// jobChannel to distribute tasks to workers.
-		jobChannel = make(chan string, s.BatchSize)
-	)
+	if opts.JobBufferSize < 0 {
+		return nil, errors.New("job buffer size cannot be negative")
+	}
 
-	// Spawn the worker goroutines.
-	for i := int64(0); i < int64(s.MaxWorkers); i++ {
-		wg.Add(1)
-		go s.startWorker(i, &wg, &syncMap, jobChannel)
+	if opts.IntervalJitter < 0 {
+		return nil, errors.New("interval jitter cannot be negative")
 	}
 
-	s.logger.Debugf("Spawned %d workers.\n", s.MaxWorkers)
+	if opts.MaxDuration < 0 {
+		return nil, errors.New("max duration cannot be negative")
+	}
 
-	s.logger.Debugln("Assigning task to all workers.")
-	for ip := s.TargetsIterator.Next(); ip != nil; ip = s.TargetsIterator.Next() {
-		ipString := ip.String()
-		jobChannel <- ipString
-		s.logger.Tracef("Assigned task: %s\n", ipString)
+	if opts.InterSubnetDelay < 0 {
+		return nil, errors.New("inter-subnet delay cannot be negative")
 	}
 
-	s.logger.Debugln("Waiting all workers finish their jobs.")
-	close(jobChannel)
-	wg.Wait()
+	if opts.ScanRetries < 0 {
+		return nil, errors.New("scan retries cannot be negative")
+	}
 
-	s.logger.Debugln("All workers already stopped. Storing the results.")
-	s.Results = make(map[string]Result)
+	if opts.ScanRetryBackoff < 0 {
+		return nil, errors.New("scan retry backoff cannot be negative")
+	}
 
-	syncMap.Range(func(key, value any) bool {
-		s.Results[key.(string)] = value.(Result)
+	if opts.RetryBackoff < 0 {
+		return nil, errors.New("retry backoff cannot be negative")
+	}
 
-		return true
-	})
-	s.TotalResults = len(s.Results)
-	s.logger.Debugln("Run finished. All task done..")
-}
+	if err := opts.ScoreWeights.validate(); err != nil {
+		return nil, err
+	}
 
-// startWorker is a worker goroutine that performs the ping task assigned to it.
-// It collects the ping results and stores them in the sync.Map.
-func (s *Subping) startWorker(id int64, wg *sync.WaitGroup, sm *sync.Map, c <-chan string) {
-	defer wg.Done()
+	if _, err := ParseRTTUnit(string(opts.RTTUnit)); err != nil {
+		return nil, err
+	}
 
-	for target := range c {
-		s.logger.WithField("worker", id).Tracef("Got task %s.\n", target)
+	if opts.RTTSmoothingFactor < 0 || opts.RTTSmoothingFactor > 1 {
+		return nil, errors.New("RTT smoothing factor should be between 0 and 1")
+	}
+
+	for _, port := range opts.Ports {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("port %d is out of range (1-65535)", port)
+		}
+	}
+
+	for _, timeout := range opts.Timeouts {
+		if timeout <= 0 {
+			return nil, errors.New("timeouts should be more than zero (0)")
+		}
+	}
 
-		p := RunPing(target, s.Count, s.Interval, s.Timeout)
-		sm.Store(target, Result{
-			AvgRtt:                p.AvgRtt,
-			PacketLoss:            p.PacketLoss,
-			PacketsSent:           p.PacketsSent,
-			PacketsRecv:           p.PacketsRecv,
-			PacketsRecvDuplicates: p.PacketsRecvDuplicates,
-		})
+	skipped := make([]string, 0, len(opts.Exclude)+len(opts.KnownHosts))
+	skipped = append(skipped, opts.Exclude...)
+	skipped = append(skipped, opts.KnownHosts...)
 
-		time.Sleep(s.Interval)
+	excluded, err := newIPSet(skipped)
+	if err != nil {
+		return nil, err
 	}
-}
 
-// GetOnlineHosts returns a map of online hosts and their corresponding ping results,
-// as well as the total number of online hosts.
-func (s *Subping) GetOnlineHosts() (map[string]Result, int) {
-	r := make(map[string]Result)
+	sources, err := parseSources(opts.Sources)
+	if err != nil {
+		return nil, err
+	}
 
-	for ip, stats := range s.Results {
-		if stats.PacketsRecv > 0 {
-			r[ip] = stats
+	labels, err := normalizeLabels(opts.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	subnet, extraCIDRs := splitSubnetList(opts.Subnet, opts.Subnets)
+
+	var targetOf map[string]string
+	if subnet != "" {
+		var cidrs []string
+		cidrs, targetOf = expandRanges(append([]string{subnet}, extraCIDRs...))
+		subnet, extraCIDRs = cidrs[0], cidrs[1:]
+	}
+
+	opts.IPFamily, err = ParseIPFamily(string(opts.IPFamily))
+	if err != nil {
+		return nil, err
+	}
+
+	hostIPs, unresolvedHosts := resolveHosts(opts.Hosts, opts.IPFamily, opts.LookupIP)
+	if subnet == "" {
+		if len(hostIPs) == 0 && opts.IPFamily != IPFamilyAny {
+			return nil, fmt.Errorf("none of the %d hosts resolved to an address of family %s", len(opts.Hosts), opts.IPFamily)
+		}
+
+		if len(hostIPs) == 0 {
+			return nil, fmt.Errorf("none of the %d hosts could be resolved", len(opts.Hosts))
 		}
+
+		subnet = hostSubnet(hostIPs[0]).String()
+		hostIPs = hostIPs[1:]
 	}
 
-	return r, len(r)
-}
+	ips, err := network.NewSubnetHostsIteratorFromCIDRString(subnet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subnet %q: %w", subnet, err)
+	}
 
-// RunPing performs a ping operation to the specified IP address.
-// It sends the specified number of ping requests with the given interval and timeout.
-func RunPing(ipAddress string, count int, interval time.Duration, timeout time.Duration) ping.Statistics {
-	pinger, err := ping.NewPinger(ipAddress)
+	if opts.MaxHosts == 0 {
+		opts.MaxHosts = DefaultMaxHosts
+	}
+
+	if opts.ScanRetryBackoff == 0 {
+		opts.ScanRetryBackoff = DefaultScanRetryBackoff
+	}
+
+	if opts.RetryBackoff == 0 {
+		opts.RetryBackoff = DefaultRetryBackoff
+	}
+
+	if opts.RTTSmoothingFactor == 0 {
+		opts.RTTSmoothingFactor = DefaultRTTSmoothingFactor
+	}
+
+	extraSubnets, err := parseSubnets(ips.IPNet, extraCIDRs)
 	if err != nil {
-		logrus.Printf("Failed to create pinger for IP Address: %s\n", ipAddress)
-		return ping.Statistics{}
+		return nil, err
 	}
 
-	pinger.Count = count
-	pinger.Interval = interval
+	extraSubnets = appendHostSubnets(ips.IPNet, extraSubnets, hostIPs)
 
-	if timeout > 0 {
-		pinger.Timeout = timeout
+	subnets := []string{ips.IPNet.String()}
+	totalHosts := hostCount(ips.IPNet, opts.Quick)
+
+	for _, ipNet := range append([]*net.IPNet{ips.IPNet}, extraSubnets...) {
+		if err := checkRangeSize(ipNet, opts.MaxHosts, opts.AllowLargeRanges); err != nil {
+			return nil, err
+		}
 	}
 
-	if runtime.GOOS == "windows" {
-		pinger.SetPrivileged(true)
+	for _, ipNet := range extraSubnets {
+		subnets = append(subnets, ipNet.String())
+		totalHosts = addHostCounts(totalHosts, hostCount(ipNet, opts.Quick))
 	}
 
-	err = pinger.Run()
+	requestedWorkers := opts.MaxWorkers
+	if totalHosts > 0 && opts.MaxWorkers > totalHosts {
+		if opts.StrictWorkers {
+			return nil, fmt.Errorf("requested %d workers for %d hosts, lower the number of workers to at most %d",
+				opts.MaxWorkers, totalHosts, totalHosts)
+		}
+
+		opts.MaxWorkers = totalHosts
+	}
+
+	jobBufferDefaulted := opts.JobBufferSize == 0
+	if jobBufferDefaulted {
+		opts.JobBufferSize = defaultJobBufferSize(totalHosts, opts.MaxWorkers)
+	}
+
+	if opts.LogLevel == "" {
+		opts.LogLevel = "error"
+	}
+
+	logLevel, err := logrus.ParseLevel(opts.LogLevel)
 	if err != nil {
-		logrus.Printf("Failed to ping the address %s, %v\n", ipAddress, err.Error())
-		return ping.Statistics{}
+		return nil, fmt.Errorf("invalid log level: %w", err)
 	}
 
-	return *pinger.Statistics()
-}
+	var logger Logger = newLogrusLogger(logLevel)
+	if opts.Logger != nil {
+		logger = opts.Logger
+	}
+
+	pinger := opts.Pinger
+	if pinger == nil {
+		kind := ping.KindICMP
+		if len(opts.Ports) > 0 {
+			kind = ping.KindTCP
+		}
+
+		pinger, err = ping.NewPingerWithOptions(kind, opts.Ports)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	clock := opts.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	seed := opts.Seed
+	if opts.Shuffle {
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+
+		ips.Shuffle(seed)
+	}
 
-// calculateMaxPartitionSize calculates the maximum size of each partition given the total data size and the desired number of partitions.
-func calculateMaxPartitionSize(dataSize int, numPartitions int) (int, error) {
-	maxPartitionSize := dataSize / numPartitions
-	remainder := dataSize % numPartitions
+	if opts.Quick {
+		ips.RestrictToCommonHosts()
+	}
+
+	instance := &Subping{
+		TargetsIterator:      ips,
+		Count:                opts.Count,
+		ConfirmCount:         opts.ConfirmCount,
+		Interval:             opts.Interval,
+		Timeout:              opts.Timeout,
+		Timeouts:             opts.Timeouts,
+		Retries:              opts.Retries,
+		JobBufferSize:        opts.JobBufferSize,
+		MaxWorkers:           opts.MaxWorkers,
+		Shuffle:              opts.Shuffle,
+		Seed:                 seed,
+		ScoreWeights:         opts.ScoreWeights,
+		OfflineReminderEvery: opts.OfflineReminderEvery,
+		RTTUnit:              opts.RTTUnit,
+		Ports:                opts.Ports,
+		Privileged:           opts.Privileged,
+		RetryOnAllOffline:    opts.RetryOnAllOffline,
+		RetryPrivileged:      opts.RetryPrivileged,
+		IntervalJitter:       opts.IntervalJitter,
+		InterSubnetDelay:     opts.InterSubnetDelay,
+		FallbackToMock:       opts.FallbackToMock,
+		MaxTotalPackets:      opts.MaxTotalPackets,
+		Labels:               labels,
+		OnResult:             opts.OnResult,
+		OnDispatch:           opts.OnDispatch,
+		Processors:           opts.Processors,
+		ResultStore:          opts.ResultStore,
+		Sources:              opts.Sources,
+		Subnets:              subnets,
+		ScanRetries:          opts.ScanRetries,
+		ScanRetryBackoff:     opts.ScanRetryBackoff,
+		RetryBackoff:         opts.RetryBackoff,
+		MaxDuration:          opts.MaxDuration,
+		Quick:                opts.Quick,
+		StopOnFirstReply:     opts.StopOnFirstReply,
+		PayloadSize:          opts.PayloadSize,
+		TTL:                  opts.TTL,
+		TOS:                  opts.TOS,
+		CollectWorkerStats:   opts.CollectWorkerStats,
+		RTTSmoothingFactor:   opts.RTTSmoothingFactor,
+		RateLimit:            opts.RateLimit,
+		Adaptive:             opts.Adaptive,
+		limiter:              newLimiter(opts.RateLimit, opts.Count, opts.ConfirmCount),
+		sources:              sources,
+		UnresolvedHosts:      unresolvedHosts,
+		extraSubnets:         extraSubnets,
+		targetOf:             targetOf,
+		jobBufferDefaulted:   jobBufferDefaulted,
+		locator:              opts.Locator,
+		excluded:             excluded,
+		pinger:               pinger,
+		clock:                clock,
+		logger:               logger,
+	}
+
+	instance.config = *opts
+	instance.config.Subnet = ips.IPNet.String()
+	instance.config.Subnets = subnets[1:]
+	instance.config.Seed = seed
+	instance.config.ScoreWeights = opts.ScoreWeights.withDefaults()
+
+	if requestedWorkers != opts.MaxWorkers {
+		// A few workers too many are harmless, only a large excess hints at a mistake worth a warning.
+		logClamp := instance.logger.Debug
+		if requestedWorkers >= 2*opts.MaxWorkers {
+			logClamp = instance.logger.Warn
+		}
+
+		logClamp(fmt.Sprintf("Requested %d workers for %d hosts; using %d.",
+			requestedWorkers, totalHosts, opts.MaxWorkers))
+	}
+
+	return instance, nil
+}
+
+// Run starts the Subping process, concurrently pinging the target IP addresses.
+// It spawns worker goroutines, assigns tasks to them, waits for them to finish,
+// and collects the results.
+func (s *Subping) Run() {
+	_ = s.RunContext(context.Background())
+}
+
+// RunContext is like Run, but stops the scan once ctx is done: the targets not pinged yet are skipped, the pings in
+// flight are completed, and Results holds the partial results, processed by the Processors as usual. The scan is then
+// not retried. It returns ctx.Err() if the scan was interrupted, and nil otherwise. Exceeding MaxDuration interrupts
+// the scan likewise, with context.DeadlineExceeded. With a ResultStore, the results are written to it instead of
+// Results, see Options.ResultStore.
+func (s *Subping) RunContext(ctx context.Context) error {
+	ctx, cancel := s.beginRun(ctx)
+	defer cancel()
+
+	runStart := s.now()
+
+	s.TargetsIterator.Reset()
+	s.setResults(nil)
+
+	if s.ResultStore != nil {
+		return s.runToStore(ctx)
+	}
+
+	s.setResults(s.scan(ctx, s.targets(s.TargetsIterator)))
+
+	backoff := s.ScanRetryBackoff
+	for attempt := 1; attempt <= s.ScanRetries && s.err == nil && ctx.Err() == nil && !s.PacketCapReached() &&
+		s.systemicFailure(); attempt++ {
+		s.logger.Warn(fmt.Sprintf("%d targets could not be pinged, which usually means a transient network failure. "+
+			"Retrying the scan in %s (%d/%d).", s.failedTargets.Load(), backoff, attempt, s.ScanRetries))
+
+		if !s.sleep(ctx, backoff) {
+			break
+		}
+		backoff *= 2
+
+		s.setResults(mergeBestResults(copyResults(s.Results), s.scan(ctx, s.targets(s.NewIterator()))))
+	}
+
+	if _, online := s.GetOnlineHosts(); online == 0 && len(s.Results) > 0 && s.RetryOnAllOffline && ctx.Err() == nil &&
+		!s.PacketCapReached() {
+		s.logger.Warn("No host replied. This usually means a permission or routing problem, e.g. unprivileged " +
+			"ICMP sockets are not allowed (see the net.ipv4.ping_group_range sysctl) or there is no route to the subnet. " +
+			"Retrying once.")
+
+		if s.RetryPrivileged {
+			s.Privileged = true
+		}
+
+		s.setResults(s.scan(ctx, s.targets(s.NewIterator())))
+	}
+
+	pinged := len(s.Results)
+
+	if len(s.Processors) > 0 {
+		results := copyResults(s.Results)
+		for _, p := range s.Processors {
+			results = p.Process(results)
+		}
+
+		s.setResults(results)
+	}
+
+	s.TotalResults = len(s.Results)
+
+	return s.finishRun(ctx, runStart, pinged)
+}
+
+// beginRun starts a run of Run or Stream: it clears the error, the traffic estimate and the packet budget of the last
+// run and records its start for ScanContext. It returns ctx bounded by MaxDuration.
+func (s *Subping) beginRun(ctx context.Context) (context.Context, context.CancelFunc) {
+	s.startedAt = time.Now()
+	s.err = nil
+	s.resetTraffic()
+	s.resetPacketBudget()
+
+	if s.MaxDuration > 0 {
+		return context.WithTimeout(ctx, s.MaxDuration)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// finishRun ends the run started at runStart, of which pinged targets were pinged: it records Elapsed, warns when
+// the packet cap left the results partial, and returns ctx.Err().
+func (s *Subping) finishRun(ctx context.Context, runStart time.Time, pinged int) error {
+	s.Elapsed = s.now().Sub(runStart)
+
+	if s.PacketCapReached() {
+		s.logger.Warn(fmt.Sprintf("The cap of %d packets is reached: the results are partial, only %d targets were pinged.",
+			s.MaxTotalPackets, pinged))
+	}
+
+	if ctx.Err() != nil {
+		s.logger.Debug("Run interrupted. The results are partial.")
+
+		return ctx.Err()
+	}
+
+	s.logger.Debug("Run finished. All task done..")
+
+	return nil
+}
+
+// Scan pings the targets described by opts and returns their results, keyed by IP address. It is the functional form
+// of NewSubping and RunContext: the Subping is built for this call only and NewSubping does not modify opts, so
+// concurrent calls may share the same Options. It returns the error of NewSubping for invalid options, ctx.Err() if
+// the scan was interrupted, and ErrPermissionDenied if it was aborted, along with the results gathered so far. With a
+// ResultStore, the results are written to it and the returned map is empty.
+func Scan(ctx context.Context, opts *Options) (map[string]Result, error) {
+	s, err := NewSubping(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.RunContext(ctx); err != nil {
+		return s.Results, err
+	}
+
+	return s.Results, s.Err()
+}
+
+// runToStore scans the targets once, writing their results to ResultStore instead of Results. It returns ctx.Err() if
+// the scan was interrupted, and nil otherwise.
+func (s *Subping) runToStore(ctx context.Context) error {
+	runStart := s.now()
+
+	s.store = s.ResultStore
+	defer func() { s.store = nil }()
+
+	s.setResults(s.scan(ctx, s.targets(s.TargetsIterator)))
+	s.TotalResults = int(s.doneTargets.Load())
+
+	return s.finishRun(ctx, runStart, s.TotalResults)
+}
+
+// systemicFailure reports whether most targets of the last scan pass could not be pinged at all, as when the link is
+// down, rather than a few hosts failing on their own.
+func (s *Subping) systemicFailure() bool {
+	failed := s.failedTargets.Load()
+
+	return failed > 0 && failed*2 > s.doneTargets.Load()
+}
+
+// sleep pauses the calling goroutine for d on the clock of s, returning early once ctx is done. It reports whether
+// ctx is still not done.
+func (s *Subping) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	slept := make(chan struct{})
+	go func() {
+		s.clock.Sleep(d)
+		close(slept)
+	}()
+
+	select {
+	case <-slept:
+		return ctx.Err() == nil
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// scan pings every target yielded by it using the worker pool and returns the results. Once ctx is done, the targets
+// not pinged yet are skipped. The subnet each target is taken from is recorded in origins.
+func (s *Subping) scan(ctx context.Context, it *network.MultiSubnetHostsIterator) map[string]Result {
+	s.doneTargets.Store(0)
+	s.onlineTargets.Store(0)
+
+	var (
+		// store holds the results from workers: the ResultStore of Run, or a new in-memory one.
+		store = s.store
+
+		// wg WaitGroup to synchronize the workers.
+		wg sync.WaitGroup
+
+		// jobChannel to distribute tasks to workers.
+		jobChannel = make(chan string, s.JobBufferSize)
+
+		// pending tracks the dispatched targets that are not pinged yet.
+		pending sync.WaitGroup
+	)
+
+	// Only the in-memory store is read by snapshot: reading a ResultStore would copy it whole into memory.
+	if store == nil {
+		store = &memoryStore{}
+
+		if s.stream == nil {
+			s.setLive(store)
+		}
+	}
+
+	s.failedTargets.Store(0)
+	s.origins = make(map[string]string)
+
+	s.workerStats = nil
+	if s.CollectWorkerStats {
+		s.workerStats = make([]WorkerStat, s.MaxWorkers)
+		for i := range s.workerStats {
+			s.workerStats[i].Worker = i
+		}
+	}
+
+	// Ping the first target before spawning the workers, so that a systemic permission
+	// failure is detected instead of reporting every target offline.
+	first, ok := s.nextTarget(it)
+	if !ok {
+		return map[string]Result{}
+	}
+
+	firstStart := time.Now()
+	s.dispatch(first)
+	firstResult, err := s.pingHost(ctx, first)
+	if errors.Is(err, ping.ErrTOSUnsupported) {
+		s.err = err
+
+		return map[string]Result{}
+	}
+
+	if errors.Is(err, os.ErrPermission) {
+		if !s.FallbackToMock {
+			s.err = fmt.Errorf("%w (%v)", ErrPermissionDenied, err)
+
+			return map[string]Result{}
+		}
+
+		s.logger.Warn(fmt.Sprintf("%v. FALLING BACK TO THE MOCK PINGER: THE RESULTS ARE SIMULATED, NO PACKET IS SENT.",
+			ErrPermissionDenied))
+
+		s.pinger = ping.NewMockPinger(nil)
+		s.Simulated = true
+		firstResult, err = s.pingHost(ctx, first)
+	}
+
+	if !errors.Is(err, errPacketCapReached) && !interrupted(ctx, err) {
+		if err != nil {
+			s.failedTargets.Add(1)
+		}
+
+		s.recordWorkerStat(0, time.Since(firstStart))
+		s.storeResult(ctx, store, first, firstResult)
+	}
+
+	if s.Adaptive {
+		s.scaler = newWorkerScaler(s.MaxWorkers)
+		defer func() { s.scaler = nil }()
+	}
+
+	// Spawn the worker goroutines.
+	for i := int64(0); i < int64(s.MaxWorkers); i++ {
+		wg.Add(1)
+		go s.startWorker(ctx, i, &wg, &pending, store, jobChannel)
+	}
+
+	if s.scaler != nil {
+		s.logger.Debug(fmt.Sprintf("Spawned %d workers, %d of them pinging at first.", s.MaxWorkers, s.scaler.limit))
+	} else {
+		s.logger.Debug(fmt.Sprintf("Spawned %d workers.", s.MaxWorkers))
+	}
+
+	s.logger.Debug("Assigning task to all workers.")
+	subnet := s.targetOf[s.origins[normalizeKey(first)]]
+assign:
+	for target, ok := s.nextTarget(it); ok && ctx.Err() == nil; target, ok = s.nextTarget(it) {
+		if origin := s.targetOf[s.origins[normalizeKey(target)]]; origin != subnet {
+			subnet = origin
+			if s.InterSubnetDelay > 0 {
+				s.logger.Debug("Waiting for the previous subnet to finish before starting the next one.", "subnet", subnet)
+				pending.Wait()
+				if !s.sleep(ctx, s.InterSubnetDelay) {
+					break assign
+				}
+			}
+		}
+
+		pending.Add(1)
+		select {
+		case jobChannel <- target:
+			s.trace("Assigned task.", "target", target)
+		case <-ctx.Done():
+			pending.Done()
+
+			break assign
+		}
+	}
+
+	s.logger.Debug("Waiting all workers finish their jobs.")
+	close(jobChannel)
+	wg.Wait()
+
+	s.logger.Debug("All workers already stopped. Storing the results.")
+	results := make(map[string]Result)
+
+	// The results written to the ResultStore of Run are left there.
+	if s.store != nil {
+		return results
+	}
+
+	store.Range(func(ip string, result Result) bool {
+		results[ip] = result
+
+		return true
+	})
+
+	return results
+}
+
+// interrupted reports whether the ping failing with err was given up because ctx is done.
+func interrupted(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() != nil
+}
+
+// mergeBestResults merges the results of a re-run of the scan into best, keeping the better result of each target:
+// the one with the most replies, or else the one of an attempt that was actually performed.
+func mergeBestResults(best, results map[string]Result) map[string]Result {
+	for ip, result := range results {
+		previous, ok := best[ip]
+		if !ok || result.PacketsRecv > previous.PacketsRecv ||
+			(result.PacketsRecv == previous.PacketsRecv && previous.PacketsSent == 0) {
+			best[ip] = result
+		}
+	}
+
+	return best
+}
+
+// nextTarget returns the next target yielded by it that is not excluded, and records the subnet it is taken from.
+// It returns false when it is exhausted.
+func (s *Subping) nextTarget(it *network.MultiSubnetHostsIterator) (string, bool) {
+	for ip, origin := it.Next(); ip != nil; ip, origin = it.Next() {
+		target := ip.String()
+		if _, ok := s.excluded[target]; ok {
+			s.trace("Skipped excluded target.", "target", target)
+			continue
+		}
+
+		s.origins[normalizeKey(target)] = origin.String()
+
+		return target, true
+	}
+
+	return "", false
+}
+
+// startWorker is a worker goroutine that performs the ping task assigned to it.
+// It collects the ping results and stores them in store, marking each task done in pending. Once ctx is done, the
+// remaining tasks are marked done without pinging their target.
+func (s *Subping) startWorker(ctx context.Context, id int64, wg, pending *sync.WaitGroup, store ResultStore,
+	c <-chan string) {
+	defer wg.Done()
+
+	for target := range c {
+		if ctx.Err() != nil {
+			pending.Done()
+
+			continue
+		}
+
+		s.trace("Got task.", "worker", id, "target", target)
+
+		if s.scaler != nil {
+			s.scaler.acquire()
+		}
+
+		pinged := s.work(ctx, id, store, target)
+		pending.Done()
+
+		if s.scaler != nil {
+			s.scaler.release()
+		}
+
+		if pinged {
+			s.sleep(ctx, s.nextInterval())
+		}
+	}
+}
+
+// work pings target on behalf of the worker id and stores its result in store. It returns false when the target
+// was not pinged because the MaxTotalPackets budget is exhausted or ctx is done.
+func (s *Subping) work(ctx context.Context, id int64, store ResultStore, target string) bool {
+	start := time.Now()
+	s.dispatch(target)
+
+	result, err := s.pingHost(ctx, target)
+	if errors.Is(err, errPacketCapReached) || interrupted(ctx, err) {
+		return false
+	}
+
+	if err != nil {
+		s.failedTargets.Add(1)
+	}
+
+	s.recordWorkerStat(id, time.Since(start))
+	s.observeOutcome(target, result)
+
+	s.storeResult(ctx, store, target, result)
+
+	return true
+}
+
+// recordWorkerStat accounts a target pinged in busy to the statistics of the worker id, when they are collected.
+func (s *Subping) recordWorkerStat(id int64, busy time.Duration) {
+	if s.workerStats == nil {
+		return
+	}
+
+	s.workerStats[id].Hosts++
+	s.workerStats[id].Busy += busy
+}
+
+// dispatch reports target to OnDispatch, under its normalized key.
+func (s *Subping) dispatch(target string) {
+	if s.OnDispatch != nil {
+		s.OnDispatch(normalizeKey(target))
+	}
+}
+
+// storeResult reports the result of target to OnResult, then stores it in store under its normalized key,
+// or sends it to the channel of Stream when streaming, unless ctx is done first.
+func (s *Subping) storeResult(ctx context.Context, store ResultStore, target string, result Result) {
+	defer s.doneTargets.Add(1)
+
+	if result.PacketsRecv > 0 {
+		s.onlineTargets.Add(1)
+	}
+
+	key := normalizeKey(target)
+
+	if s.OnResult != nil {
+		s.OnResult(key, result)
+	}
+
+	if s.stream != nil {
+		select {
+		case s.stream <- s.Host(key, result):
+		case <-ctx.Done():
+		}
+
+		return
+	}
+
+	store.Store(key, result)
+}
+
+// nextInterval returns the pause before the next target of a worker: Interval,
+// shifted by a uniformly distributed random duration in [-IntervalJitter, IntervalJitter].
+// The pause is never negative.
+func (s *Subping) nextInterval() time.Duration {
+	if s.IntervalJitter <= 0 {
+		return s.Interval
+	}
+
+	interval := s.Interval + time.Duration(rand.Int63n(int64(2*s.IntervalJitter)+1)) - s.IntervalJitter
+	if interval < 0 {
+		return 0
+	}
+
+	return interval
+}
+
+// targets returns an iterator over the hosts of first followed by the hosts of the additional subnets,
+// shuffled with Seed when Shuffle is enabled.
+func (s *Subping) targets(first *network.SubnetHostsIterator) *network.MultiSubnetHostsIterator {
+	iterators := []*network.SubnetHostsIterator{first}
+	for _, ipNet := range s.extraSubnets {
+		iterators = append(iterators, s.newSubnetIterator(ipNet))
+	}
+
+	return network.NewMultiSubnetHostsIterator(iterators...)
+}
+
+// TotalHosts returns the number of hosts to ping in all the scanned subnets. The excluded and known hosts are not
+// pinged, so they are not counted.
+func (s *Subping) TotalHosts() int {
+	total := hostCount(s.TargetsIterator.IPNet, s.Quick)
+	for _, ipNet := range s.extraSubnets {
+		total = addHostCounts(total, hostCount(ipNet, s.Quick))
+	}
+
+	if total == math.MaxInt {
+		return total
+	}
+
+	return total - s.excludedTargets()
+}
+
+// excludedTargets returns the number of excluded and known hosts among the hosts of the scanned subnets, only
+// counting their common hosts when Quick is set.
+func (s *Subping) excludedTargets() int {
+	if len(s.excluded) == 0 {
+		return 0
+	}
+
+	count := 0
+	for _, ipNet := range append([]*net.IPNet{s.TargetsIterator.IPNet}, s.extraSubnets...) {
+		if s.Quick {
+			for _, ip := range network.NewSubnetHostsIterator(ipNet).CommonHosts() {
+				if _, ok := s.excluded[ip.String()]; ok {
+					count++
+				}
+			}
+
+			continue
+		}
+
+		for ip := range s.excluded {
+			if ipNet.Contains(net.ParseIP(ip)) {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+// Progress returns the number of targets pinged so far by the current scan, or by the last one once it finished,
+// and the number of hosts to ping, e.g. to render a progress bar. It is safe to call while Run executes in another
+// goroutine. The count restarts at each scan, so a retried scan or a new round of Watch starts again from zero.
+func (s *Subping) Progress() (done, total int) {
+	return int(s.doneTargets.Load()), s.TotalHosts()
+}
+
+// hostCount returns the number of hosts to ping in ipNet, only counting its common hosts when quick is set.
+func hostCount(ipNet *net.IPNet, quick bool) int {
+	if quick {
+		return len(network.NewSubnetHostsIterator(ipNet).CommonHosts())
+	}
+
+	return network.CalculateTotalHosts(ipNet)
+}
+
+// addHostCounts returns the sum of the host counts a and b, clamped at math.MaxInt like CalculateTotalHosts instead
+// of overflowing when several large IPv6 subnets are scanned together.
+func addHostCounts(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+
+	return a + b
+}
+
+// NewIterator returns a fresh iterator over the configured subnet, shuffled with Seed when Shuffle is enabled
+// and restricted to its common hosts when Quick is enabled.
+// The returned iterator is independent of TargetsIterator, so it can be used to
+// build custom scan loops (for example together with PingHost) without affecting Run.
+func (s *Subping) NewIterator() *network.SubnetHostsIterator {
+	return s.newSubnetIterator(s.TargetsIterator.IPNet)
+}
+
+// newSubnetIterator returns a fresh iterator over ipNet, shuffled with Seed when Shuffle is enabled
+// and restricted to its common hosts when Quick is enabled.
+func (s *Subping) newSubnetIterator(ipNet *net.IPNet) *network.SubnetHostsIterator {
+	it := network.NewSubnetHostsIterator(ipNet)
+	if s.Shuffle {
+		it.Shuffle(s.Seed)
+	}
+
+	if s.Quick {
+		it.RestrictToCommonHosts()
+	}
+
+	return it
+}
+
+// PingHost pings a single target using the Count, Interval and Timeout configured
+// on the Subping instance and returns its result, including its Score.
+// A target that does not reply is retried up to Retries times, each attempt using
+// the matching entry of Timeouts. A target that lost packets is then confirmed with
+// ConfirmCount requests when it is set.
+func (s *Subping) PingHost(target string) Result {
+	result, _ := s.pingHost(context.Background(), target)
+
+	return result
+}
+
+// pingHost implements PingHost, giving up the remaining attempts once ctx is done. It also returns the error of the
+// last attempt when no attempt could be performed at all.
+func (s *Subping) pingHost(ctx context.Context, target string) (Result, error) {
+	var (
+		result    Result
+		performed bool
+		lastErr   error
+	)
+
+	var src string
+	if len(s.sources) > 0 {
+		if ip := net.ParseIP(target); ip != nil {
+			src = selectSource(ip, s.sources)
+		}
+	}
+
+	backoff := s.RetryBackoff
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		r, err := s.probe(ctx, target, src, s.Count, s.attemptTimeout(attempt))
+		if errors.Is(err, errPacketCapReached) {
+			lastErr = err
+			break
+		}
+		if err != nil {
+			s.logger.Debug(fmt.Sprintf("Attempt %d failed.", attempt+1), "target", target, "error", err)
+			lastErr = err
+
+			if attempt < s.Retries {
+				if !s.sleep(ctx, backoff) {
+					break
+				}
+				backoff *= 2
+			}
+
+			continue
+		}
+
+		result = r
+		performed = true
+		if result.PacketsRecv > 0 {
+			break
+		}
+	}
+
+	if performed && s.ConfirmCount > 0 && result.PacketLoss > 0 {
+		// The timeout covers all the requests of a ping, so it grows with the number of requests.
+		timeout := s.attemptTimeout(0) / time.Duration(s.Count) * time.Duration(s.ConfirmCount)
+
+		r, err := s.probe(ctx, target, src, s.ConfirmCount, timeout)
+		if err == nil {
+			result = r
+		} else {
+			s.logger.Debug("Confirmation failed.", "target", target, "error", err)
+		}
+	}
+
+	result.Score = Score(result, s.ScoreWeights)
+
+	if performed {
+		return result, nil
+	}
+
+	return result, lastErr
+}
+
+// probe pings target once from src with up to count requests, within the MaxTotalPackets budget.
+// It returns errPacketCapReached when the budget is exhausted.
+func (s *Subping) probe(ctx context.Context, target, src string, count int, timeout time.Duration) (Result, error) {
+	granted := s.reservePackets(count)
+	if granted == 0 {
+		return Result{}, errPacketCapReached
+	}
+
+	if err := s.waitForRate(ctx, granted); err != nil {
+		s.releasePackets(granted)
+
+		return Result{}, err
+	}
+
+	r, err := s.safePing(target, ping.Options{
+		Count:            granted,
+		Interval:         s.Interval,
+		Timeout:          timeout,
+		Privileged:       s.Privileged,
+		Source:           src,
+		StopOnFirstReply: s.StopOnFirstReply,
+		Size:             s.PayloadSize,
+		TTL:              s.TTL,
+		TOS:              s.TOS,
+	})
+	if err != nil {
+		return r, err
+	}
+
+	if s.StopOnFirstReply && r.PacketsSent < granted {
+		s.releasePackets(granted - r.PacketsSent)
+	}
+
+	s.recordTraffic(target, r)
+	r.Count = count
+
+	return r, nil
+}
+
+// safePing pings target with the pinger, converting a panic of a misbehaving Pinger into an error,
+// so that a single target cannot crash the worker pool.
+func (s *Subping) safePing(target string, opts ping.Options) (result Result, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.logger.Error(fmt.Sprintf("The pinger panicked: %v", p), "target", target)
+			err = fmt.Errorf("the pinger panicked on %s: %v", target, p)
+		}
+	}()
+
+	return s.pinger.Ping(target, opts)
+}
+
+// reservePackets reserves up to n packets of the MaxTotalPackets budget of the current run
+// and returns the number of packets granted, which is zero once the budget is exhausted.
+func (s *Subping) reservePackets(n int) int {
+	if s.MaxTotalPackets <= 0 {
+		return n
+	}
+
+	for {
+		sent := s.sentPackets.Load()
+
+		granted := int64(s.MaxTotalPackets) - sent
+		if granted >= int64(n) {
+			granted = int64(n)
+		} else {
+			s.packetCapReached.Store(true)
+		}
+
+		if granted <= 0 {
+			return 0
+		}
+
+		if s.sentPackets.CompareAndSwap(sent, sent+granted) {
+			return int(granted)
+		}
+	}
+}
+
+// resetPacketBudget gives a new run, or Watch round, the whole MaxTotalPackets budget. The passes of a run, its scan
+// retries included, share the budget.
+func (s *Subping) resetPacketBudget() {
+	s.sentPackets.Store(0)
+	s.packetCapReached.Store(false)
+}
+
+// releasePackets returns n reserved but unsent packets to the MaxTotalPackets budget of the current run.
+func (s *Subping) releasePackets(n int) {
+	if s.MaxTotalPackets > 0 {
+		s.sentPackets.Add(-int64(n))
+	}
+}
+
+// PacketCapReached reports whether MaxTotalPackets was reached during the last run,
+// in which case the results are partial.
+func (s *Subping) PacketCapReached() bool {
+	return s.packetCapReached.Load()
+}
+
+// Err returns the error that aborted the last run, or nil if it completed.
+// A run is aborted with ErrPermissionDenied when its first target failed with a permission error
+// and FallbackToMock is unset, and with ping.ErrTOSUnsupported when the pinger cannot mark the requests with TOS.
+func (s *Subping) Err() error {
+	return s.err
+}
+
+// attemptTimeout returns the timeout for the given zero-based attempt.
+// Attempts beyond the end of Timeouts are clamped to its last entry.
+func (s *Subping) attemptTimeout(attempt int) time.Duration {
+	if len(s.Timeouts) == 0 {
+		return s.Timeout
+	}
+
+	if attempt >= len(s.Timeouts) {
+		attempt = len(s.Timeouts) - 1
+	}
+
+	return s.Timeouts[attempt]
+}
+
+// GetOnlineHosts returns a map of online hosts and their corresponding ping results,
+// as well as the total number of online hosts. It is safe to call GetOnlineHosts while Run is in progress, e.g.
+// for a live dashboard: it then returns a snapshot of the hosts found online so far. With a ResultStore, the results
+// are not read back from the store, so it returns no host; Summary still counts the online hosts.
+func (s *Subping) GetOnlineHosts() (map[string]Result, int) {
+	r := make(map[string]Result)
+
+	for ip, stats := range s.snapshot() {
+		if stats.PacketsRecv > 0 {
+			r[ip] = stats
+		}
+	}
+
+	return r, len(r)
+}
+
+// FilterResults returns the results of the last run satisfying pred, keyed by IP address. Results is left untouched,
+// so the filtered out hosts still count in Summary. Like GetOnlineHosts, it is safe to call while Run is in progress,
+// and it returns no host with a ResultStore.
+func (s *Subping) FilterResults(pred func(Result) bool) map[string]Result {
+	r := make(map[string]Result)
+
+	for ip, result := range s.snapshot() {
+		if pred(result) {
+			r[ip] = result
+		}
+	}
+
+	return r
+}
+
+// RunPing performs a ping operation to the specified IP address.
+// It sends the specified number of ping requests with the given interval and timeout.
+func RunPing(ipAddress string, count int, interval time.Duration, timeout time.Duration) probing.Statistics {
+	pinger, err := probing.NewPinger(ipAddress)
+	if err != nil {
+		logrus.Printf("Failed to create pinger for IP Address: %s\n", ipAddress)
+		return probing.Statistics{}
+	}
+
+	pinger.Count = count
+	pinger.Interval = interval
+
+	if timeout > 0 {
+		pinger.Timeout = timeout
+	}
+
+	if runtime.GOOS == "windows" {
+		pinger.SetPrivileged(true)
+	}
+
+	err = pinger.Run()
+	if err != nil {
+		logrus.Printf("Failed to ping the address %s, %v\n", ipAddress, err.Error())
+		return probing.Statistics{}
+	}
+
+	return *pinger.Statistics()
+}
+
+// newIPSet builds a set of normalized IP addresses from the given list.
+func newIPSet(ips []string) (map[string]struct{}, error) {
+	set := make(map[string]struct{}, len(ips))
+
+	for _, ip := range ips {
+		normalized, err := network.NormalizeIP(ip)
+		if err != nil {
+			return nil, err
+		}
+
+		set[normalized] = struct{}{}
+	}
+
+	return set, nil
+}
+
+// checkRangeSize returns an error when ipNet holds more than maxHosts hosts and large ranges are not allowed, or
+// more hosts than an int can count in any case. The number of hosts is computed exactly, since it overflows an int
+// for large IPv6 networks.
+func checkRangeSize(ipNet *net.IPNet, maxHosts int, allowLargeRanges bool) error {
+	ones, bits := ipNet.Mask.Size()
+	hosts := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+
+	if !network.HostCountFits(ipNet) {
+		return fmt.Errorf("subnet %s holds %s hosts, which is too large to scan even when large ranges are allowed: %w",
+			ipNet, hosts, network.ErrTooManyHosts)
+	}
+
+	if allowLargeRanges {
+		return nil
+	}
+
+	if hosts.Cmp(big.NewInt(int64(maxHosts))) > 0 {
+		return fmt.Errorf("subnet %s holds %s hosts, which exceeds the safety threshold of %d hosts: "+
+			"scan a smaller range, raise the threshold or explicitly allow large ranges", ipNet, hosts, maxHosts)
+	}
+
+	return nil
+}
+
+// splitSubnetList splits a comma separated list of subnets into its first subnet and the additional subnets,
+// which precede subnets.
+func splitSubnetList(subnet string, subnets []string) (string, []string) {
+	parts := strings.Split(subnet, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	return parts[0], append(parts[1:], subnets...)
+}
+
+// expandRanges replaces the ranges, IPv4 wildcards and single IP addresses among the given subnets by the subnets
+// covering them, in CIDR notation, and returns the given subnet or range each resulting subnet is taken from, keyed by
+// canonical CIDR. The entries that are none of them are left as they are, to be reported by parseSubnets.
+func expandRanges(targets []string) ([]string, map[string]string) {
+	cidrs := make([]string, 0, len(targets))
+	targetOf := make(map[string]string, len(targets))
+
+	for _, target := range targets {
+		spec, err := network.ParseTargetSpec(target)
+		if err != nil || (spec.Kind != network.TargetRange && spec.Kind != network.TargetWildcard &&
+			spec.Kind != network.TargetIP) {
+			cidrs = append(cidrs, target)
+			if _, ipNet, err := net.ParseCIDR(target); err == nil {
+				targetOf[ipNet.String()] = target
+			}
+
+			continue
+		}
+
+		for _, ipNet := range spec.IPNets() {
+			cidrs = append(cidrs, ipNet.String())
+			targetOf[ipNet.String()] = target
+		}
+	}
+
+	return cidrs, targetOf
+}
+
+// parseSubnets parses the additional subnets, refusing those overlapping each other or first.
+func parseSubnets(first *net.IPNet, cidrs []string) ([]*net.IPNet, error) {
+	subnets := make([]*net.IPNet, 0, len(cidrs))
+	seen := []*net.IPNet{first}
+
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet %q: %w", cidr, err)
+		}
+
+		for _, other := range seen {
+			if other.Contains(ipNet.IP) || ipNet.Contains(other.IP) {
+				return nil, fmt.Errorf("subnet %s overlaps subnet %s", ipNet, other)
+			}
+		}
+
+		seen = append(seen, ipNet)
+		subnets = append(subnets, ipNet)
+	}
+
+	return subnets, nil
+}
+
+// normalizeLabels returns a copy of labels keyed by normalized IP address.
+func normalizeLabels(labels map[string]map[string]string) (map[string]map[string]string, error) {
+	normalized := make(map[string]map[string]string, len(labels))
+
+	for ip, l := range labels {
+		key, err := network.NormalizeIP(ip)
+		if err != nil {
+			return nil, fmt.Errorf("invalid labeled host: %w", err)
+		}
+
+		normalized[key] = l
+	}
+
+	return normalized, nil
+}
+
+// normalizeKey returns the canonical form of target to be used as a result key.
+// Targets that are not IP addresses are returned unchanged.
+func normalizeKey(target string) string {
+	if normalized, err := network.NormalizeIP(target); err == nil {
+		return normalized
+	}
+
+	return target
+}
 
-	if remainder != 0 {
-		maxPartitionSize++
+// defaultJobBufferSize returns the number of hosts each worker pings when totalHosts are spread evenly among workers,
+// rounded up, so that every worker has a target queued while the others are busy. It is at least 1, so the job
+// channel is never unbuffered.
+func defaultJobBufferSize(totalHosts int, workers int) int {
+	// Written to round up without overflowing when totalHosts is close to math.MaxInt.
+	size := totalHosts / workers
+	if totalHosts%workers != 0 {
+		size++
 	}
 
-	if maxPartitionSize < 0 {
-		return 0, errors.New("the value exceeds the range of int")
+	if size < 1 {
+		return 1
 	}
 
-	return maxPartitionSize, nil
+	return size
 }
@@ -1,12 +1,27 @@
 package subping_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/export"
 	"github.com/fadhilyori/subping/pkg/network"
+	"github.com/fadhilyori/subping/pkg/ping"
 )
 
 func TestRunSubping(t *testing.T) {
@@ -226,3 +241,2006 @@ func TestRunPing(t *testing.T) {
 		})
 	}
 }
+
+func TestNewIteratorWithPingHost(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "127.0.0.0/30",
+		Count:      1,
+		Interval:   100 * time.Millisecond,
+		Timeout:    300 * time.Millisecond,
+		MaxWorkers: 1,
+		LogLevel:   "error",
+		Pinger:     &stubPinger{online: map[string]time.Duration{"127.0.0.1": time.Millisecond}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	// Exhaust the instance iterator to make sure NewIterator is independent of it.
+	for ip := sp.TargetsIterator.Next(); ip != nil; ip = sp.TargetsIterator.Next() {
+	}
+
+	results := make(map[string]subping.Result)
+	it := sp.NewIterator()
+
+	for ip := it.Next(); ip != nil; ip = it.Next() {
+		results[ip.String()] = sp.PingHost(ip.String())
+	}
+
+	if len(results) != it.TotalHosts {
+		t.Fatalf("NewIterator() yielded %d hosts, want %d", len(results), it.TotalHosts)
+	}
+
+	if r := results["127.0.0.1"]; r.PacketsRecv != 1 {
+		t.Errorf("PingHost(127.0.0.1) PacketsRecv = %v, want 1", r.PacketsRecv)
+	}
+}
+
+// recordingPinger is a ping.Pinger that never gets a reply and records the options of every call.
+type recordingPinger struct {
+	mu    sync.Mutex
+	calls []ping.Options
+}
+
+func (p *recordingPinger) Ping(_ string, opts ping.Options) (ping.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls = append(p.calls, opts)
+
+	return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+}
+
+func TestPayloadSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		payloadSize int
+		want        int
+		wantErr     bool
+	}{
+		{name: "Default", payloadSize: 0, want: ping.DefaultPayloadSize},
+		{name: "Larger payload", payloadSize: 1472, want: 1472},
+		{name: "Largest payload", payloadSize: ping.MaxPayloadSize, want: ping.MaxPayloadSize},
+		{name: "Too small", payloadSize: ping.DefaultPayloadSize - 1, wantErr: true},
+		{name: "Too large", payloadSize: ping.MaxPayloadSize + 1, wantErr: true},
+		{name: "Negative", payloadSize: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := &recordingPinger{}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:      "10.0.0.1/32",
+				Count:       1,
+				MaxWorkers:  1,
+				PayloadSize: tt.payloadSize,
+				Pinger:      pinger,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			sp.Run()
+
+			if len(pinger.calls) == 0 {
+				t.Fatal("Ping() was never called")
+			}
+
+			for _, opts := range pinger.calls {
+				if opts.Size != tt.want {
+					t.Errorf("Ping() called with Size = %d, want %d", opts.Size, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     int
+		wantErr bool
+	}{
+		{name: "Default", ttl: 0},
+		{name: "Low TTL", ttl: 3},
+		{name: "Largest TTL", ttl: ping.MaxTTL},
+		{name: "Too large", ttl: ping.MaxTTL + 1, wantErr: true},
+		{name: "Negative", ttl: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     "10.0.0.0/31",
+				Count:      1,
+				MaxWorkers: 1,
+				TTL:        tt.ttl,
+				Pinger: ping.NewMockPinger(map[string]ping.MockHostConfig{
+					"10.0.0.1": {Online: true, Latency: time.Millisecond},
+				}),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			sp.Run()
+
+			if got := sp.Results["10.0.0.1"].TTL; got != tt.ttl {
+				t.Errorf("TTL of 10.0.0.1 = %d, want %d", got, tt.ttl)
+			}
+
+			if got := sp.Results["10.0.0.0"].TTL; got != 0 {
+				t.Errorf("TTL of the offline 10.0.0.0 = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestTOS(t *testing.T) {
+	tests := []struct {
+		name    string
+		tos     int
+		wantErr bool
+	}{
+		{name: "Unmarked", tos: 0},
+		{name: "EF DSCP", tos: 184},
+		{name: "Largest TOS", tos: ping.MaxTOS},
+		{name: "Too large", tos: ping.MaxTOS + 1, wantErr: true},
+		{name: "Negative", tos: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := &recordingPinger{}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     "10.0.0.0/31",
+				Count:      1,
+				MaxWorkers: 1,
+				TOS:        tt.tos,
+				Pinger:     pinger,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			sp.Run()
+
+			for _, opts := range pinger.calls {
+				if opts.TOS != tt.tos {
+					t.Errorf("Ping() got TOS %d, want %d", opts.TOS, tt.tos)
+				}
+			}
+		})
+	}
+}
+
+func TestTOSUnsupported(t *testing.T) {
+	// The ICMP pinger cannot mark the requests.
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "127.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 2,
+		Timeout:    time.Second,
+		TOS:        184,
+		Clock:      &recordingClock{},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	if !errors.Is(sp.Err(), ping.ErrTOSUnsupported) {
+		t.Errorf("Err() = %v, want %v", sp.Err(), ping.ErrTOSUnsupported)
+	}
+
+	if sp.TotalResults != 0 {
+		t.Errorf("TotalResults = %d, want the scan aborted on the first target", sp.TotalResults)
+	}
+}
+
+func TestPingHostStagedTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		timeouts []time.Duration
+		retries  int
+		want     []time.Duration
+	}{
+		{
+			name:     "One timeout per attempt",
+			timeouts: []time.Duration{100 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second},
+			retries:  2,
+			want:     []time.Duration{100 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second},
+		},
+		{
+			name:     "Extra attempts clamp to the last timeout",
+			timeouts: []time.Duration{100 * time.Millisecond, 500 * time.Millisecond},
+			retries:  3,
+			want:     []time.Duration{100 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name:    "Without timeouts list",
+			timeout: time.Second,
+			retries: 1,
+			want:    []time.Duration{time.Second, time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := &recordingPinger{}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     "127.0.0.1/32",
+				Count:      1,
+				Timeout:    tt.timeout,
+				Timeouts:   tt.timeouts,
+				Retries:    tt.retries,
+				MaxWorkers: 1,
+				Pinger:     pinger,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.PingHost("127.0.0.1")
+
+			if len(pinger.calls) != len(tt.want) {
+				t.Fatalf("PingHost() made %d attempts, want %d", len(pinger.calls), len(tt.want))
+			}
+
+			for i, call := range pinger.calls {
+				if call.Timeout != tt.want[i] {
+					t.Errorf("PingHost() attempt %d timeout = %v, want %v", i+1, call.Timeout, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// stubPinger is a ping.Pinger that reports the configured targets as online with the given RTT
+// and every other target as offline.
+type stubPinger struct {
+	online map[string]time.Duration
+}
+
+func (p *stubPinger) Ping(target string, opts ping.Options) (ping.Result, error) {
+	rtt, ok := p.online[target]
+	if !ok {
+		return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+	}
+
+	return ping.Result{
+		AvgRtt:      rtt,
+		PacketsSent: opts.Count,
+		PacketsRecv: opts.Count,
+	}, nil
+}
+
+func TestExcludeNormalizesIPv6(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "2001:db8::/126",
+		Count:      1,
+		MaxWorkers: 2,
+		Exclude:    []string{"2001:0DB8:0000::0001", "2001:db8:0:0:0:0:0:2"},
+		Pinger:     &stubPinger{online: map[string]time.Duration{"2001:db8::3": time.Millisecond}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	var got []string
+	for ip := range sp.Results {
+		got = append(got, ip)
+	}
+	sort.Strings(got)
+
+	want := []string{"2001:db8::", "2001:db8::3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Run() result keys = %v, want %v", got, want)
+	}
+}
+
+func TestKnownHostsAreSkipped(t *testing.T) {
+	pinger := &recordingPinger{}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/28",
+		Count:      1,
+		MaxWorkers: 4,
+		KnownHosts: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+		Pinger:     pinger,
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	if sp.TotalResults != 12 {
+		t.Errorf("Run() TotalResults = %v, want 12", sp.TotalResults)
+	}
+
+	if len(pinger.calls) != 12 {
+		t.Errorf("Run() pinged %d targets, want 12", len(pinger.calls))
+	}
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		if _, ok := sp.Results[ip]; ok {
+			t.Errorf("Run() Results contains known host %s", ip)
+		}
+	}
+}
+
+// privilegedPinger is a ping.Pinger on which targets only reply to privileged pings.
+type privilegedPinger struct{}
+
+func (privilegedPinger) Ping(_ string, opts ping.Options) (ping.Result, error) {
+	if !opts.Privileged {
+		return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+	}
+
+	return ping.Result{AvgRtt: time.Millisecond, PacketsSent: opts.Count, PacketsRecv: opts.Count}, nil
+}
+
+func TestRetryOnAllOffline(t *testing.T) {
+	tests := []struct {
+		name              string
+		pinger            ping.Pinger
+		retryOnAllOffline bool
+		retryPrivileged   bool
+		wantOnline        int
+	}{
+		{
+			name:              "First pass fails and retry succeeds",
+			pinger:            &sequencePinger{up: map[string][]bool{"10.0.0.1": {false, true}, "10.0.0.2": {false, true}}, calls: make(map[string]int)},
+			retryOnAllOffline: true,
+			wantOnline:        2,
+		},
+		{
+			name:              "Without retry",
+			pinger:            &sequencePinger{up: map[string][]bool{"10.0.0.1": {false, true}, "10.0.0.2": {false, true}}, calls: make(map[string]int)},
+			retryOnAllOffline: false,
+			wantOnline:        0,
+		},
+		{
+			name:              "Retry in privileged mode",
+			pinger:            privilegedPinger{},
+			retryOnAllOffline: true,
+			retryPrivileged:   true,
+			wantOnline:        4,
+		},
+		{
+			name:              "Retry without privileged mode",
+			pinger:            privilegedPinger{},
+			retryOnAllOffline: true,
+			wantOnline:        0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:            "10.0.0.0/30",
+				Count:             1,
+				MaxWorkers:        2,
+				Pinger:            tt.pinger,
+				RetryOnAllOffline: tt.retryOnAllOffline,
+				RetryPrivileged:   tt.retryPrivileged,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			if _, online := sp.GetOnlineHosts(); online != tt.wantOnline {
+				t.Errorf("Run() online hosts = %v, want %v", online, tt.wantOnline)
+			}
+
+			if sp.TotalResults != 4 {
+				t.Errorf("Run() TotalResults = %v, want 4", sp.TotalResults)
+			}
+		})
+	}
+}
+
+// recordingClock is a subping.Clock that records the requested pauses instead of sleeping.
+// Its time only advances when it sleeps.
+type recordingClock struct {
+	mu     sync.Mutex
+	sleeps []time.Duration
+	now    time.Time
+}
+
+func (c *recordingClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+func (c *recordingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func TestIntervalJitter(t *testing.T) {
+	const (
+		interval = 100 * time.Millisecond
+		jitter   = 30 * time.Millisecond
+	)
+
+	clock := &recordingClock{}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:         "10.0.0.0/24",
+		Count:          1,
+		Interval:       interval,
+		IntervalJitter: jitter,
+		MaxWorkers:     4,
+		Pinger:         &stubPinger{},
+		Clock:          clock,
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	// The first target is pinged before the workers are spawned, without pausing.
+	if len(clock.sleeps) != sp.TotalResults-1 {
+		t.Fatalf("got %d pauses, want one per target pinged by a worker (%d)", len(clock.sleeps), sp.TotalResults-1)
+	}
+
+	distinct := make(map[time.Duration]struct{})
+	for _, d := range clock.sleeps {
+		if d < interval-jitter || d > interval+jitter {
+			t.Errorf("pause %v is outside of [%v, %v]", d, interval-jitter, interval+jitter)
+		}
+
+		distinct[d] = struct{}{}
+	}
+
+	if len(distinct) < 2 {
+		t.Errorf("got %d distinct pauses, want the jitter to vary them", len(distinct))
+	}
+}
+
+func TestIntervalJitterNegative(t *testing.T) {
+	_, err := subping.NewSubping(&subping.Options{
+		Subnet:         "10.0.0.0/30",
+		Count:          1,
+		IntervalJitter: -time.Millisecond,
+		MaxWorkers:     1,
+		Pinger:         &stubPinger{},
+	})
+	if err == nil {
+		t.Error("NewSubping() error = nil, want an error for a negative interval jitter")
+	}
+}
+
+// deniedPinger is a ping.Pinger that is never permitted to send packets. It counts its calls.
+type deniedPinger struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *deniedPinger) Ping(target string, _ ping.Options) (ping.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls++
+
+	return ping.Result{}, fmt.Errorf("failed to ping %s: %w", target, os.ErrPermission)
+}
+
+func TestPermissionDeniedOnFirstPing(t *testing.T) {
+	tests := []struct {
+		name             string
+		fallbackToMock   bool
+		wantErr          error
+		wantSimulated    bool
+		wantTotalResults int
+		wantCalls        int
+	}{
+		{
+			name:             "Aborts with a privilege message",
+			fallbackToMock:   false,
+			wantErr:          subping.ErrPermissionDenied,
+			wantSimulated:    false,
+			wantTotalResults: 0,
+			wantCalls:        1,
+		},
+		{
+			name:             "Falls back to the mock pinger",
+			fallbackToMock:   true,
+			wantErr:          nil,
+			wantSimulated:    true,
+			wantTotalResults: 8,
+			wantCalls:        1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := &deniedPinger{}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:         "10.0.0.0/29",
+				Count:          1,
+				MaxWorkers:     2,
+				Pinger:         pinger,
+				FallbackToMock: tt.fallbackToMock,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			if !errors.Is(sp.Err(), tt.wantErr) || (tt.wantErr == nil && sp.Err() != nil) {
+				t.Errorf("Err() = %v, want %v", sp.Err(), tt.wantErr)
+			}
+
+			if sp.Simulated != tt.wantSimulated {
+				t.Errorf("Simulated = %v, want %v", sp.Simulated, tt.wantSimulated)
+			}
+
+			if got := sp.Summary().Simulated; got != tt.wantSimulated {
+				t.Errorf("Summary().Simulated = %v, want %v", got, tt.wantSimulated)
+			}
+
+			if sp.TotalResults != tt.wantTotalResults {
+				t.Errorf("TotalResults = %d, want %d", sp.TotalResults, tt.wantTotalResults)
+			}
+
+			if pinger.calls != tt.wantCalls {
+				t.Errorf("the real pinger was called %d times, want %d", pinger.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// countingPinger is a ping.Pinger on which every target replies. It counts the requested packets.
+type countingPinger struct {
+	packets atomic.Int64
+}
+
+func (p *countingPinger) Ping(_ string, opts ping.Options) (ping.Result, error) {
+	p.packets.Add(int64(opts.Count))
+
+	return ping.Result{AvgRtt: time.Millisecond, PacketsSent: opts.Count, PacketsRecv: opts.Count}, nil
+}
+
+func TestMaxTotalPackets(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxTotalPackets  int
+		wantPackets      int64
+		wantTotalResults int
+		wantCapReached   bool
+	}{
+		{
+			name:             "Cap stops the scan",
+			maxTotalPackets:  10,
+			wantPackets:      10,
+			wantTotalResults: 4,
+			wantCapReached:   true,
+		},
+		{
+			name:             "Cap above the scan volume",
+			maxTotalPackets:  100,
+			wantPackets:      48,
+			wantTotalResults: 16,
+			wantCapReached:   false,
+		},
+		{
+			name:             "No cap",
+			maxTotalPackets:  0,
+			wantPackets:      48,
+			wantTotalResults: 16,
+			wantCapReached:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := &countingPinger{}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:          "10.0.0.0/28",
+				Count:           3,
+				MaxWorkers:      4,
+				Pinger:          pinger,
+				MaxTotalPackets: tt.maxTotalPackets,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			if got := pinger.packets.Load(); got != tt.wantPackets {
+				t.Errorf("requested %d packets, want %d", got, tt.wantPackets)
+			}
+
+			if sp.TotalResults != tt.wantTotalResults {
+				t.Errorf("TotalResults = %d, want %d", sp.TotalResults, tt.wantTotalResults)
+			}
+
+			if sp.PacketCapReached() != tt.wantCapReached {
+				t.Errorf("PacketCapReached() = %v, want %v", sp.PacketCapReached(), tt.wantCapReached)
+			}
+		})
+	}
+}
+
+// offlineCountingPinger is a ping.Pinger on which no target replies, or every ping fails when fail is set. It counts
+// the requested packets.
+type offlineCountingPinger struct {
+	fail    bool
+	packets atomic.Int64
+}
+
+func (p *offlineCountingPinger) Ping(target string, opts ping.Options) (ping.Result, error) {
+	p.packets.Add(int64(opts.Count))
+
+	if p.fail {
+		return ping.Result{}, fmt.Errorf("failed to ping %s: network is unreachable", target)
+	}
+
+	return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+}
+
+func TestMaxTotalPacketsAcrossScanRetries(t *testing.T) {
+	tests := []struct {
+		name              string
+		fail              bool
+		scanRetries       int
+		retryOnAllOffline bool
+	}{
+		{name: "Retry on all offline", retryOnAllOffline: true},
+		{name: "Scan retries", fail: true, scanRetries: 3},
+		{name: "Both", fail: true, scanRetries: 3, retryOnAllOffline: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := &offlineCountingPinger{fail: tt.fail}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:            "10.0.0.0/28",
+				Count:             1,
+				MaxWorkers:        4,
+				MaxTotalPackets:   5,
+				ScanRetries:       tt.scanRetries,
+				RetryOnAllOffline: tt.retryOnAllOffline,
+				Clock:             &recordingClock{},
+				Pinger:            pinger,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			if got := pinger.packets.Load(); got != 5 {
+				t.Errorf("requested %d packets, want the 5 packets of MaxTotalPackets", got)
+			}
+
+			if !sp.PacketCapReached() {
+				t.Error("PacketCapReached() = false, want true")
+			}
+		})
+	}
+}
+
+func TestNewSubpingKeepsOptions(t *testing.T) {
+	opts := &subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 16,
+		Pinger:     ping.NewMockPinger(nil),
+	}
+	want := *opts
+
+	for i := 0; i < 2; i++ {
+		if _, err := subping.NewSubping(opts); err != nil {
+			t.Fatalf("NewSubping() error = %v", err)
+		}
+
+		// Options holds funcs and interfaces, so it is compared through its printed form.
+		if got := fmt.Sprintf("%+v", *opts); got != fmt.Sprintf("%+v", want) {
+			t.Fatalf("NewSubping() modified the options:\ngot  %s\nwant %+v", got, want)
+		}
+	}
+}
+
+func TestRangeSizeSafetyThreshold(t *testing.T) {
+	tests := []struct {
+		name             string
+		subnet           string
+		maxHosts         int
+		allowLargeRanges bool
+		wantErr          bool
+	}{
+		{
+			name:    "Whole IPv4 space is refused",
+			subnet:  "0.0.0.0/0",
+			wantErr: true,
+		},
+		{
+			name:    "/8 is over the default threshold",
+			subnet:  "10.0.0.0/8",
+			wantErr: true,
+		},
+		{
+			name:    "/24 is under the default threshold",
+			subnet:  "10.0.0.0/24",
+			wantErr: false,
+		},
+		{
+			name:    "Huge IPv6 range is refused",
+			subnet:  "2001:db8::/32",
+			wantErr: true,
+		},
+		{
+			name:     "/24 is over a custom threshold",
+			subnet:   "10.0.0.0/24",
+			maxHosts: 100,
+			wantErr:  true,
+		},
+		{
+			name:             "/8 is allowed explicitly",
+			subnet:           "10.0.0.0/8",
+			allowLargeRanges: true,
+			wantErr:          false,
+		},
+		{
+			name:             "IPv6 /64 is refused even when allowed explicitly",
+			subnet:           "2001:db8::/64",
+			allowLargeRanges: true,
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := subping.NewSubping(&subping.Options{
+				Subnet:           tt.subnet,
+				Count:            1,
+				MaxWorkers:       1,
+				Pinger:           &stubPinger{},
+				MaxHosts:         tt.maxHosts,
+				AllowLargeRanges: tt.allowLargeRanges,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// flakyPinger is a pinger whose targets all fail on the first call, as on a dropping link, except 10.0.0.3 which only
+// replies to the first call. On the following calls, 10.0.0.1 and 10.0.0.2 reply.
+type flakyPinger struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (p *flakyPinger) Ping(target string, opts ping.Options) (ping.Result, error) {
+	p.mu.Lock()
+	p.calls[target]++
+	call := p.calls[target]
+	p.mu.Unlock()
+
+	online := ping.Result{AvgRtt: time.Millisecond, PacketsSent: opts.Count, PacketsRecv: opts.Count}
+	offline := ping.Result{PacketsSent: opts.Count, PacketLoss: 100}
+
+	switch {
+	case target == "10.0.0.3" && call == 1:
+		return online, nil
+	case call == 1:
+		return ping.Result{}, errors.New("sendto: network is unreachable")
+	case target == "10.0.0.1" || target == "10.0.0.2":
+		return online, nil
+	default:
+		return offline, nil
+	}
+}
+
+func TestScanRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		scanRetries int
+		wantOnline  []string
+		wantBackoff []time.Duration
+	}{
+		{
+			name:        "Without scan retries",
+			scanRetries: 0,
+			wantOnline:  []string{"10.0.0.3"},
+		},
+		{
+			name:        "Re-run keeps the best results",
+			scanRetries: 3,
+			wantOnline:  []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+			wantBackoff: []time.Duration{subping.DefaultScanRetryBackoff},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &recordingClock{}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:      "10.0.0.0/30",
+				Count:       1,
+				MaxWorkers:  2,
+				Pinger:      &flakyPinger{calls: make(map[string]int)},
+				Clock:       clock,
+				ScanRetries: tt.scanRetries,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			if sp.TotalResults != 4 {
+				t.Errorf("TotalResults = %d, want 4", sp.TotalResults)
+			}
+
+			online, _ := sp.GetOnlineHosts()
+
+			var got []string
+			for ip := range online {
+				got = append(got, ip)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tt.wantOnline) {
+				t.Errorf("online hosts = %v, want %v", got, tt.wantOnline)
+			}
+
+			var backoff []time.Duration
+			for _, d := range clock.sleeps {
+				if d > 0 {
+					backoff = append(backoff, d)
+				}
+			}
+
+			if !reflect.DeepEqual(backoff, tt.wantBackoff) {
+				t.Errorf("backoff pauses = %v, want %v", backoff, tt.wantBackoff)
+			}
+		})
+	}
+}
+
+// isolatedFailurePinger is a pinger on which every target replies, except 10.0.0.5 whose ping always fails.
+type isolatedFailurePinger struct {
+	calls atomic.Int64
+}
+
+func (p *isolatedFailurePinger) Ping(target string, opts ping.Options) (ping.Result, error) {
+	p.calls.Add(1)
+
+	if target == "10.0.0.5" {
+		return ping.Result{}, errors.New("sendto: no buffer space available")
+	}
+
+	return ping.Result{AvgRtt: time.Millisecond, PacketsSent: opts.Count, PacketsRecv: opts.Count}, nil
+}
+
+func TestScanRetriesIgnoreIsolatedFailures(t *testing.T) {
+	clock := &recordingClock{}
+	pinger := &isolatedFailurePinger{}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:      "10.0.0.0/28",
+		Count:       1,
+		MaxWorkers:  4,
+		Pinger:      pinger,
+		Clock:       clock,
+		ScanRetries: 3,
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	if got := pinger.calls.Load(); got != 16 {
+		t.Errorf("Ping() called %d times, want 16: a single failing host must not re-run the scan", got)
+	}
+
+	for _, d := range clock.sleeps {
+		if d > 0 {
+			t.Errorf("Run() paused %s before re-running the scan, want no re-run", d)
+		}
+	}
+
+	if _, online := sp.GetOnlineHosts(); online != 15 {
+		t.Errorf("GetOnlineHosts() = %d hosts, want 15", online)
+	}
+}
+
+func TestScanRetriesBackoff(t *testing.T) {
+	clock := &recordingClock{}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:           "10.0.0.2/32",
+		Count:            1,
+		MaxWorkers:       1,
+		Pinger:           &errorPinger{},
+		Clock:            clock,
+		ScanRetries:      3,
+		ScanRetryBackoff: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}
+	if !reflect.DeepEqual(clock.sleeps, want) {
+		t.Errorf("backoff pauses = %v, want %v", clock.sleeps, want)
+	}
+}
+
+func TestRetriesOnFailure(t *testing.T) {
+	tests := []struct {
+		name        string
+		retries     int
+		wantOnline  bool
+		wantBackoff []time.Duration
+	}{
+		{
+			name:        "Retries outlast the failures",
+			retries:     2,
+			wantOnline:  true,
+			wantBackoff: []time.Duration{subping.DefaultRetryBackoff, 2 * subping.DefaultRetryBackoff},
+		},
+		{
+			name:        "Retries exhausted",
+			retries:     1,
+			wantOnline:  false,
+			wantBackoff: []time.Duration{subping.DefaultRetryBackoff},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &recordingClock{}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     "10.0.0.1/32",
+				Count:      1,
+				MaxWorkers: 1,
+				Retries:    tt.retries,
+				Clock:      clock,
+				Pinger: ping.NewMockPinger(map[string]ping.MockHostConfig{
+					"10.0.0.1": {Online: true, Latency: time.Millisecond, FailFirst: 2},
+				}),
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			if online := sp.Results["10.0.0.1"].PacketsRecv > 0; online != tt.wantOnline {
+				t.Errorf("10.0.0.1 online = %v, want %v", online, tt.wantOnline)
+			}
+
+			var backoff []time.Duration
+			for _, d := range clock.sleeps {
+				if d > 0 {
+					backoff = append(backoff, d)
+				}
+			}
+
+			if !reflect.DeepEqual(backoff, tt.wantBackoff) {
+				t.Errorf("backoff pauses = %v, want %v", backoff, tt.wantBackoff)
+			}
+		})
+	}
+}
+
+// errorPinger is a pinger failing every call.
+type errorPinger struct{}
+
+func (errorPinger) Ping(string, ping.Options) (ping.Result, error) {
+	return ping.Result{}, errors.New("sendto: network is unreachable")
+}
+
+// panickingPinger is a buggy ping.Pinger panicking on the targets of panics and reporting the others online.
+type panickingPinger struct {
+	panics map[string]bool
+}
+
+func (p *panickingPinger) Ping(target string, opts ping.Options) (ping.Result, error) {
+	if p.panics[target] {
+		panic("index out of range")
+	}
+
+	return ping.Result{AvgRtt: time.Millisecond, PacketsSent: opts.Count, PacketsRecv: opts.Count}, nil
+}
+
+func TestPanickingPinger(t *testing.T) {
+	tests := []struct {
+		name   string
+		panics string
+	}{
+		{
+			name:   "Panic on the first target",
+			panics: "10.0.0.0",
+		},
+		{
+			name:   "Panic in a worker",
+			panics: "10.0.0.2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     "10.0.0.0/30",
+				Count:      1,
+				MaxWorkers: 2,
+				Pinger:     &panickingPinger{panics: map[string]bool{tt.panics: true}},
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			if err := sp.Err(); err != nil {
+				t.Errorf("Err() = %v, want nil", err)
+			}
+
+			if sp.TotalResults != 4 {
+				t.Errorf("TotalResults = %d, want 4", sp.TotalResults)
+			}
+
+			for ip, result := range sp.Results {
+				if online := result.PacketsRecv > 0; online == (ip == tt.panics) {
+					t.Errorf("Results[%s] online = %t, want %t", ip, online, ip != tt.panics)
+				}
+			}
+		})
+	}
+}
+
+func TestStopOnFirstReply(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:           "10.0.0.0/30",
+		Count:            3,
+		MaxWorkers:       1,
+		StopOnFirstReply: true,
+		MaxTotalPackets:  6,
+		Pinger: ping.NewMockPinger(map[string]ping.MockHostConfig{
+			"10.0.0.0": {Online: true, Latency: time.Millisecond},
+			"10.0.0.1": {Online: true, Latency: time.Millisecond},
+			"10.0.0.2": {Online: true, Latency: time.Millisecond},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	wantSent := map[string]int{"10.0.0.0": 1, "10.0.0.1": 1, "10.0.0.2": 1, "10.0.0.3": 3}
+	for ip, want := range wantSent {
+		result, ok := sp.Results[ip]
+		if !ok {
+			t.Errorf("Results[%s] is missing", ip)
+			continue
+		}
+
+		if result.PacketsSent != want {
+			t.Errorf("Results[%s].PacketsSent = %d, want %d", ip, result.PacketsSent, want)
+		}
+	}
+
+	if sp.PacketCapReached() {
+		t.Error("PacketCapReached() = true, want the unsent packets returned to the budget")
+	}
+}
+
+func TestConfirmCount(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:       "10.0.0.0/30",
+		Count:        1,
+		ConfirmCount: 5,
+		MaxWorkers:   2,
+		Pinger: ping.NewMockPinger(map[string]ping.MockHostConfig{
+			"10.0.0.0": {Online: true, Latency: time.Millisecond},
+			"10.0.0.1": {Online: true, Latency: time.Millisecond, PacketLoss: 60},
+			"10.0.0.2": {Online: true, Latency: time.Millisecond, PacketLoss: 20},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	tests := []struct {
+		ip       string
+		wantSent int
+		wantRecv int
+	}{
+		{ip: "10.0.0.0", wantSent: 1, wantRecv: 1},
+		{ip: "10.0.0.1", wantSent: 5, wantRecv: 2},
+		{ip: "10.0.0.2", wantSent: 1, wantRecv: 1},
+		{ip: "10.0.0.3", wantSent: 5, wantRecv: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			result, ok := sp.Results[tt.ip]
+			if !ok {
+				t.Fatalf("Results[%s] is missing", tt.ip)
+			}
+
+			if result.Count != tt.wantSent {
+				t.Errorf("Count = %d, want %d", result.Count, tt.wantSent)
+			}
+
+			if result.PacketsSent != tt.wantSent || result.PacketsRecv != tt.wantRecv {
+				t.Errorf("PacketsSent, PacketsRecv = %d, %d, want %d, %d",
+					result.PacketsSent, result.PacketsRecv, tt.wantSent, tt.wantRecv)
+			}
+		})
+	}
+}
+
+func TestConfirmCountValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		confirmCount int
+		wantErr      bool
+	}{
+		{name: "Disabled", confirmCount: 0},
+		{name: "Greater than Count", confirmCount: 5},
+		{name: "Equal to Count", confirmCount: 3, wantErr: true},
+		{name: "Negative", confirmCount: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := subping.NewSubping(&subping.Options{
+				Subnet:       "10.0.0.0/30",
+				Count:        3,
+				ConfirmCount: tt.confirmCount,
+				MaxWorkers:   1,
+				Pinger:       ping.NewMockPinger(nil),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuick(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "192.168.1.0/24",
+		Subnets:    []string{"10.0.0.0/30"},
+		Count:      1,
+		MaxWorkers: 4,
+		Quick:      true,
+		Pinger:     &stubPinger{online: map[string]time.Duration{"192.168.1.1": time.Millisecond}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	if sp.TotalHosts() != 17 {
+		t.Errorf("TotalHosts() = %d, want 17", sp.TotalHosts())
+	}
+
+	sp.Run()
+
+	want := map[string]subping.SubnetStats{
+		"192.168.1.0/24": {Total: 13, Online: 1, Offline: 12},
+		"10.0.0.0/30":    {Total: 4, Offline: 4},
+	}
+	if got := sp.PerSubnetStats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PerSubnetStats() = %v, want %v", got, want)
+	}
+
+	if _, ok := sp.Results["192.168.1.128"]; ok {
+		t.Error("Results hold 192.168.1.128, which is not a common host")
+	}
+}
+
+func TestInterSubnetDelay(t *testing.T) {
+	const delay = 5 * time.Second
+
+	tests := []struct {
+		name    string
+		subnet  string
+		subnets []string
+		hosts   map[string][]net.IP
+		want    map[string]time.Duration
+	}{
+		{
+			name:    "Subnets",
+			subnet:  "10.0.0.0/30",
+			subnets: []string{"10.0.1.0/30", "10.0.2.0/31"},
+			want: map[string]time.Duration{
+				"10.0.0.0": 0, "10.0.0.1": 0, "10.0.0.2": 0, "10.0.0.3": 0,
+				"10.0.1.0": delay, "10.0.1.1": delay, "10.0.1.2": delay, "10.0.1.3": delay,
+				"10.0.2.0": 2 * delay, "10.0.2.1": 2 * delay,
+			},
+		},
+		{
+			// The range is covered by 10.0.0.1/32, 10.0.0.2/31 and 10.0.0.4/32, scanned without pausing.
+			name:    "Range",
+			subnet:  "10.0.0.1-10.0.0.4",
+			subnets: []string{"10.0.1.0/31"},
+			want: map[string]time.Duration{
+				"10.0.0.1": 0, "10.0.0.2": 0, "10.0.0.3": 0, "10.0.0.4": 0,
+				"10.0.1.0": delay, "10.0.1.1": delay,
+			},
+		},
+		{
+			name:   "Hosts",
+			subnet: "10.0.0.0/31",
+			hosts: map[string][]net.IP{
+				"a.example.com": {net.ParseIP("10.0.5.1")},
+				"b.example.com": {net.ParseIP("10.0.6.1"), net.ParseIP("10.0.6.2")},
+			},
+			want: map[string]time.Duration{
+				"10.0.0.0": 0, "10.0.0.1": 0,
+				"10.0.5.1": delay, "10.0.6.1": delay, "10.0.6.2": delay,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &recordingClock{}
+
+			var (
+				mu       sync.Mutex
+				pingedAt = make(map[string]time.Duration)
+				hosts    []string
+			)
+
+			for host := range tt.hosts {
+				hosts = append(hosts, host)
+			}
+			sort.Strings(hosts)
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:           tt.subnet,
+				Subnets:          tt.subnets,
+				Hosts:            hosts,
+				LookupIP:         func(host string) ([]net.IP, error) { return tt.hosts[host], nil },
+				Count:            1,
+				MaxWorkers:       2,
+				InterSubnetDelay: delay,
+				Clock:            clock,
+				Pinger:           ping.NewMockPinger(nil),
+				OnResult: func(ip string, _ subping.Result) {
+					mu.Lock()
+					defer mu.Unlock()
+
+					pingedAt[ip] = clock.Now().Sub(time.Time{})
+				},
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			// The interval is zero, so the clock only advances with the pauses between subnets.
+			if !reflect.DeepEqual(pingedAt, tt.want) {
+				t.Errorf("targets pinged at %v, want %v", pingedAt, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnDispatchAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	audit, err := export.OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/29",
+		Count:      1,
+		MaxWorkers: 3,
+		Exclude:    []string{"10.0.0.4"},
+		Pinger:     &stubPinger{online: map[string]time.Duration{"10.0.0.1": time.Millisecond}},
+		OnDispatch: func(ip string) {
+			if err := audit.Record(ip); err != nil {
+				t.Errorf("Record() error = %v", err)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	if err := audit.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		_, ip, _ := strings.Cut(line, " ")
+		got = append(got, ip)
+	}
+	sort.Strings(got)
+
+	// Every target is recorded once, whether or not it replied; the excluded one is never dispatched.
+	want := []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.5", "10.0.0.6", "10.0.0.7"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("audit log records %v, want %v", got, want)
+	}
+}
+
+func TestMaxWorkersExceedingHosts(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxWorkers  int
+		strict      bool
+		wantWorkers int
+		wantWarning string
+		wantErr     bool
+	}{
+		{
+			name:        "Fewer workers than hosts",
+			maxWorkers:  2,
+			wantWorkers: 2,
+		},
+		{
+			name:        "As many workers as hosts",
+			maxWorkers:  4,
+			strict:      true,
+			wantWorkers: 4,
+		},
+		{
+			name:        "A few workers too many are lowered without a warning",
+			maxWorkers:  6,
+			wantWorkers: 4,
+		},
+		{
+			name:        "Too many workers are lowered with a warning",
+			maxWorkers:  256,
+			wantWorkers: 4,
+			wantWarning: "Requested 256 workers for 4 hosts; using 4.",
+		},
+		{
+			name:       "Too many workers are refused when strict",
+			maxWorkers: 256,
+			strict:     true,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sp *subping.Subping
+			var err error
+
+			logs := captureStderr(t, func() {
+				sp, err = subping.NewSubping(&subping.Options{
+					Subnet:        "10.0.0.0/30",
+					Count:         1,
+					MaxWorkers:    tt.maxWorkers,
+					StrictWorkers: tt.strict,
+					LogLevel:      "warn",
+					Pinger:        ping.NewMockPinger(nil),
+				})
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if sp.MaxWorkers != tt.wantWorkers {
+				t.Errorf("MaxWorkers = %d, want %d", sp.MaxWorkers, tt.wantWorkers)
+			}
+
+			if tt.wantWarning == "" && logs != "" {
+				t.Errorf("NewSubping() logged %q, want no warning", logs)
+			}
+			if tt.wantWarning != "" && !strings.Contains(logs, tt.wantWarning) {
+				t.Errorf("NewSubping() logged %q, want the warning %q", logs, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestJobBufferSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxWorkers    int
+		jobBufferSize int
+		want          int
+		wantErr       bool
+	}{
+		{
+			name:       "Hosts evenly divisible by the workers",
+			maxWorkers: 4,
+			want:       4,
+		},
+		{
+			name:       "Hosts not evenly divisible by the workers are rounded up",
+			maxWorkers: 3,
+			want:       6,
+		},
+		{
+			name:       "Single worker",
+			maxWorkers: 1,
+			want:       16,
+		},
+		{
+			name:       "More workers than hosts are lowered to one per host",
+			maxWorkers: 128,
+			want:       1,
+		},
+		{
+			name:          "Set by the caller",
+			maxWorkers:    3,
+			jobBufferSize: 1,
+			want:          1,
+		},
+		{
+			name:          "Negative",
+			maxWorkers:    3,
+			jobBufferSize: -1,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:        "10.0.0.0/28",
+				Count:         1,
+				MaxWorkers:    tt.maxWorkers,
+				JobBufferSize: tt.jobBufferSize,
+				Pinger:        ping.NewMockPinger(nil),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if sp.JobBufferSize != tt.want {
+				t.Errorf("JobBufferSize = %d, want %d", sp.JobBufferSize, tt.want)
+			}
+
+			sp.Run()
+
+			if sp.TotalResults != 16 {
+				t.Errorf("TotalResults = %d, want 16", sp.TotalResults)
+			}
+		})
+	}
+}
+
+// captureStderr returns what fn writes to os.Stderr, where the loggers created by fn write by default.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	fn()
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	return string(data)
+}
+
+func TestRunTwice(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/29",
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger:     &stubPinger{online: map[string]time.Duration{"10.0.0.1": time.Millisecond}},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	for run := 1; run <= 2; run++ {
+		sp.Results = nil
+		sp.Run()
+
+		if len(sp.Results) != 8 {
+			t.Errorf("run %d: Run() produced %d results, want 8", run, len(sp.Results))
+		}
+	}
+}
+
+func TestOnResult(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		reported = map[string]subping.Result{}
+		calls    int
+	)
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/28",
+		Count:      1,
+		MaxWorkers: 8,
+		Pinger:     &stubPinger{online: map[string]time.Duration{"10.0.0.1": time.Millisecond}},
+		OnResult: func(ip string, result subping.Result) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			calls++
+			reported[ip] = result
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	if calls != 16 {
+		t.Errorf("OnResult was called %d times, want 16", calls)
+	}
+
+	if !reflect.DeepEqual(reported, sp.Results) {
+		t.Errorf("OnResult reported %v, want the results %v", reported, sp.Results)
+	}
+}
+
+func TestCommaSeparatedSubnet(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30, 192.168.1.0/30",
+		Subnets:    []string{"172.16.0.0/31"},
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger:     &stubPinger{},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	want := []string{"10.0.0.0/30", "192.168.1.0/30", "172.16.0.0/31"}
+	if !reflect.DeepEqual(sp.Subnets, want) {
+		t.Errorf("Subnets = %v, want %v", sp.Subnets, want)
+	}
+
+	if sp.TotalHosts() != 10 {
+		t.Errorf("TotalHosts() = %d, want 10", sp.TotalHosts())
+	}
+
+	sp.Run()
+
+	if len(sp.Results) != 10 {
+		t.Errorf("Run() produced %d results, want 10", len(sp.Results))
+	}
+}
+
+func TestSubnetTargets(t *testing.T) {
+	tests := []struct {
+		name           string
+		subnet         string
+		subnets        []string
+		wantSubnets    []string
+		wantTotalHosts int
+		wantErr        bool
+	}{
+		{
+			name:           "Bare IPv4 address",
+			subnet:         "10.0.0.5",
+			wantSubnets:    []string{"10.0.0.5/32"},
+			wantTotalHosts: 1,
+		},
+		{
+			name:           "Bare IPv6 address",
+			subnet:         "2001:db8::1",
+			wantSubnets:    []string{"2001:db8::1/128"},
+			wantTotalHosts: 1,
+		},
+		{
+			name:           "Bare IP address among the additional subnets",
+			subnet:         "10.0.0.0/30",
+			subnets:        []string{"192.168.1.1"},
+			wantSubnets:    []string{"10.0.0.0/30", "192.168.1.1/32"},
+			wantTotalHosts: 5,
+		},
+		{
+			name:    "Garbage subnet",
+			subnet:  "not-a-subnet!",
+			wantErr: true,
+		},
+		{
+			name:    "Garbage additional subnet",
+			subnet:  "10.0.0.0/30",
+			subnets: []string{"10.0.0.0/33"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     tt.subnet,
+				Subnets:    tt.subnets,
+				Count:      1,
+				MaxWorkers: 1,
+				Pinger:     &stubPinger{},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSubping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(sp.Subnets, tt.wantSubnets) {
+				t.Errorf("Subnets = %v, want %v", sp.Subnets, tt.wantSubnets)
+			}
+
+			if sp.TotalHosts() != tt.wantTotalHosts {
+				t.Errorf("TotalHosts() = %d, want %d", sp.TotalHosts(), tt.wantTotalHosts)
+			}
+		})
+	}
+}
+
+func TestTotalHostsExcluded(t *testing.T) {
+	it, err := network.NewSubnetHostsIteratorFromCIDRString("10.0.0.0/24")
+	if err != nil {
+		t.Fatalf("NewSubnetHostsIteratorFromCIDRString() error = %v", err)
+	}
+	commonHosts := len(it.CommonHosts())
+
+	tests := []struct {
+		name    string
+		subnet  string
+		exclude []string
+		known   []string
+		quick   bool
+		want    int
+	}{
+		{name: "No exclusion", subnet: "10.0.0.0/29", want: 8},
+		{name: "Excluded and known hosts", subnet: "10.0.0.0/29", exclude: []string{"10.0.0.1"},
+			known: []string{"10.0.0.2", "10.0.0.3"}, want: 5},
+		{name: "Exclusions outside the subnet", subnet: "10.0.0.0/29", exclude: []string{"10.0.1.1", "2001:db8::1"},
+			want: 8},
+		{name: "Several subnets", subnet: "10.0.0.0/30,10.0.1.0/30", exclude: []string{"10.0.0.1", "10.0.1.1"},
+			want: 6},
+		{name: "Quick scan", subnet: "10.0.0.0/24", exclude: []string{"10.0.0.1", "10.0.0.50"}, quick: true,
+			want: commonHosts - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:     tt.subnet,
+				Count:      1,
+				MaxWorkers: 1,
+				Exclude:    tt.exclude,
+				KnownHosts: tt.known,
+				Quick:      tt.quick,
+				Pinger:     &stubPinger{},
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			if got := sp.TotalHosts(); got != tt.want {
+				t.Errorf("TotalHosts() = %d, want %d", got, tt.want)
+			}
+
+			sp.Run()
+
+			if done, total := sp.Progress(); done != total {
+				t.Errorf("Progress() = %d, %d after the run, want every host to ping done", done, total)
+			}
+		})
+	}
+}
+
+func TestTotalHostsSaturates(t *testing.T) {
+	if strconv.IntSize != 64 {
+		t.Skip("a /66 subnet is too large to scan on 32-bit platforms")
+	}
+
+	// Each /66 holds 2^62 hosts, so together they hold more hosts than an int can count.
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:           "2001:db8::/66,2001:db8:1::/66",
+		Count:            1,
+		MaxWorkers:       1,
+		Exclude:          []string{"2001:db8::1"},
+		AllowLargeRanges: true,
+		Pinger:           &stubPinger{},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	if got := sp.TotalHosts(); got != math.MaxInt {
+		t.Errorf("TotalHosts() = %d, want %d", got, math.MaxInt)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		observed []int
+		sp       *subping.Subping
+	)
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/29",
+		Count:      1,
+		MaxWorkers: 1,
+		Pinger:     &stubPinger{},
+		OnDispatch: func(string) {
+			done, _ := sp.Progress()
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			observed = append(observed, done)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	for run := 1; run <= 2; run++ {
+		observed = nil
+		sp.Run()
+
+		if want := []int{0, 1, 2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(observed, want) {
+			t.Errorf("run %d: Progress() before each ping reported %v done, want %v", run, observed, want)
+		}
+
+		if done, total := sp.Progress(); done != 8 || total != 8 {
+			t.Errorf("run %d: Progress() = %d, %d, want 8, 8", run, done, total)
+		}
+	}
+}
+
+func TestRunContextInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pinged atomic.Int64
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:      "10.0.0.0/28",
+		Count:       1,
+		MaxWorkers:  1,
+		ScanRetries: 1,
+		Clock:       &recordingClock{},
+		Pinger:      &errorPinger{},
+		OnResult: func(string, subping.Result) {
+			if pinged.Add(1) == 3 {
+				cancel()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	if err := sp.RunContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("RunContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	// The scan is neither continued nor retried once interrupted.
+	if len(sp.Results) != 3 || pinged.Load() != 3 {
+		t.Errorf("RunContext() pinged %d targets and produced %d results, want 3", pinged.Load(), len(sp.Results))
+	}
+
+	if err := sp.RunContext(context.Background()); err != nil {
+		t.Errorf("RunContext() error = %v, want nil", err)
+	}
+
+	if len(sp.Results) != 16 {
+		t.Errorf("RunContext() produced %d results after an interrupted run, want 16", len(sp.Results))
+	}
+}
+
+func TestRunContextInterruptsPauses(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pinged atomic.Int64
+
+	// The real clock is used: the pauses after the ping of the worker and before the next subnet are interrupted.
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:           "10.0.0.0/31",
+		Subnets:          []string{"10.0.1.0/31"},
+		Count:            1,
+		Interval:         time.Hour,
+		MaxWorkers:       1,
+		InterSubnetDelay: time.Hour,
+		Pinger:           ping.NewMockPinger(nil),
+		OnResult: func(string, subping.Result) {
+			if pinged.Add(1) == 2 {
+				cancel()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- sp.RunContext(ctx) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("RunContext() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("RunContext() did not return once interrupted")
+	}
+
+	if len(sp.Results) != 2 {
+		t.Errorf("RunContext() produced %d results, want 2", len(sp.Results))
+	}
+}
+
+// sleepingPinger is a ping.Pinger taking delay to report every target as offline.
+type sleepingPinger struct {
+	delay time.Duration
+}
+
+func (p sleepingPinger) Ping(_ string, opts ping.Options) (ping.Result, error) {
+	time.Sleep(p.delay)
+
+	return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+}
+
+func TestMaxDuration(t *testing.T) {
+	// MaxDuration is measured on the wall clock, so the margins are wide: the first ping completes long before
+	// MaxDuration, and the 64 hosts take at least 1.28s to ping one after the other.
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:      "10.0.0.0/26",
+		Count:       1,
+		MaxWorkers:  1,
+		MaxDuration: 200 * time.Millisecond,
+		Pinger:      sleepingPinger{delay: 20 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	if err := sp.RunContext(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RunContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The pings completed within MaxDuration are kept.
+	if len(sp.Results) == 0 || len(sp.Results) >= 64 {
+		t.Errorf("RunContext() produced %d results, want the partial results of the first 200ms", len(sp.Results))
+	}
+
+	if _, err := subping.NewSubping(&subping.Options{
+		Subnet:      "10.0.0.0/28",
+		Count:       1,
+		MaxWorkers:  1,
+		MaxDuration: -time.Second,
+		Pinger:      sleepingPinger{},
+	}); err == nil {
+		t.Error("NewSubping() with a negative MaxDuration did not fail")
+	}
+}
+
+func TestFilterResults(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger: ping.NewMockPinger(map[string]ping.MockHostConfig{
+			"10.0.0.1": {Online: true, Latency: time.Millisecond},
+			"10.0.0.2": {Online: true, Latency: 50 * time.Millisecond},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	sp.Run()
+
+	slow := sp.FilterResults(func(r subping.Result) bool { return r.AvgRtt >= 10*time.Millisecond })
+
+	if _, ok := slow["10.0.0.2"]; len(slow) != 1 || !ok {
+		t.Errorf("FilterResults() = %v, want only 10.0.0.2", slow)
+	}
+
+	if len(sp.Results) != 4 {
+		t.Errorf("FilterResults() left %d results, want the 4 results untouched", len(sp.Results))
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        subping.Options
+		wantResults int
+		wantErr     error
+	}{
+		{
+			name: "Returns the results",
+			opts: subping.Options{
+				Subnet:     "10.0.0.0/30",
+				Count:      1,
+				MaxWorkers: 8,
+				Pinger:     ping.NewMockPinger(map[string]ping.MockHostConfig{"10.0.0.1": {Online: true}}),
+			},
+			wantResults: 4,
+		},
+		{
+			name: "Reports an aborted scan",
+			opts: subping.Options{
+				Subnet:     "10.0.0.0/30",
+				Count:      1,
+				MaxWorkers: 8,
+				Pinger:     &deniedPinger{},
+			},
+			wantErr: subping.ErrPermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+
+			results, err := subping.Scan(context.Background(), &opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Scan() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(results) != tt.wantResults {
+				t.Errorf("Scan() returned %d results, want %d", len(results), tt.wantResults)
+			}
+
+			if opts.MaxWorkers != 8 || opts.LogLevel != "" {
+				t.Errorf("Scan() defaulted the options in place: %+v", opts)
+			}
+		})
+	}
+
+	if _, err := subping.Scan(context.Background(), &subping.Options{Subnet: "invalid"}); err == nil {
+		t.Error("Scan() error = nil, want an error for an invalid subnet")
+	}
+}
@@ -0,0 +1,70 @@
+package subping
+
+import (
+	"net"
+
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+const (
+	// icmpHeaderSize is the size of the ICMP echo header.
+	icmpHeaderSize = 8
+
+	// tcpHeaderSize is the size of a TCP header without options.
+	tcpHeaderSize = 20
+
+	// ipv4HeaderSize is the size of an IPv4 header without options.
+	ipv4HeaderSize = 20
+
+	// ipv6HeaderSize is the size of an IPv6 header without extension headers.
+	ipv6HeaderSize = 40
+)
+
+// TrafficStats returns an estimate of the bytes sent and received by the last run, or the latest Watch round,
+// retries included, counting the IP and ICMP or TCP headers of every packet.
+func (s *Subping) TrafficStats() (sentBytes, recvBytes int64) {
+	return s.sentBytes.Load(), s.recvBytes.Load()
+}
+
+// resetTraffic starts the traffic estimate of a new run, or Watch round, from zero.
+func (s *Subping) resetTraffic() {
+	s.sentBytes.Store(0)
+	s.recvBytes.Store(0)
+}
+
+// recordTraffic adds the packets of the ping of target reported by r to the traffic of the current run.
+// The probes of the TCP pinger are told apart by their Result.Ports.
+func (s *Subping) recordTraffic(target string, r Result) {
+	size := int64(packetSize(target, s.PayloadSize))
+	if r.Ports != nil {
+		size = int64(segmentSize(target))
+	}
+
+	s.sentBytes.Add(int64(r.PacketsSent) * size)
+	s.recvBytes.Add(int64(r.PacketsRecv+r.PacketsRecvDuplicates) * size)
+}
+
+// packetSize returns the size on the wire, IP header included, of an echo request to target carrying payloadSize
+// bytes, or ping.DefaultPayloadSize bytes when payloadSize is zero.
+func packetSize(target string, payloadSize int) int {
+	if payloadSize == 0 {
+		payloadSize = ping.DefaultPayloadSize
+	}
+
+	size := icmpHeaderSize + payloadSize
+	if ip := net.ParseIP(target); ip != nil && ip.To4() == nil {
+		return size + ipv6HeaderSize
+	}
+
+	return size + ipv4HeaderSize
+}
+
+// segmentSize returns the size on the wire, IP header included, of a TCP segment without options or payload to
+// target.
+func segmentSize(target string) int {
+	if ip := net.ParseIP(target); ip != nil && ip.To4() == nil {
+		return tcpHeaderSize + ipv6HeaderSize
+	}
+
+	return tcpHeaderSize + ipv4HeaderSize
+}
@@ -0,0 +1,126 @@
+package subping_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// portPinger answers like the TCP pinger: every ping makes two connection attempts to port 80, one completing.
+type portPinger struct{}
+
+func (portPinger) Ping(_ string, _ ping.Options) (ping.Result, error) {
+	return ping.Result{PacketsSent: 2, PacketsRecv: 1, PacketLoss: 50, Ports: map[int]bool{80: true}}, nil
+}
+
+func TestTrafficStats(t *testing.T) {
+	tests := []struct {
+		name        string
+		subnet      string
+		payloadSize int
+		hosts       map[string]ping.MockHostConfig
+		pinger      ping.Pinger
+		wantSent    int64
+		wantRecv    int64
+	}{
+		{
+			name:   "IPv4",
+			subnet: "10.0.0.0/30",
+			hosts: map[string]ping.MockHostConfig{
+				"10.0.0.1": {Online: true, Latency: time.Millisecond},
+				"10.0.0.2": {Online: true, Latency: time.Millisecond, PacketLoss: 50},
+			},
+			// 4 hosts × 4 packets of 52 bytes sent, 4 + 2 replies received.
+			wantSent: 4 * 4 * 52,
+			wantRecv: 6 * 52,
+		},
+		{
+			name:   "IPv6",
+			subnet: "2001:db8::/127",
+			hosts: map[string]ping.MockHostConfig{
+				"2001:db8::1": {Online: true, Latency: time.Millisecond},
+			},
+			// 2 hosts × 4 packets of 72 bytes sent, 4 replies received.
+			wantSent: 2 * 4 * 72,
+			wantRecv: 4 * 72,
+		},
+		{
+			name:        "IPv4 with a larger payload",
+			subnet:      "10.0.0.1/32",
+			payloadSize: 1472,
+			hosts: map[string]ping.MockHostConfig{
+				"10.0.0.1": {Online: true, Latency: time.Millisecond},
+			},
+			// 4 packets of 1500 bytes sent and received.
+			wantSent: 4 * 1500,
+			wantRecv: 4 * 1500,
+		},
+		{
+			name:   "TCP over IPv4",
+			subnet: "10.0.0.0/31",
+			pinger: portPinger{},
+			// 2 hosts × 2 segments of 40 bytes sent, 1 received.
+			wantSent: 2 * 2 * 40,
+			wantRecv: 2 * 40,
+		},
+		{
+			name:   "TCP over IPv6",
+			subnet: "2001:db8::1/128",
+			pinger: portPinger{},
+			// 2 segments of 60 bytes sent, 1 received.
+			wantSent: 2 * 60,
+			wantRecv: 60,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := tt.pinger
+			if pinger == nil {
+				pinger = ping.NewMockPinger(tt.hosts)
+			}
+
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:      tt.subnet,
+				Count:       4,
+				MaxWorkers:  2,
+				PayloadSize: tt.payloadSize,
+				Pinger:      pinger,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			sent, recv := sp.TrafficStats()
+			if sent != tt.wantSent || recv != tt.wantRecv {
+				t.Errorf("TrafficStats() = (%d, %d), want (%d, %d)", sent, recv, tt.wantSent, tt.wantRecv)
+			}
+		})
+	}
+}
+
+func TestTrafficStatsWatch(t *testing.T) {
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/30",
+		Count:      4,
+		MaxWorkers: 2,
+		Pinger: ping.NewMockPinger(map[string]ping.MockHostConfig{
+			"10.0.0.1": {Online: true, Latency: time.Millisecond},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	for range sp.Watch(context.Background(), 0, 3) {
+	}
+
+	// Only the latest round is counted: 4 hosts × 4 packets of 52 bytes sent, 4 replies received.
+	if sent, recv := sp.TrafficStats(); sent != 4*4*52 || recv != 4*52 {
+		t.Errorf("TrafficStats() after 3 rounds = (%d, %d), want (%d, %d)", sent, recv, 4*4*52, 4*52)
+	}
+}
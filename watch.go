@@ -0,0 +1,132 @@
+package subping
+
+import (
+	"context"
+	"time"
+)
+
+// EventType identifies the kind of state change reported by Watch.
+type EventType string
+
+const (
+	// EventOnline is emitted when a host is seen online for the first time or comes back online.
+	EventOnline EventType = "online"
+
+	// EventOffline is emitted when a host is seen offline for the first time or goes offline.
+	EventOffline EventType = "offline"
+
+	// EventStillOffline is emitted periodically while a host stays offline, see Options.OfflineReminderEvery.
+	EventStillOffline EventType = "still_offline"
+)
+
+// Event describes a state change of a host observed by Watch.
+type Event struct {
+	// IP is the address of the host.
+	IP string
+
+	// Type is the kind of state change.
+	Type EventType
+
+	// Round is the one-based number of the scan round in which the event occurred.
+	Round int
+
+	// OfflineRounds is the number of consecutive rounds the host has been offline, including this one.
+	OfflineRounds int
+
+	// Result is the ping result of the host in this round.
+	Result Result
+}
+
+// Watch repeatedly scans the targets, waiting interval between the end of a round and the start
+// of the next one, and sends an Event on the returned channel whenever a host changes state.
+// A host that stays in the same state produces no further events, except for the periodic
+// EventStillOffline reminders enabled by OfflineReminderEvery.
+//
+// Watch stops after rounds scan rounds, or runs until ctx is cancelled when rounds is zero or less. Cancelling ctx
+// also stops the round in progress, skipping the targets not pinged yet.
+// The channel is closed once Watch stops. Results holds the results of the latest round.
+// A round aborted by a permission error also stops Watch; Err then reports the error.
+// SmoothedRTT reports the moving average of the RTT of each host across the rounds.
+func (s *Subping) Watch(ctx context.Context, interval time.Duration, rounds int) <-chan Event {
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+
+		s.err = nil
+		s.resetSmoothedRTT()
+
+		// offlineRounds holds the number of consecutive offline rounds of each host, zero meaning online.
+		offlineRounds := make(map[string]int)
+
+		for round := 1; rounds <= 0 || round <= rounds; round++ {
+			startTime := time.Now()
+			s.startedAt = startTime
+			s.resetTraffic()
+			s.resetPacketBudget()
+
+			// Like RunContext, a cancelled ctx stops the round in progress; its partial results are discarded.
+			results := s.scan(ctx, s.targets(s.NewIterator()))
+			if s.err != nil || ctx.Err() != nil {
+				s.setLive(nil)
+
+				return
+			}
+
+			s.setResults(results)
+			s.TotalResults = len(results)
+			s.Elapsed = time.Since(startTime)
+			s.updateSmoothedRTT(results)
+
+			for _, ip := range sortIPs(results) {
+				event, ok := s.observe(offlineRounds, ip, round, results[ip])
+				if !ok {
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if rounds > 0 && round == rounds {
+				return
+			}
+
+			if !s.sleep(ctx, interval) {
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// observe records the state of ip in the given round and returns the event to emit, if any.
+func (s *Subping) observe(offlineRounds map[string]int, ip string, round int, r Result) (Event, bool) {
+	previous, seen := offlineRounds[ip]
+	event := Event{IP: ip, Round: round, Result: r}
+
+	if r.PacketsRecv > 0 {
+		offlineRounds[ip] = 0
+		event.Type = EventOnline
+
+		return event, !seen || previous > 0
+	}
+
+	offlineRounds[ip] = previous + 1
+	event.OfflineRounds = previous + 1
+
+	switch {
+	case !seen || previous == 0:
+		event.Type = EventOffline
+		return event, true
+	case s.OfflineReminderEvery > 0 && previous%s.OfflineReminderEvery == 0:
+		event.Type = EventStillOffline
+		return event, true
+	}
+
+	return event, false
+}
@@ -0,0 +1,173 @@
+package subping_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+// sequencePinger is a ping.Pinger replying according to a per-target sequence of states,
+// one entry per call. Targets without a sequence, or calls past its end, are offline.
+type sequencePinger struct {
+	mu    sync.Mutex
+	up    map[string][]bool
+	calls map[string]int
+}
+
+func (p *sequencePinger) Ping(target string, opts ping.Options) (ping.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	call := p.calls[target]
+	p.calls[target]++
+
+	if seq := p.up[target]; call < len(seq) && seq[call] {
+		return ping.Result{AvgRtt: time.Millisecond, PacketsSent: opts.Count, PacketsRecv: opts.Count}, nil
+	}
+
+	return ping.Result{PacketsSent: opts.Count, PacketLoss: 100}, nil
+}
+
+func TestWatchEvents(t *testing.T) {
+	type event struct {
+		IP            string
+		Type          subping.EventType
+		Round         int
+		OfflineRounds int
+	}
+
+	tests := []struct {
+		name          string
+		reminderEvery int
+		up            map[string][]bool
+		want          []event
+	}{
+		{
+			name: "Persistently offline host emits a single event",
+			up: map[string][]bool{
+				"10.0.0.1": {true, true, true, true, true, true, true},
+			},
+			want: []event{
+				{IP: "10.0.0.0", Type: subping.EventOffline, Round: 1, OfflineRounds: 1},
+				{IP: "10.0.0.1", Type: subping.EventOnline, Round: 1},
+			},
+		},
+		{
+			name:          "Persistently offline host with reminders",
+			reminderEvery: 3,
+			up: map[string][]bool{
+				"10.0.0.1": {true, true, true, true, true, true, true},
+			},
+			want: []event{
+				{IP: "10.0.0.0", Type: subping.EventOffline, Round: 1, OfflineRounds: 1},
+				{IP: "10.0.0.1", Type: subping.EventOnline, Round: 1},
+				{IP: "10.0.0.0", Type: subping.EventStillOffline, Round: 4, OfflineRounds: 4},
+				{IP: "10.0.0.0", Type: subping.EventStillOffline, Round: 7, OfflineRounds: 7},
+			},
+		},
+		{
+			name: "Only transitions are emitted",
+			up: map[string][]bool{
+				"10.0.0.0": {false, false, false, false, false, false, false},
+				"10.0.0.1": {true, true, false, false, true, true, true},
+			},
+			want: []event{
+				{IP: "10.0.0.0", Type: subping.EventOffline, Round: 1, OfflineRounds: 1},
+				{IP: "10.0.0.1", Type: subping.EventOnline, Round: 1},
+				{IP: "10.0.0.1", Type: subping.EventOffline, Round: 3, OfflineRounds: 1},
+				{IP: "10.0.0.1", Type: subping.EventOnline, Round: 5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:               "10.0.0.0/31",
+				Count:                1,
+				MaxWorkers:           2,
+				OfflineReminderEvery: tt.reminderEvery,
+				Pinger:               &sequencePinger{up: tt.up, calls: make(map[string]int)},
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			var got []event
+			for e := range sp.Watch(context.Background(), 0, 7) {
+				got = append(got, event{IP: e.IP, Type: e.Type, Round: e.Round, OfflineRounds: e.OfflineRounds})
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Watch() events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchPausesOnClock(t *testing.T) {
+	clock := &recordingClock{}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/31",
+		Count:      1,
+		MaxWorkers: 2,
+		Pinger:     &stubPinger{},
+		Clock:      clock,
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	// The pauses of an hour between the rounds are taken on the clock, not waited for.
+	for range sp.Watch(context.Background(), time.Hour, 3) {
+	}
+
+	var pauses int
+	for _, d := range clock.sleeps {
+		if d == time.Hour {
+			pauses++
+		}
+	}
+
+	if pauses != 2 {
+		t.Errorf("Watch() paused %d times on the clock between 3 rounds, want 2: %v", pauses, clock.sleeps)
+	}
+}
+
+func TestWatchCancelStopsRound(t *testing.T) {
+	pinger := &gatedPinger{gate: "10.0.0.1", reached: make(chan struct{}), release: make(chan struct{})}
+
+	sp, err := subping.NewSubping(&subping.Options{
+		Subnet:     "10.0.0.0/28",
+		Count:      1,
+		MaxWorkers: 1,
+		Pinger:     pinger,
+	})
+	if err != nil {
+		t.Fatalf("NewSubping() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := sp.Watch(ctx, time.Hour, 0)
+
+	<-pinger.reached
+	cancel()
+	close(pinger.release)
+
+	for event := range events {
+		t.Errorf("Watch() sent %+v for an interrupted round", event)
+	}
+
+	if sp.TotalResults != 0 {
+		t.Errorf("TotalResults = %d, want the interrupted round discarded", sp.TotalResults)
+	}
+
+	if _, online := sp.GetOnlineHosts(); online != 0 {
+		t.Errorf("GetOnlineHosts() = %d online hosts, want the interrupted round discarded", online)
+	}
+}
@@ -0,0 +1,32 @@
+package subping
+
+import (
+	"time"
+)
+
+// WorkerStat holds the statistics of a worker during a scan.
+type WorkerStat struct {
+	// Worker is the zero-based index of the worker.
+	Worker int
+
+	// Hosts is the number of targets whose result was stored by the worker.
+	Hosts int
+
+	// Busy is the total time the worker spent pinging its targets, excluding the pauses between them.
+	Busy time.Duration
+}
+
+// WorkerStats returns the statistics of each worker in the last run, or Watch round, ordered by worker index.
+// The first target, pinged before the workers start to detect permission problems, is accounted to worker 0.
+// It reveals load imbalance, such as a worker stuck on slow timeouts. It returns nil unless
+// Options.CollectWorkerStats is set.
+func (s *Subping) WorkerStats() []WorkerStat {
+	if s.workerStats == nil {
+		return nil
+	}
+
+	stats := make([]WorkerStat, len(s.workerStats))
+	copy(stats, s.workerStats)
+
+	return stats
+}
@@ -0,0 +1,59 @@
+package subping_test
+
+import (
+	"testing"
+
+	"github.com/fadhilyori/subping"
+	"github.com/fadhilyori/subping/pkg/ping"
+)
+
+func TestWorkerStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		collect bool
+	}{
+		{name: "Collected", collect: true},
+		{name: "Not collected", collect: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := subping.NewSubping(&subping.Options{
+				Subnet:             "10.0.0.0/26",
+				Count:              1,
+				MaxWorkers:         4,
+				Pinger:             ping.NewMockPinger(nil),
+				CollectWorkerStats: tt.collect,
+			})
+			if err != nil {
+				t.Fatalf("NewSubping() error = %v", err)
+			}
+
+			sp.Run()
+
+			stats := sp.WorkerStats()
+			if !tt.collect {
+				if stats != nil {
+					t.Errorf("WorkerStats() = %v, want nil", stats)
+				}
+				return
+			}
+
+			if len(stats) != 4 {
+				t.Fatalf("WorkerStats() returned %d workers, want 4", len(stats))
+			}
+
+			total := 0
+			for i, stat := range stats {
+				if stat.Worker != i {
+					t.Errorf("WorkerStats()[%d].Worker = %d, want %d", i, stat.Worker, i)
+				}
+
+				total += stat.Hosts
+			}
+
+			if total != sp.TotalResults {
+				t.Errorf("sum of the worker hosts = %d, want TotalResults = %d", total, sp.TotalResults)
+			}
+		})
+	}
+}